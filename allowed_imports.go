@@ -0,0 +1,75 @@
+package godepfind
+
+import (
+	"sort"
+	"strings"
+)
+
+// Violation is a dependency edge forbidden by CheckAllowedImports rules
+type Violation struct {
+	Importer string // package declaring the import
+	Importee string // imported package not allowed by the rule
+	Rule     string // rule key (package or prefix) that governs the importer
+}
+
+// CheckAllowedImports reports the dependency edges that break an import
+// allowlist. rules maps a package, or a prefix covering its subpackages, to the
+// import path prefixes it may import; the longest matching key governs a
+// package. Packages matched by no rule are unrestricted, and standard library
+// imports are always allowed. Violations are sorted by importer then importee.
+func (g *GoDepFind) CheckAllowedImports(rules map[string][]string) ([]Violation, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	violations := []Violation{}
+	for importer, deps := range g.dependencyGraph {
+		rule, governed := matchingRule(rules, importer)
+		if !governed {
+			continue
+		}
+		for _, importee := range deps {
+			if _, local := g.packageCache[importee]; !local && isStandardImportPath(importee) {
+				continue
+			}
+			if !allowedImport(rules[rule], importee) {
+				violations = append(violations, Violation{Importer: importer, Importee: importee, Rule: rule})
+			}
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Importer != violations[j].Importer {
+			return violations[i].Importer < violations[j].Importer
+		}
+		return violations[i].Importee < violations[j].Importee
+	})
+	return violations, nil
+}
+
+// matchingRule returns the longest rule key equal to pkgPath or a path prefix of it
+func matchingRule(rules map[string][]string, pkgPath string) (string, bool) {
+	best, found := "", false
+	for key := range rules {
+		if hasPathPrefix(pkgPath, key) && (!found || len(key) > len(best)) {
+			best, found = key, true
+		}
+	}
+	return best, found
+}
+
+// allowedImport reports whether importee is under one of the allowed prefixes
+func allowedImport(allowed []string, importee string) bool {
+	for _, prefix := range allowed {
+		if hasPathPrefix(importee, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix reports whether path equals prefix or is below it
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
@@ -0,0 +1,43 @@
+package godepfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckAllowedImports(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":                "module testmod\n\ngo 1.21\n",
+		"cmd/app/main.go":       "package main\n\nimport \"testmod/api\"\n\nfunc main() { api.Do() }\n",
+		"api/api.go":            "package api\n\nimport (\n\t\"fmt\"\n\n\t\"testmod/domain\"\n\t\"testmod/storage/sql\"\n)\n\nfunc Do() { fmt.Println(domain.X, sql.X) }\n",
+		"domain/domain.go":      "package domain\n\nimport \"testmod/storage/sql\"\n\nvar X = sql.X\n",
+		"domain/model/model.go": "package model\n\nimport \"testmod/domain\"\n\nvar X = domain.X\n",
+		"storage/sql/sql.go":    "package sql\n\nvar X = 1\n",
+	})
+	finder := New(root)
+
+	rules := map[string][]string{
+		// the domain layer must not depend on storage
+		"testmod/domain": {"testmod/domain"},
+		"testmod/api":    {"testmod/domain", "testmod/storage"},
+	}
+	violations, err := finder.CheckAllowedImports(rules)
+	if err != nil {
+		t.Fatalf("CheckAllowedImports failed: %v", err)
+	}
+	want := []Violation{{Importer: "testmod/domain", Importee: "testmod/storage/sql", Rule: "testmod/domain"}}
+	if !reflect.DeepEqual(violations, want) {
+		t.Errorf("CheckAllowedImports = %+v, want %+v", violations, want)
+	}
+
+	// A more specific rule overrides the prefix rule for its subpackages
+	rules["testmod/domain/model"] = []string{}
+	violations, err = finder.CheckAllowedImports(rules)
+	if err != nil {
+		t.Fatalf("CheckAllowedImports failed: %v", err)
+	}
+	want = append(want, Violation{Importer: "testmod/domain/model", Importee: "testmod/domain", Rule: "testmod/domain/model"})
+	if !reflect.DeepEqual(violations, want) {
+		t.Errorf("CheckAllowedImports = %+v, want %+v", violations, want)
+	}
+}
@@ -0,0 +1,111 @@
+package godepfind
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SetArtifactResolver installs a custom build output → source main resolver
+// consulted by MainForArtifact before the default rules. The resolver returns
+// the main package path and true when it recognizes the artifact.
+func (g *GoDepFind) SetArtifactResolver(resolver func(artifactPath string) (mainPkg string, ok bool)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.artifactResolver = resolver
+}
+
+// MainForArtifact maps a build output (e.g. "web/public/app.wasm") back to
+// the main package it was compiled from. The configured resolver is tried
+// first; otherwise the main is found by name: the artifact's base name
+// without extension matches the main's directory name. ".wasm" artifacts only
+// match mains with a js/wasm build target, and when no name matches, fall back
+// to the wasm main whose directory contains the artifact, or the only wasm
+// main of the module.
+func (g *GoDepFind) MainForArtifact(artifactPath string) (string, error) {
+	if artifactPath == "" {
+		return "", fmt.Errorf("artifactPath cannot be empty")
+	}
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if g.artifactResolver != nil {
+		if mainPkg, ok := g.artifactResolver(artifactPath); ok {
+			return mainPkg, nil
+		}
+	}
+	if !filepath.IsAbs(artifactPath) {
+		artifactPath = filepath.Join(g.rootDir, artifactPath)
+	}
+	artifactAbs, err := filepath.Abs(artifactPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve artifactPath to absolute path: %w", err)
+	}
+
+	base := filepath.Base(artifactAbs)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	isWasm := ext == ".wasm"
+
+	var candidates, byName, byDir []string
+	for _, mainPath := range g.mainPackages {
+		if isWasm && !g.targetsWasm(mainPath) {
+			continue
+		}
+		candidates = append(candidates, mainPath)
+		target := g.mainTarget(mainPath)
+		if filepath.Base(target.Dir) == name {
+			byName = append(byName, mainPath)
+		}
+		if target.Dir != "" && (artifactAbs == target.Dir || strings.HasPrefix(artifactAbs, target.Dir+string(filepath.Separator))) {
+			byDir = append(byDir, mainPath)
+		}
+	}
+
+	for _, matches := range [][]string{byName, byDir} {
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			sort.Strings(matches)
+			return "", fmt.Errorf("artifact %s matches several mains: %v", artifactPath, matches)
+		}
+	}
+	if isWasm && len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	return "", fmt.Errorf("no main package found for artifact: %s", artifactPath)
+}
+
+// targetsWasm reports whether a main package has a js/wasm build target: it
+// imports syscall/js or has files only built for js/wasm
+func (g *GoDepFind) targetsWasm(mainPath string) bool {
+	pkg := g.packageCache[mainPath]
+	if pkg == nil {
+		return false
+	}
+	if g.goos == "js" && g.goarch == "wasm" {
+		return true // every main is built for the configured wasm platform
+	}
+	ctx := g.buildContext()
+	ctx.GOOS, ctx.GOARCH = "js", "wasm"
+	wasmPkg, err := ctx.ImportDir(pkg.Dir, 0)
+	if err != nil || wasmPkg.Name != "main" {
+		return false
+	}
+	if contains(wasmPkg.Imports, "syscall/js") {
+		return true
+	}
+	for _, file := range wasmPkg.GoFiles {
+		if !contains(pkg.GoFiles, file) {
+			return true
+		}
+	}
+	return false
+}
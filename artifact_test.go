@@ -0,0 +1,74 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func artifactModuleFiles() map[string]string {
+	return map[string]string{
+		"go.mod":          "module testmod\n\ngo 1.21\n",
+		"cmd/app/main.go": "package main\n\nfunc main() {}\n",
+		"cmd/api/main.go": "package main\n\nfunc main() {}\n",
+		// web/app builds a different main for js/wasm
+		"web/app/main.go":      "//go:build !wasm\n\npackage main\n\nfunc main() {}\n",
+		"web/app/main_wasm.go": "//go:build js && wasm\n\npackage main\n\nfunc main() {}\n",
+	}
+}
+
+func TestMainForArtifactWasm(t *testing.T) {
+	root := writeTestModule(t, artifactModuleFiles())
+	finder := New(root)
+
+	tests := []struct {
+		artifact string
+		expected string
+	}{
+		// cmd/app shares the name but has no js/wasm build target
+		{"web/public/app.wasm", "testmod/web/app"},
+		// no name match: the wasm main whose directory holds the artifact
+		{filepath.Join(root, "web", "app", "main.wasm"), "testmod/web/app"},
+		{"bin/api", "testmod/cmd/api"},
+	}
+	for _, tt := range tests {
+		mainPkg, err := finder.MainForArtifact(tt.artifact)
+		if err != nil {
+			t.Fatalf("MainForArtifact(%s) failed: %v", tt.artifact, err)
+		}
+		if mainPkg != tt.expected {
+			t.Errorf("MainForArtifact(%s) = %s, want %s", tt.artifact, mainPkg, tt.expected)
+		}
+	}
+
+	// Both cmd/app and web/app match a native artifact named app
+	if _, err := finder.MainForArtifact("bin/app"); err == nil {
+		t.Error("expected error for an ambiguous artifact")
+	}
+	if _, err := finder.MainForArtifact("bin/unknown"); err == nil {
+		t.Error("expected error for an unknown artifact")
+	}
+}
+
+func TestMainForArtifactCustomResolver(t *testing.T) {
+	root := writeTestModule(t, artifactModuleFiles())
+	finder := New(root)
+	finder.SetArtifactResolver(func(artifactPath string) (string, bool) {
+		if filepath.Base(artifactPath) == "bundle.wasm" {
+			return "testmod/web/app", true
+		}
+		return "", false
+	})
+
+	mainPkg, err := finder.MainForArtifact("dist/bundle.wasm")
+	if err != nil {
+		t.Fatalf("MainForArtifact failed: %v", err)
+	}
+	if mainPkg != "testmod/web/app" {
+		t.Errorf("expected resolver result, got %s", mainPkg)
+	}
+
+	// Unrecognized artifacts fall back to the default rules
+	if mainPkg, err := finder.MainForArtifact("bin/api"); err != nil || mainPkg != "testmod/cmd/api" {
+		t.Errorf("expected fallback to testmod/cmd/api, got %s (%v)", mainPkg, err)
+	}
+}
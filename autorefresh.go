@@ -0,0 +1,87 @@
+package godepfind
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SetAutoRefresh enables a staleness check at the start of each query: the
+// latest modification time of the source directories under the roots is
+// compared with the one recorded when the cache was built, and the cache is
+// rebuilt when a directory changed since, e.g. after a git checkout done
+// outside the event stream. Directory times change when files are added,
+// removed or renamed, not when a file is edited in place. Disabled by
+// default: the cache is trusted until an explicit event.
+func (g *GoDepFind) SetAutoRefresh(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.autoRefresh = enabled
+	if enabled && g.cachedModule {
+		g.recordTreeModTime()
+	}
+}
+
+// refreshIfStale invalidates the cache when auto refresh is enabled and the
+// tree changed since it was built, so the next ensureCacheInitialized rebuilds it
+func (g *GoDepFind) refreshIfStale() {
+	if g.cachedModule && g.cacheStale() {
+		g.cachedModule = false
+	}
+}
+
+// cacheStale reports whether auto refresh is enabled and a source directory
+// was modified after the cache was built
+func (g *GoDepFind) cacheStale() bool {
+	if !g.autoRefresh {
+		return false
+	}
+	latest, err := g.treeModTime()
+	return err == nil && latest.After(g.builtModTime)
+}
+
+// recordTreeModTime records the current tree modification time as the one the
+// cache reflects
+func (g *GoDepFind) recordTreeModTime() {
+	if latest, err := g.treeModTime(); err == nil {
+		g.builtModTime = latest
+	}
+}
+
+// treeModTime returns the latest modification time of the directories under
+// the roots, skipping those ignored by the go tool (testdata, and names
+// starting with "." or "_")
+func (g *GoDepFind) treeModTime() (time.Time, error) {
+	var latest time.Time
+	for _, root := range g.roots() {
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			return time.Time{}, err
+		}
+		err = filepath.WalkDir(rootAbs, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			name := d.Name()
+			if path != rootAbs && (name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return latest, nil
+}
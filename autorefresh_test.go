@@ -0,0 +1,52 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAutoRefreshRebuildsStaleCache(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	auto := New(root)
+	auto.SetAutoRefresh(true)
+	manual := New(root)
+	for _, finder := range []*GoDepFind{auto, manual} {
+		if _, err := finder.ListMainPackages(); err != nil {
+			t.Fatalf("ListMainPackages failed: %v", err)
+		}
+	}
+
+	// Unchanged tree: no rebuild
+	listed := auto.listCount
+	if _, err := auto.ListMainPackages(); err != nil {
+		t.Fatalf("ListMainPackages failed: %v", err)
+	}
+	if auto.listCount != listed {
+		t.Errorf("expected no rebuild of an unchanged tree, got %d more go list runs", auto.listCount-listed)
+	}
+
+	// A command added outside the event stream (e.g. by a git checkout)
+	if err := os.MkdirAll(filepath.Join(root, "cmd", "tool"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "cmd", "tool", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mains, err := auto.ListMainPackages()
+	if err != nil {
+		t.Fatalf("ListMainPackages failed: %v", err)
+	}
+	if !contains(mains, "testmod/cmd/tool") {
+		t.Errorf("expected auto refresh to pick up testmod/cmd/tool, got %v", mains)
+	}
+
+	mains, err = manual.ListMainPackages()
+	if err != nil {
+		t.Fatalf("ListMainPackages failed: %v", err)
+	}
+	if contains(mains, "testmod/cmd/tool") {
+		t.Errorf("expected the cache to be trusted without auto refresh, got %v", mains)
+	}
+}
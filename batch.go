@@ -0,0 +1,63 @@
+package godepfind
+
+import "path/filepath"
+
+// WhichFilesAreMine decides ownership of several files for one handler. Files
+// whose owner cannot be determined are returned in unresolved instead of the
+// owned map, so callers don't mistake them for "not owned": files outside
+// rootDir, Go files that belong to no known package (orphaned, or in a package
+// that failed to load) and non-Go files outside the handler's asset roots.
+// Unresolved files keep the input order.
+func (g *GoDepFind) WhichFilesAreMine(mainInputFileRelativePath string, fileAbsPaths []string) (owned map[string]bool, unresolved []string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, nil, err
+	}
+
+	owned = make(map[string]bool, len(fileAbsPaths))
+	unresolved = []string{}
+	for _, fileAbsPath := range fileAbsPaths {
+		if !g.fileResolvable(mainInputFileRelativePath, fileAbsPath) {
+			unresolved = append(unresolved, fileAbsPath)
+			continue
+		}
+		// No event: a batch query must not update the cache
+		isMine, _, err := g.thisFileIsMine(mainInputFileRelativePath, fileAbsPath, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		owned[fileAbsPath] = isMine
+	}
+	return owned, unresolved, nil
+}
+
+// fileResolvable reports whether the ownership rules can place a file: it is
+// under rootDir and either under an asset root of the handler, the handler's
+// own main file, or a Go file whose package is known by its exact path (or
+// whose directory package excludes it by build constraints)
+func (g *GoDepFind) fileResolvable(mainInputFileRelativePath, fileAbsPath string) bool {
+	fileAbsPath = g.rootPath(fileAbsPath)
+	absPath, err := filepath.Abs(fileAbsPath)
+	if err != nil {
+		return false
+	}
+	relativeFilePath, insideRoot := g.relativeToRoot(absPath)
+	if !insideRoot {
+		return false
+	}
+	if owned, isAssetHandler := g.matchesAssetRoot(mainInputFileRelativePath, relativeFilePath); owned || isAssetHandler {
+		return true
+	}
+	if filepath.Ext(absPath) != ".go" {
+		return false
+	}
+	if relativeFilePath == filepath.Clean(mainInputFileRelativePath) {
+		return true
+	}
+	if _, exact, _ := g.resolvePackageForFile(absPath); exact {
+		return true
+	}
+	return g.isExcludedByConstraints(absPath)
+}
@@ -0,0 +1,60 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWhichFilesAreMineReportsUnresolved(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":      "module testmod\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+		"other/o.go":  "package other\n",
+		"notes.txt":   "notes\n",
+	})
+	finder := New(root)
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+
+	// Created after the cache was built: no known package contains it
+	orphan := filepath.Join(root, "orphan", "orphan.go")
+	if err := os.MkdirAll(filepath.Dir(orphan), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(orphan, []byte("package orphan\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "outside.go")
+	if err := os.WriteFile(outside, []byte("package outside\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{
+		filepath.Join(root, "app", "main.go"),
+		filepath.Join(root, "lib", "lib.go"),
+		orphan,
+		filepath.Join(root, "other", "o.go"),
+		outside,
+		filepath.Join(root, "notes.txt"),
+	}
+	owned, unresolved, err := finder.WhichFilesAreMine("app/main.go", files)
+	if err != nil {
+		t.Fatalf("WhichFilesAreMine failed: %v", err)
+	}
+
+	wantOwned := map[string]bool{
+		filepath.Join(root, "app", "main.go"): true,
+		filepath.Join(root, "lib", "lib.go"):  true,
+		filepath.Join(root, "other", "o.go"):  false,
+	}
+	if !reflect.DeepEqual(owned, wantOwned) {
+		t.Errorf("owned = %v, want %v", owned, wantOwned)
+	}
+	if want := []string{orphan, outside, filepath.Join(root, "notes.txt")}; !reflect.DeepEqual(unresolved, want) {
+		t.Errorf("unresolved = %v, want %v", unresolved, want)
+	}
+}
@@ -140,7 +140,7 @@ func BenchmarkRealWorldScenario(b *testing.B) {
 	}
 
 	files := []string{"module1/module1.go", "module2/module2.go", "module3/module3.go", "module4/module4.go"}
-	events := []string{"write", "create", "remove"}
+	events := []FileEvent{EventWrite, EventCreate, EventRemove}
 
 	// Warm up cache
 	for _, mainInputFileRelativePath := range mainFilePaths {
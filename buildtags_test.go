@@ -0,0 +1,26 @@
+package godepfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetBuildTags(t *testing.T) {
+	finder := New("testproject")
+	finder.SetBuildTags([]string{"integration", "production"})
+
+	program, args := finder.listCommand("./...")
+	if program != "go" {
+		t.Errorf("expected program go, got %s", program)
+	}
+	expected := []string{"list", "-tags", "integration,production", "./..."}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected args %v, got %v", expected, args)
+	}
+	if tags := finder.buildContext().BuildTags; !reflect.DeepEqual(tags, []string{"integration", "production"}) {
+		t.Errorf("expected the import context to use the tags, got %v", tags)
+	}
+	if _, err := finder.listPackages("./..."); err != nil {
+		t.Fatalf("listPackages failed: %v", err)
+	}
+}
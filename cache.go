@@ -1,28 +1,42 @@
 package godepfind
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"go/build"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"sort"
+	"time"
 )
 
 // updateCacheForFile updates cache based on file events
-func (g *GoDepFind) updateCacheForFile(filePath, event string) error {
+func (g *GoDepFind) updateCacheForFile(filePath string, event FileEvent) error {
+	event, err := normalizeEvent(event)
+	if err != nil {
+		return err
+	}
 	// Initialize cache if needed
 	if err := g.ensureCacheInitialized(); err != nil {
 		return err
 	}
+	// Module files can reshape the whole package graph
+	if isModuleFile(filePath) {
+		return g.rebuildCache()
+	}
 
 	switch event {
-	case "write":
-		// Invalidate only the package containing the file
-		return g.invalidatePackageCache(filePath)
-	case "create":
+	case EventWrite:
+		return g.handleFileWrite(filePath)
+	case EventCreate:
 		// Re-scan dependencies of the parent package + update fileToPackage mapping
 		return g.handleFileCreate(filePath)
-	case "remove":
+	case EventRemove: // also "delete", normalized above
 		// Invalidate dependencies pointing to that file + remove from fileToPackage
 		return g.handleFileRemove(filePath)
-	case "rename":
+	case EventRename:
 		// Treat as remove + create sequence
 		if err := g.handleFileRemove(filePath); err != nil {
 			return err
@@ -33,6 +47,68 @@ func (g *GoDepFind) updateCacheForFile(filePath, event string) error {
 	return nil
 }
 
+// DuplicateFileAttributions returns the files that appear in the GoFiles of
+// more than one package (e.g. a file symlinked into two directories), mapping
+// the file's real absolute path to the sorted packages claiming it. Such files
+// can only keep one entry in the path-to-package mapping.
+func (g *GoDepFind) DuplicateFileAttributions() (map[string][]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	result := make(map[string][]string, len(g.duplicateFiles))
+	for file, pkgs := range g.duplicateFiles {
+		result[file] = append([]string{}, pkgs...)
+	}
+	return result, nil
+}
+
+// Reset drops the cache so its memory can be reclaimed; the next query
+// rebuilds it from scratch. Configuration (tags, handlers, asset roots, ...)
+// is kept. Reset waits for in-flight queries and blocks new ones while it
+// runs, so it is safe to call while other goroutines use the finder.
+func (g *GoDepFind) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cachedModule = false
+	g.packageCache = make(map[string]*build.Package)
+	g.dependencyGraph = make(map[string][]string)
+	g.reverseDeps = make(map[string][]string)
+	g.filePathToPackage = make(map[string]string)
+	g.fileToPackages = make(map[string][]string)
+	g.fileToTestPackages = make(map[string][]string)
+	g.mainPackages = []string{}
+	g.lineCounts = make(map[string]int)
+	g.importHashes = make(map[string]uint64)
+	g.duplicateFiles = nil
+	g.externalPackages = nil
+	g.resolution = nil
+	g.reachable = nil
+	g.nowReachable = nil
+	g.nowUnreachable = nil
+	g.invalidateListMemo()
+}
+
+// lockQuery acquires the lock for a read-only query: the read lock when the
+// cache is built, or the write lock while it is (re)built. The returned
+// function releases whichever lock was taken.
+func (g *GoDepFind) lockQuery() (unlock func(), err error) {
+	g.mu.RLock()
+	if g.cachedModule && !g.cacheStale() {
+		return g.mu.RUnlock, nil
+	}
+	g.mu.RUnlock()
+
+	g.mu.Lock()
+	g.refreshIfStale()
+	if err := g.ensureCacheInitialized(); err != nil {
+		g.mu.Unlock()
+		return nil, err
+	}
+	return g.mu.Unlock, nil
+}
+
 // ensureCacheInitialized initializes cache if not already done (lazy loading)
 func (g *GoDepFind) ensureCacheInitialized() error {
 	if !g.cachedModule {
@@ -41,6 +117,52 @@ func (g *GoDepFind) ensureCacheInitialized() error {
 	return nil
 }
 
+// listedPackages returns the imported packages matched by a go list pattern.
+// Results are memoized per pattern until the package set may change (cache
+// rebuild, file created or removed), so lookups falling back to a full scan
+// right after a rebuild don't run go list again.
+func (g *GoDepFind) listedPackages(pattern string) (map[string]*build.Package, error) {
+	g.listMu.Lock()
+	packages, memoized := g.listMemo[pattern]
+	g.listMu.Unlock()
+	if memoized && g.cachedModule {
+		return packages, nil
+	}
+
+	paths, err := g.listPackages(pattern)
+	if err != nil {
+		return nil, err
+	}
+	packages, err = g.getPackages(paths)
+	if err != nil {
+		return nil, err
+	}
+	g.memoizeListedPackages(pattern, packages)
+	return packages, nil
+}
+
+// memoizeListedPackages stores a copy of the packages listed for pattern, so
+// later cache edits don't alter the memo
+func (g *GoDepFind) memoizeListedPackages(pattern string, packages map[string]*build.Package) {
+	memo := make(map[string]*build.Package, len(packages))
+	for pkgPath, pkg := range packages {
+		memo[pkgPath] = pkg
+	}
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+	if g.listMemo == nil {
+		g.listMemo = make(map[string]map[string]*build.Package)
+	}
+	g.listMemo[pattern] = memo
+}
+
+// invalidateListMemo drops the memoized go list results
+func (g *GoDepFind) invalidateListMemo() {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+	g.listMemo = nil
+}
+
 // invalidatePackageCache invalidates cache for a specific package
 func (g *GoDepFind) invalidatePackageCache(filePath string) error {
 	// Find the package containing this file
@@ -53,15 +175,24 @@ func (g *GoDepFind) invalidatePackageCache(filePath string) error {
 	delete(g.packageCache, pkg)
 	delete(g.dependencyGraph, pkg)
 	delete(g.reverseDeps, pkg)
+	delete(g.lineCounts, pkg)
+	delete(g.importHashes, pkg)
+
+	// Remove the package from the importer lists of everything it imported,
+	// so no stale reverse edges survive
+	for dep, importers := range g.reverseDeps {
+		if remaining := removeAllStrings(importers, pkg); len(remaining) > 0 {
+			g.reverseDeps[dep] = remaining
+		} else {
+			delete(g.reverseDeps, dep)
+		}
+	}
 
-	// Remove from other packages' dependency lists
-	for otherPkg := range g.dependencyGraph {
-		deps := g.dependencyGraph[otherPkg]
-		for i, dep := range deps {
-			if dep == pkg {
-				g.dependencyGraph[otherPkg] = append(deps[:i], deps[i+1:]...)
-				break
-			}
+	// Remove from other packages' dependency lists. A new slice is stored:
+	// editing in place would write through to a cached package's Imports
+	for otherPkg, deps := range g.dependencyGraph {
+		if contains(deps, pkg) {
+			g.dependencyGraph[otherPkg] = removeAllStrings(deps, pkg)
 		}
 	}
 	return nil
@@ -77,11 +208,14 @@ func (g *GoDepFind) invalidatePackageCacheOnly(filePath string) error {
 
 	// Only remove from packageCache, preserve dependencyGraph and reverseDeps
 	delete(g.packageCache, pkg)
+	delete(g.lineCounts, pkg)
+	delete(g.importHashes, pkg)
 	return nil
 }
 
 // handleFileCreate handles file creation events
 func (g *GoDepFind) handleFileCreate(filePath string) error {
+	g.invalidateListMemo() // the package set may have changed
 	// filePath is now always required and contains full path
 	pkg, err := g.findPackageContainingFileByPath(filePath)
 	if err != nil {
@@ -91,39 +225,266 @@ func (g *GoDepFind) handleFileCreate(filePath string) error {
 	if pkg != "" {
 		// Update path mapping
 		if absPath, err := filepath.Abs(filePath); err == nil {
-			g.filePathToPackage[absPath] = pkg
+			g.filePathToPackage[g.pathKey(absPath)] = pkg
 		}
 
 		// Add to filename mapping (don't overwrite, append if not exists)
-		fileName := filepath.Base(filePath)
+		fileName := g.pathKey(filepath.Base(filePath))
 		if !contains(g.fileToPackages[fileName], pkg) {
 			g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkg)
+			sort.Strings(g.fileToPackages[fileName])
+		}
+		g.repairFileAttribution(fileName, pkg)
+
+		if err := g.invalidatePackageCache(filePath); err != nil {
+			return err
 		}
+		g.refreshPackage(pkg, "")
+	}
+	if g.autoRefresh {
+		g.recordTreeModTime() // the event accounts for the directory change
+	}
+	return nil
+}
 
-		return g.invalidatePackageCache(filePath)
+// handleFileWrite handles file write events: the package containing the file
+// is re-imported, and its edges are only invalidated and rebuilt when the
+// edit changed its imports (or files). Body-only edits leave the graph alone.
+func (g *GoDepFind) handleFileWrite(filePath string) error {
+	pkg, err := g.findPackageContainingFileByPath(filePath)
+	if err != nil || pkg == "" {
+		return nil
+	}
+	g.updateStats.WriteEvents++
+	defer g.notifyUpdate()
+	if g.reimportKeepsImports(pkg) {
+		g.updateStats.ImportsUnchanged++
+		return nil
 	}
+	if err := g.invalidatePackageCache(filePath); err != nil {
+		return err
+	}
+	g.refreshPackage(pkg, "")
 	return nil
 }
 
+// reimportKeepsImports re-imports a cached package and, when its importsHash
+// is unchanged, replaces the cached package in place and reports true. The
+// hash is compared with that of the package's last go/build import, never
+// with one of go list output: the loaders can disagree on the same tree
+// (GOFLAGS tags, cgo's implicit imports), so a package loaded by a rebuild
+// takes the slow path on its first write.
+func (g *GoDepFind) reimportKeepsImports(pkgPath string) bool {
+	baseline, imported := g.importHashes[pkgPath]
+	if !imported || g.packageCache[pkgPath] == nil {
+		return false
+	}
+	dir, ok := g.currentResolution().importPathDir(pkgPath)
+	if !ok {
+		return false
+	}
+	pkg, err := g.buildContext().ImportDir(g.rootRelativeDir(dir), 0)
+	if err != nil || importsHash(pkg) != baseline {
+		return false
+	}
+	g.packageCache[pkgPath] = pkg
+	delete(g.lineCounts, pkgPath)
+	return true
+}
+
 // handleFileRemove handles file removal events
 func (g *GoDepFind) handleFileRemove(filePath string) error {
+	g.invalidateListMemo() // the package set may have changed
 	// Remove from path mapping
 	if filePath != "" {
 		if absPath, err := filepath.Abs(filePath); err == nil {
-			delete(g.filePathToPackage, absPath)
+			delete(g.filePathToPackage, g.pathKey(absPath))
 		}
 	}
 
 	// Remove from filename mapping requires package lookup first
+	var pkg string
 	if filePath != "" {
-		pkg, _ := g.findPackageContainingFileByPath(filePath)
+		pkg, _ = g.findPackageContainingFileByPath(filePath)
 		if pkg != "" {
-			fileName := filepath.Base(filePath)
+			fileName := g.pathKey(filepath.Base(filePath))
 			g.fileToPackages[fileName] = removeString(g.fileToPackages[fileName], pkg)
 		}
 	}
 
-	return g.invalidatePackageCache(filePath)
+	if err := g.invalidatePackageCache(filePath); err != nil {
+		return err
+	}
+	if pkg != "" {
+		g.refreshPackage(pkg, filePath)
+	}
+	if g.autoRefresh {
+		g.recordTreeModTime() // the event accounts for the directory change
+	}
+	return nil
+}
+
+// refreshPackage re-imports a single package after one of its files was
+// created or removed: its cache entry and import edges are restored, and it is
+// registered or unregistered as a main package by its name, so a new command
+// is recognized without rebuilding the whole cache. A package left without Go
+// files is unregistered; one that doesn't parse (e.g. a file being written)
+// is left for the next event. removedFile, when not empty, is left out of the
+// package even if the remove event arrives before the file is gone from disk.
+func (g *GoDepFind) refreshPackage(pkgPath, removedFile string) {
+	dir, ok := g.currentResolution().importPathDir(pkgPath)
+	if !ok {
+		return
+	}
+	ctx := g.buildContext()
+	if removedFile != "" {
+		removedName := filepath.Base(removedFile)
+		ctx.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return nil, err
+			}
+			infos := make([]fs.FileInfo, 0, len(entries))
+			for _, entry := range entries {
+				if entry.Name() == removedName {
+					continue
+				}
+				if info, err := entry.Info(); err == nil {
+					infos = append(infos, info)
+				}
+			}
+			return infos, nil
+		}
+	}
+	pkg, err := ctx.ImportDir(g.rootRelativeDir(dir), 0)
+	if err != nil {
+		var noGo *build.NoGoError
+		if errors.As(err, &noGo) {
+			g.mainPackages = removeString(g.mainPackages, pkgPath)
+		}
+		return
+	}
+	if g.ignoredPackage(pkg) {
+		return
+	}
+
+	if pkg.Name == "main" {
+		if !g.isMainPackage(pkgPath) {
+			g.mainPackages = append(g.mainPackages, pkgPath)
+		}
+	} else {
+		g.mainPackages = removeString(g.mainPackages, pkgPath)
+	}
+
+	g.packageCache[pkgPath] = pkg
+	g.importHashes[pkgPath] = importsHash(pkg)
+	deps := append([]string(nil), pkg.Imports...) // the graph is edited in place, pkg.Imports must not be
+	if g.excludeStdlib {
+		deps = make([]string, 0, len(pkg.Imports))
+		for _, imp := range pkg.Imports {
+			if !g.skipsStdlibImport(imp, g.packageCache, g.externalPackages) {
+				deps = append(deps, imp)
+			}
+		}
+	}
+	g.dependencyGraph[pkgPath] = deps
+	g.mapTestFiles(pkgPath, pkg)
+	importers := deps
+	if testImports := g.enabledTestImports(pkg); len(testImports) > 0 {
+		importers = append(append([]string{}, importers...), testImports...)
+	}
+	for _, imp := range importers {
+		if g.skipsStdlibImport(imp, g.packageCache, g.externalPackages) {
+			continue
+		}
+		if !contains(g.reverseDeps[imp], pkgPath) {
+			g.reverseDeps[imp] = append(g.reverseDeps[imp], pkgPath)
+		}
+	}
+
+	// Restore the edges of the packages importing pkgPath, dropped when it was
+	// invalidated: their sources didn't change
+	for otherPath, other := range g.packageCache {
+		if other == nil || otherPath == pkgPath {
+			continue
+		}
+		if contains(other.Imports, pkgPath) && !contains(g.dependencyGraph[otherPath], pkgPath) {
+			g.dependencyGraph[otherPath] = append(g.dependencyGraph[otherPath], pkgPath)
+			sort.Strings(g.dependencyGraph[otherPath])
+		}
+		imports := other.Imports
+		if testImports := g.enabledTestImports(other); len(testImports) > 0 {
+			imports = append(append([]string{}, imports...), testImports...)
+		}
+		if contains(imports, pkgPath) && !contains(g.reverseDeps[pkgPath], otherPath) {
+			g.reverseDeps[pkgPath] = append(g.reverseDeps[pkgPath], otherPath)
+		}
+	}
+}
+
+// mapTestFiles records the _test.go files of pkg in fileToTestPackages,
+// replacing the files previously recorded for pkgPath
+func (g *GoDepFind) mapTestFiles(pkgPath string, pkg *build.Package) {
+	for path, pkgs := range g.fileToTestPackages {
+		if contains(pkgs, pkgPath) {
+			if pkgs = removeString(pkgs, pkgPath); len(pkgs) == 0 {
+				delete(g.fileToTestPackages, path)
+			} else {
+				g.fileToTestPackages[path] = pkgs
+			}
+		}
+	}
+	for _, files := range [][]string{pkg.TestGoFiles, pkg.XTestGoFiles} {
+		for _, file := range files {
+			absPath, err := filepath.Abs(filepath.Join(pkg.Dir, file))
+			if err != nil || g.ignoredPath(absPath) {
+				continue
+			}
+			key := g.pathKey(absPath)
+			if !contains(g.fileToTestPackages[key], pkgPath) {
+				g.fileToTestPackages[key] = append(g.fileToTestPackages[key], pkgPath)
+			}
+		}
+	}
+}
+
+// repairFileAttribution drops from the filename mapping every package other
+// than pkg whose directory no longer contains fileName. A file moved between
+// packages (e.g. when splitting a package) may leave its old package behind
+// if the remove event is lost or processed after the old package was
+// invalidated.
+func (g *GoDepFind) repairFileAttribution(fileName, pkg string) {
+	var kept []string
+	for _, candidate := range g.fileToPackages[fileName] {
+		if candidate == pkg || g.packageHasFile(candidate, fileName) {
+			kept = append(kept, candidate)
+		}
+	}
+	g.fileToPackages[fileName] = kept
+}
+
+// packageHasFile reports whether one of the known directories of pkgPath
+// contains fileName on disk. Packages with no known directory are assumed to
+// still contain it.
+func (g *GoDepFind) packageHasFile(pkgPath, fileName string) bool {
+	var dirs []string
+	if pkg := g.packageCache[pkgPath]; pkg != nil {
+		dirs = append(dirs, pkg.Dir)
+	}
+	for path, owner := range g.filePathToPackage {
+		if owner == pkgPath && !contains(dirs, filepath.Dir(path)) {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+	if len(dirs) == 0 {
+		return true
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, fileName)); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
 // Helper functions
@@ -145,32 +506,101 @@ func removeString(slice []string, item string) []string {
 	return slice
 }
 
+// removeAllStrings returns a new slice without any occurrence of item
+func removeAllStrings(slice []string, item string) []string {
+	result := make([]string, 0, len(slice))
+	for _, s := range slice {
+		if s != item {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// RebuildCacheContext rebuilds the whole cache, killing go list and stopping
+// package imports when ctx is cancelled. A cancelled rebuild returns
+// ctx.Err() and leaves the previous cache untouched.
+func (g *GoDepFind) RebuildCacheContext(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rebuildCacheContext(ctx)
+}
+
 // rebuildCache rebuilds the entire cache from scratch
 func (g *GoDepFind) rebuildCache() error {
-	// 1. Get all packages
-	allPaths, err := g.listPackages("./...")
-	if err != nil {
-		return fmt.Errorf("failed to list packages: %w", err)
-	}
+	return g.rebuildCacheContext(context.Background())
+}
 
+// rebuildCacheContext rebuilds the cache. Everything that can be cancelled
+// runs before the cache is modified, so a cancellation leaves it untouched.
+func (g *GoDepFind) rebuildCacheContext(ctx context.Context) error {
+	// 1. Get all packages, resolved consistently with the module mode. The
+	// tree time is taken first, so changes made during the rebuild count.
+	var treeModTime time.Time
+	if g.autoRefresh {
+		treeModTime, _ = g.treeModTime()
+	}
+	previousResolution := g.resolution
+	g.resolution = g.detectResolution()
+	fail := func(step string, err error) error {
+		g.resolution = previousResolution
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%s: %w", step, err)
+	}
 	// 2. Build package cache
-	packages, err := g.getPackages(allPaths)
+	allPaths, packages, embeds, err := g.listAndImportPackages(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get packages: %w", err)
+		return fail("failed to load packages", err)
+	}
+	ignored := make(map[string]bool) // listed packages dropped by the ignore patterns
+	for _, pkgPath := range allPaths {
+		if _, kept := packages[pkgPath]; !kept {
+			ignored[pkgPath] = true
+		}
+	}
+	g.loadModulePackagesAboveRoot(ctx, packages, ignored)
+	external := make(map[string]*build.Package)
+	if g.includeExternal {
+		external = g.loadExternalPackages(ctx, packages, ignored)
+	}
+	if ctx.Err() != nil {
+		g.resolution = previousResolution
+		return ctx.Err()
 	}
 	g.packageCache = packages
+	g.embedFiles = embeds
+	g.invalidateListMemo()
+	g.memoizeListedPackages("./...", packages)
+	g.lineCounts = make(map[string]int)
+	g.importHashes = make(map[string]uint64)
 
 	// 3. Build dependency graph and reverse dependencies
 	g.dependencyGraph = make(map[string][]string)
 	g.reverseDeps = make(map[string][]string)
 
+	skipImport := func(imp string) bool {
+		return ignored[imp] || g.skipsStdlibImport(imp, packages, external)
+	}
 	for pkgPath, pkg := range packages {
 		if pkg != nil {
-			// Store dependencies
-			g.dependencyGraph[pkgPath] = pkg.Imports
+			// Store dependencies, without edges to ignored (or excluded
+			// standard library) packages. The graph gets its own copy: it is
+			// edited in place while pkg.Imports must stay intact
+			deps := append([]string(nil), pkg.Imports...)
+			if len(ignored) > 0 || g.excludeStdlib {
+				deps = make([]string, 0, len(pkg.Imports))
+				for _, imp := range pkg.Imports {
+					if !skipImport(imp) {
+						deps = append(deps, imp)
+					}
+				}
+			}
+			g.dependencyGraph[pkgPath] = deps
 
 			// Build reverse dependencies
-			for _, imp := range pkg.Imports {
+			for _, imp := range deps {
 				if g.reverseDeps[imp] == nil {
 					g.reverseDeps[imp] = []string{}
 				}
@@ -178,56 +608,117 @@ func (g *GoDepFind) rebuildCache() error {
 			}
 
 			// Include test imports if enabled
-			if g.testImports {
-				for _, imp := range pkg.TestImports {
-					if g.reverseDeps[imp] == nil {
-						g.reverseDeps[imp] = []string{}
-					}
-					g.reverseDeps[imp] = append(g.reverseDeps[imp], pkgPath)
+			for _, imp := range g.enabledTestImports(pkg) {
+				if skipImport(imp) {
+					continue
 				}
-				for _, imp := range pkg.XTestImports {
-					if g.reverseDeps[imp] == nil {
-						g.reverseDeps[imp] = []string{}
-					}
-					g.reverseDeps[imp] = append(g.reverseDeps[imp], pkgPath)
+				if g.reverseDeps[imp] == nil {
+					g.reverseDeps[imp] = []string{}
+				}
+				g.reverseDeps[imp] = append(g.reverseDeps[imp], pkgPath)
+			}
+		}
+	}
+
+	// Extend the graph past the module boundary when external modules are included
+	g.externalPackages = external
+	for pkgPath, pkg := range g.externalPackages {
+		deps := append([]string(nil), pkg.Imports...)
+		if g.excludeStdlib {
+			deps = make([]string, 0, len(pkg.Imports))
+			for _, imp := range pkg.Imports {
+				if !skipImport(imp) {
+					deps = append(deps, imp)
 				}
 			}
 		}
+		g.dependencyGraph[pkgPath] = deps
+		for _, imp := range deps {
+			g.reverseDeps[imp] = append(g.reverseDeps[imp], pkgPath)
+		}
 	}
 
 	// 4. Build file-to-package mappings
 	g.filePathToPackage = make(map[string]string)
 	g.fileToPackages = make(map[string][]string)
+	g.fileToTestPackages = make(map[string][]string)
+	attributions := make(map[string][]string) // real file path -> packages listing it
+	realAliases := make(map[string]string)    // real file path -> package reached through a symlink
+	mapFile := func(pkgPath, dir, file string) {
+		// Absolute path mapping (unique)
+		absPath := filepath.Join(dir, file)
+		if abs, err := filepath.Abs(absPath); err == nil && g.ignoredPath(abs) {
+			return // ignored file (e.g. generated code) of a kept package
+		}
+		g.filePathToPackage[g.pathKey(absPath)] = pkgPath
+
+		// Filename mapping (may have multiple packages)
+		fileName := g.pathKey(filepath.Base(file))
+		g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkgPath)
+
+		// Track the real file behind the path to detect files shared by several
+		// packages (symlinked or listed twice)
+		realPath, err := canonicalPath(absPath)
+		if err != nil {
+			realPath = absPath
+		}
+		if realPath != absPath {
+			realAliases[realPath] = pkgPath
+		}
+		if !contains(attributions[realPath], pkgPath) {
+			attributions[realPath] = append(attributions[realPath], pkgPath)
+		}
+	}
 	for pkgPath, pkg := range packages {
 		if pkg != nil {
 			// Map Go files by absolute path AND collect by filename
 			for _, file := range pkg.GoFiles {
-				// Absolute path mapping (unique)
-				absPath := filepath.Join(pkg.Dir, file)
-				g.filePathToPackage[absPath] = pkgPath
+				mapFile(pkgPath, pkg.Dir, file)
+			}
 
-				// Filename mapping (may have multiple packages)
-				fileName := filepath.Base(file)
-				g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkgPath)
+			// Embedded files are mapped by path only: they have no package
+			// clause, so the filename fallback must not guess them
+			if g.trackEmbeds {
+				for _, file := range g.packageEmbeds(pkgPath, pkg) {
+					if !g.ignoredPath(file) {
+						if _, exists := g.filePathToPackage[g.pathKey(file)]; !exists {
+							g.filePathToPackage[g.pathKey(file)] = pkgPath
+						}
+					}
+				}
 			}
 
+			// Test files are always tracked apart, so IsTestFile works whether
+			// or not they are merged into the production mappings
+			g.mapTestFiles(pkgPath, pkg)
+
 			// Map test files if enabled
-			if g.testImports {
-				for _, file := range pkg.TestGoFiles {
-					absPath := filepath.Join(pkg.Dir, file)
-					g.filePathToPackage[absPath] = pkgPath
-					fileName := filepath.Base(file)
-					g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkgPath)
-				}
-				for _, file := range pkg.XTestGoFiles {
-					absPath := filepath.Join(pkg.Dir, file)
-					g.filePathToPackage[absPath] = pkgPath
-					fileName := filepath.Base(file)
-					g.fileToPackages[fileName] = append(g.fileToPackages[fileName], pkgPath)
-				}
+			for _, file := range g.enabledTestGoFiles(pkg) {
+				mapFile(pkgPath, pkg.Dir, file)
 			}
 		}
 	}
+	// Map the files of locally replaced modules outside the root too
+	g.mapReplacedPackages(packages, external)
+
+	// Sort the filename mappings so lookups by name don't depend on map order
+	for _, pkgs := range g.fileToPackages {
+		sort.Strings(pkgs)
+	}
+	// Map the real paths behind symlinked directories too, without overriding
+	// a package that lists the real file itself
+	for realPath, pkgPath := range realAliases {
+		if _, exists := g.filePathToPackage[g.pathKey(realPath)]; !exists {
+			g.filePathToPackage[g.pathKey(realPath)] = pkgPath
+		}
+	}
+	g.duplicateFiles = make(map[string][]string)
+	for realPath, pkgs := range attributions {
+		if len(pkgs) > 1 {
+			sort.Strings(pkgs)
+			g.duplicateFiles[realPath] = pkgs
+		}
+	}
 
 	// 5. Identify main packages
 	g.mainPackages = []string{}
@@ -237,8 +728,14 @@ func (g *GoDepFind) rebuildCache() error {
 		}
 	}
 
-	// 6. Mark cache as initialized
+	// 6. Record which packages became reachable/unreachable from the mains
+	g.updateReachability()
+
+	// 7. Mark cache as initialized
+	g.builtModTime = treeModTime
 	g.cachedModule = true
+	g.updateStats.Rebuilds++
+	g.notifyUpdate()
 
 	return nil
 }
@@ -260,13 +757,13 @@ func (g *GoDepFind) isSameFile(filePath1, filePath2 string) bool {
 
 	// If one is relative, try to make it absolute relative to rootDir
 	if !filepath.IsAbs(filePath2) {
-		abs2FromRoot, err := filepath.Abs(filepath.Join(g.rootDir, filePath2))
+		abs2FromRoot, err := filepath.Abs(g.rootPath(filePath2))
 		if err == nil {
 			abs2 = abs2FromRoot
 		}
 	}
 	if !filepath.IsAbs(filePath1) {
-		abs1FromRoot, err := filepath.Abs(filepath.Join(g.rootDir, filePath1))
+		abs1FromRoot, err := filepath.Abs(g.rootPath(filePath1))
 		if err == nil {
 			abs1 = abs1FromRoot
 		}
@@ -276,25 +773,33 @@ func (g *GoDepFind) isSameFile(filePath1, filePath2 string) bool {
 }
 
 // updateCacheForFileWithContext updates cache based on file events and handler context
-func (g *GoDepFind) updateCacheForFileWithContext(filePath, event, handlerMainFile string) error {
+func (g *GoDepFind) updateCacheForFileWithContext(filePath string, event FileEvent, handlerMainFile string) error {
+	event, err := normalizeEvent(event)
+	if err != nil {
+		return err
+	}
 	// Initialize cache if needed
 	if err := g.ensureCacheInitialized(); err != nil {
 		return err
 	}
+	// Module files can reshape the whole package graph
+	if isModuleFile(filePath) {
+		return g.rebuildCache()
+	}
 
 	switch event {
-	case "write":
+	case EventWrite:
 		// Only rescan fully if the modified file is the handler's mainInputFileRelativePath
 		if handlerMainFile != "" && g.isSameFile(filePath, handlerMainFile) {
 			return g.rescanMainPackageDependencies(filePath)
 		}
 		// For non-main files, only invalidate package cache (don't touch dependency graph)
 		return g.invalidatePackageCacheOnly(filePath)
-	case "create":
+	case EventCreate:
 		return g.handleFileCreate(filePath)
-	case "remove":
+	case EventRemove: // also "delete", normalized above
 		return g.handleFileRemove(filePath)
-	case "rename":
+	case EventRename:
 		if err := g.handleFileRemove(filePath); err != nil {
 			return err
 		}
@@ -304,16 +809,52 @@ func (g *GoDepFind) updateCacheForFileWithContext(filePath, event, handlerMainFi
 	return nil
 }
 
-// rescanMainPackageDependencies rescans only the dependencies of the main package
-func (g *GoDepFind) rescanMainPackageDependencies(mainInputFileRelativePath string) error {
-	// Simpler and robust: rebuild entire cache for module when main changes.
-	// This ensures dependencyGraph, file mappings and mainPackages stay consistent.
-	if err := g.rebuildCache(); err != nil {
+// rescanMainPackageDependencies rescans only the dependencies of the main
+// package containing mainFilePath: the package is re-imported and its edges
+// replaced, without listing the module again. The cache is rebuilt in full
+// when the package cannot be re-imported or now imports a package that was
+// not cached before (e.g. a package created since the last rebuild).
+func (g *GoDepFind) rescanMainPackageDependencies(mainFilePath string) error {
+	pkgPath, err := g.findPackageContainingFileByPath(mainFilePath)
+	if err != nil || pkgPath == "" {
+		return g.rebuildCache()
+	}
+	var previousImports []string
+	if previous := g.packageCache[pkgPath]; previous != nil {
+		previousImports = previous.Imports
+	}
+
+	if err := g.invalidatePackageCache(mainFilePath); err != nil {
 		return err
 	}
+	g.refreshPackage(pkgPath, "")
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return g.rebuildCache()
+	}
+	for _, imp := range pkg.Imports {
+		if !contains(previousImports, imp) && !g.cachesImport(imp) {
+			return g.rebuildCache()
+		}
+	}
+	g.updateReachability()
 	return nil
 }
 
+// cachesImport reports whether a rebuild would have nothing to add to the
+// cache for imp: it is cached already, or is a package that is never cached
+// (the standard library, or another module when external modules are not
+// included)
+func (g *GoDepFind) cachesImport(imp string) bool {
+	if g.packageCache[imp] != nil || g.externalPackages[imp] != nil {
+		return true
+	}
+	if _, inProject := g.currentResolution().importPathDir(imp); inProject {
+		return false
+	}
+	return !g.includeExternal || isStandardImportPath(imp)
+}
+
 // cachedImports returns true if path imports targetPkg transitively using cache
 func (g *GoDepFind) cachedImports(path, targetPkg string, visited map[string]bool) bool {
 	if visited[path] {
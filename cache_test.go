@@ -1,6 +1,7 @@
 package godepfind
 
 import (
+	"path/filepath"
 	"testing"
 )
 
@@ -161,3 +162,74 @@ func TestGoFileComesFromMainWithCache(t *testing.T) {
 		t.Errorf("Expected empty result for non-existent file, got %v", mains3)
 	}
 }
+
+func TestResetDropsCache(t *testing.T) {
+	finder := New("testproject")
+	finder.SetTestImports(true)
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+
+	finder.Reset()
+	if finder.cachedModule {
+		t.Error("expected cache to be marked uninitialized after Reset")
+	}
+	if len(finder.packageCache) != 0 || len(finder.dependencyGraph) != 0 || len(finder.reverseDeps) != 0 ||
+		len(finder.filePathToPackage) != 0 || len(finder.fileToPackages) != 0 || len(finder.mainPackages) != 0 {
+		t.Error("expected every cache map to be empty after Reset")
+	}
+	if !finder.testImports {
+		t.Error("expected configuration to survive Reset")
+	}
+
+	// The next query rebuilds the cache
+	mains, err := finder.GoFileComesFromMain("module3.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain failed: %v", err)
+	}
+	if len(mains) != 1 || mains[0] != "testproject/appCwasm" {
+		t.Errorf("expected module3.go to come from appCwasm, got %v", mains)
+	}
+}
+
+func TestMatchesHandlerFileDirectories(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":             "module testmod\n\ngo 1.21\n",
+		"pwa/main.go":        "package main\n\nfunc main() {}\n",
+		"test/pwa/main.go":   "package main\n\nfunc main() {}\n",
+		"myserver/main.go":   "package main\n\nfunc main() {}\n",
+		"server/main.go":     "package main\n\nfunc main() {}\n",
+		"cmd/server/main.go": "package main\n\nfunc main() {}\n",
+	})
+	finder := New(root)
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		mainPkg     string
+		handlerFile string
+		expected    bool
+	}{
+		{"pwa owns pwa", "testmod/pwa", "pwa/main.go", true},
+		{"test/pwa owns test/pwa", "testmod/test/pwa", "test/pwa/main.go", true},
+		{"test/pwa is not pwa", "testmod/test/pwa", "pwa/main.go", false},
+		{"pwa is not test/pwa", "testmod/pwa", "test/pwa/main.go", false},
+		{"absolute handler path", "testmod/test/pwa", filepath.Join(root, "test", "pwa", "main.go"), true},
+		{"absolute handler path of another main", "testmod/pwa", filepath.Join(root, "test", "pwa", "main.go"), false},
+		{"absolute handler path outside root", "testmod/pwa", filepath.Join(t.TempDir(), "pwa", "main.go"), false},
+		{"substring is not a match", "testmod/myserver", "server/main.go", false},
+		{"same base in another directory", "testmod/cmd/server", "server/main.go", false},
+		{"nested server", "testmod/cmd/server", "cmd/server/main.go", true},
+		{"uncached package matches whole elements", "other/test/pwa", "test/pwa/main.go", true},
+		{"uncached package suffix on element boundary", "other/myserver", "server/main.go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := finder.matchesHandlerFile(tt.mainPkg, tt.handlerFile); got != tt.expected {
+				t.Errorf("matchesHandlerFile(%s, %s) = %v, want %v", tt.mainPkg, tt.handlerFile, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package godepfind
+
+import "strings"
+
+// SetCaseInsensitivePaths enables or disables matching file paths and names
+// regardless of case, for case-insensitive filesystems (the macOS and Windows
+// defaults) where a watcher may report "Main.go" for a file cached as
+// "main.go". When enabled, the path and file name mappings are keyed by
+// lowercased paths, so results built from them (e.g. the files of
+// OwnershipDeltaAfterMainEdit or TestFilesForPackage) are lowercased too. Disabled by default.
+// Changing the setting invalidates the cache.
+func (g *GoDepFind) SetCaseInsensitivePaths(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.caseFoldPaths = enabled
+	g.cachedModule = false
+}
+
+// pathKey returns the key of a file path or name in filePathToPackage,
+// fileToPackages and fileToTestPackages: the path itself, or its lowercased form with case
+// insensitive paths
+func (g *GoDepFind) pathKey(path string) string {
+	if g.caseFoldPaths {
+		return strings.ToLower(path)
+	}
+	return path
+}
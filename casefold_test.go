@@ -0,0 +1,82 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCaseInsensitivePaths(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	// The watcher of a case-insensitive filesystem reports another spelling
+	reported := filepath.Join(root, "Internal", "Leaf", "Leaf.go")
+
+	strict := New(root)
+	if pkg, err := strict.PackageForFile(reported); err != nil || pkg != "" {
+		t.Errorf("expected no package for a mismatched case by default, got %q, %v", pkg, err)
+	}
+
+	finder := New(root)
+	finder.SetCaseInsensitivePaths(true)
+	pkg, err := finder.PackageForFile(reported)
+	if err != nil {
+		t.Fatalf("PackageForFile failed: %v", err)
+	}
+	if pkg != "testmod/internal/leaf" {
+		t.Errorf("expected testmod/internal/leaf, got %q", pkg)
+	}
+	mains, err := finder.GoFileComesFromMain("LEAF.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain failed: %v", err)
+	}
+	if !reflect.DeepEqual(mains, []string{"testmod/app"}) {
+		t.Errorf("expected [testmod/app], got %v", mains)
+	}
+	// A create event is resolved even before the reported file can be read
+	owned, err := finder.ThisFileIsMine("app/main.go", reported, EventCreate)
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !owned {
+		t.Error("expected the mismatched-case file to belong to app")
+	}
+	if owned, _ := finder.ThisFileIsMine("other/main.go", reported, EventCreate); owned {
+		t.Error("expected the mismatched-case file not to belong to other")
+	}
+}
+
+func TestCaseInsensitiveTestFiles(t *testing.T) {
+	files := chainModuleFiles()
+	files["internal/leaf/leaf_test.go"] = "package leaf\n"
+	root := writeTestModule(t, files)
+	reported := filepath.Join(root, "Internal", "Leaf", "Leaf_Test.go")
+
+	strict := New(root)
+	if _, err := strict.IsTestFile(reported); err == nil {
+		t.Error("expected a mismatched-case test file to be unknown by default")
+	}
+
+	finder := New(root)
+	finder.SetCaseInsensitivePaths(true)
+	isTest, err := finder.IsTestFile(reported)
+	if err != nil {
+		t.Fatalf("IsTestFile failed: %v", err)
+	}
+	if !isTest {
+		t.Error("expected the mismatched-case file to be a test file")
+	}
+	// A new test file is matched to its package directory regardless of case
+	isTest, err = finder.IsTestFile(filepath.Join(root, "Internal", "Leaf", "New_Test.go"))
+	if err != nil || !isTest {
+		t.Errorf("expected a new test file of a known package, got %v, %v", isTest, err)
+	}
+	testFiles, err := finder.TestFilesForPackage("testmod/internal/leaf")
+	if err != nil {
+		t.Fatalf("TestFilesForPackage failed: %v", err)
+	}
+	want := []string{strings.ToLower(filepath.Join(root, "internal", "leaf", "leaf_test.go"))}
+	if !reflect.DeepEqual(testFiles, want) {
+		t.Errorf("expected lowercased test files %v, got %v", want, testFiles)
+	}
+}
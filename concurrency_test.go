@@ -0,0 +1,54 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentQueriesAndEvents(t *testing.T) {
+	finder := New("testproject")
+	module1, err := filepath.Abs(filepath.Join("testproject", "modules", "module1", "module1.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			isMine, err := finder.ThisFileIsMine("appAserver/main.go", module1, "write")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !isMine {
+				t.Error("expected appAserver to own module1.go")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			mains, err := finder.GoFileComesFromMain("module1.go")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(mains) != 2 {
+				t.Errorf("expected 2 mains importing module1.go, got %v", mains)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := finder.FindReverseDeps("./...", []string{"testproject/modules/module3"}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent call failed: %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package godepfind
+
+// Confidence indicates how reliable an ownership decision is
+type Confidence int
+
+const (
+	// ConfidenceLow means the file's package was guessed from its file name
+	// because its exact path is not known to the cache
+	ConfidenceLow Confidence = iota
+	// ConfidenceHigh means the decision was based on the exact file path
+	// (or the file is the handler's own main file)
+	ConfidenceHigh
+)
+
+// String returns the name of the confidence level
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceHigh:
+		return "high"
+	case ConfidenceLow:
+		return "low"
+	}
+	return "unknown"
+}
@@ -0,0 +1,58 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThisFileIsMineWithConfidence(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+import "testmod/lib"
+
+func main() { lib.Do() }
+`,
+		"lib/util.go": "package lib\n\nfunc Do() {}\n",
+	})
+
+	finder := New(root)
+
+	// Exact-path resolution is high confidence
+	isMine, confidence, err := finder.ThisFileIsMineWithConfidence("app/main.go", filepath.Join(root, "lib", "util.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMineWithConfidence failed: %v", err)
+	}
+	if !isMine || confidence != ConfidenceHigh {
+		t.Errorf("expected owned with high confidence, got %v %s", isMine, confidence)
+	}
+
+	// The handler main file itself is high confidence
+	_, confidence, err = finder.ThisFileIsMineWithConfidence("app/main.go", filepath.Join(root, "app", "main.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMineWithConfidence failed: %v", err)
+	}
+	if confidence != ConfidenceHigh {
+		t.Errorf("expected high confidence for handler main file, got %s", confidence)
+	}
+
+	// A duplicated file name unknown to the cache forces the filename fallback
+	otherDir := filepath.Join(root, "other")
+	if err := os.MkdirAll(otherDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	otherUtil := filepath.Join(otherDir, "util.go")
+	if err := os.WriteFile(otherUtil, []byte("package other\n\nfunc Do() {}\n"), 0644); err != nil {
+		t.Fatalf("write other/util.go: %v", err)
+	}
+
+	_, confidence, err = finder.ThisFileIsMineWithConfidence("app/main.go", otherUtil, "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMineWithConfidence failed: %v", err)
+	}
+	if confidence != ConfidenceLow {
+		t.Errorf("expected low confidence for filename fallback, got %s", confidence)
+	}
+}
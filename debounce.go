@@ -0,0 +1,115 @@
+package godepfind
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer coalesces bursts of file events (as fired by file watchers) and
+// applies them to a GoDepFind cache as a single batched update once no new
+// event has arrived for the configured window.
+//
+// Events for the same file are deduplicated: the latest event wins, except that
+// an EventWrite following an EventCreate keeps the create (the file is still new).
+type Debouncer struct {
+	finder *GoDepFind
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]FileEvent // file path -> coalesced event
+	order   []string             // file paths in arrival order
+	timer   *time.Timer
+	onFlush func(batch map[string]FileEvent, err error)
+}
+
+// NewDebouncer creates a Debouncer that applies events to gdf after window of inactivity
+func NewDebouncer(gdf *GoDepFind, window time.Duration) *Debouncer {
+	return &Debouncer{
+		finder:  gdf,
+		window:  window,
+		pending: make(map[string]FileEvent),
+	}
+}
+
+// OnFlush registers a callback invoked after each batched update with the
+// coalesced events that were applied and the first error encountered, if any
+func (d *Debouncer) OnFlush(fn func(batch map[string]FileEvent, err error)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onFlush = fn
+}
+
+// Add records an event for filePath and restarts the debounce window. Unknown
+// events are reported by the flush that applies them.
+func (d *Debouncer) Add(filePath string, event FileEvent) {
+	if canonical, err := normalizeEvent(event); err == nil {
+		event = canonical
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if prev, exists := d.pending[filePath]; exists {
+		d.pending[filePath] = coalesceEvents(prev, event)
+	} else {
+		d.pending[filePath] = event
+		d.order = append(d.order, filePath)
+	}
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.window, func() { d.Flush() })
+}
+
+// Flush applies the pending events immediately and returns the first error
+func (d *Debouncer) Flush() error {
+	d.mu.Lock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if len(d.order) == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	batch := d.pending
+	order := d.order
+	onFlush := d.onFlush
+	d.pending = make(map[string]FileEvent)
+	d.order = nil
+	d.mu.Unlock()
+
+	var firstErr error
+	d.finder.mu.Lock()
+	for _, filePath := range order {
+		if err := d.finder.updateCacheForFile(filePath, batch[filePath]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	d.finder.mu.Unlock()
+
+	if onFlush != nil {
+		onFlush(batch, firstErr)
+	}
+	return firstErr
+}
+
+// Stop cancels any pending flush without applying the accumulated events
+func (d *Debouncer) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.pending = make(map[string]FileEvent)
+	d.order = nil
+}
+
+// coalesceEvents merges a new event for a file into its pending event
+func coalesceEvents(prev, next FileEvent) FileEvent {
+	if prev == EventCreate && next == EventWrite {
+		return EventCreate
+	}
+	return next
+}
@@ -0,0 +1,95 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDebouncerCoalescesBurst(t *testing.T) {
+	finder := New("testproject")
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+
+	debouncer := NewDebouncer(finder, 50*time.Millisecond)
+	flushes := make(chan map[string]FileEvent, 10)
+	debouncer.OnFlush(func(batch map[string]FileEvent, err error) {
+		if err != nil {
+			t.Errorf("flush error: %v", err)
+		}
+		flushes <- batch
+	})
+
+	module1 := filepath.Join("testproject", "modules", "module1", "module1.go")
+	module2 := filepath.Join("testproject", "modules", "module2", "module2.go")
+
+	debouncer.Add(module2, "create")
+	for i := 0; i < 20; i++ {
+		debouncer.Add(module1, "write")
+		debouncer.Add(module2, "write")
+	}
+
+	select {
+	case batch := <-flushes:
+		if len(batch) != 2 {
+			t.Fatalf("expected 2 coalesced file updates, got %v", batch)
+		}
+		if batch[module1] != "write" {
+			t.Errorf("expected module1 event write, got %s", batch[module1])
+		}
+		if batch[module2] != "create" {
+			t.Errorf("expected create+write to collapse into create, got %s", batch[module2])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("debouncer never flushed")
+	}
+
+	select {
+	case batch := <-flushes:
+		t.Errorf("expected a single coalesced update, got extra flush %v", batch)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestDebouncerStopDiscardsEvents(t *testing.T) {
+	finder := New("testproject")
+	debouncer := NewDebouncer(finder, 20*time.Millisecond)
+	flushed := false
+	debouncer.OnFlush(func(batch map[string]FileEvent, err error) { flushed = true })
+
+	debouncer.Add(filepath.Join("testproject", "appAserver", "main.go"), "write")
+	debouncer.Stop()
+
+	if err := debouncer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if flushed {
+		t.Error("expected Stop to discard pending events")
+	}
+}
+
+func TestDebouncerWriteKeepsImporters(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	leaf := filepath.Join(root, "internal", "leaf", "leaf.go")
+
+	debouncer := NewDebouncer(finder, time.Hour)
+	debouncer.Add(leaf, EventWrite)
+	if err := debouncer.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	// The written package is re-imported and its importers keep their edges
+	affected, err := finder.FindAffectedMains([]string{leaf})
+	if err != nil {
+		t.Fatalf("FindAffectedMains failed: %v", err)
+	}
+	if mains := affected[leaf]; len(mains) != 1 || mains[0] != "testmod/app" {
+		t.Errorf("expected leaf.go to still affect [testmod/app] after a write, got %v", mains)
+	}
+	if importers := finder.reverseDeps["testmod/internal/leaf"]; !contains(importers, "testmod/mid") {
+		t.Errorf("expected testmod/mid to still import leaf, got %v", importers)
+	}
+}
@@ -8,7 +8,14 @@ import (
 
 // DebugThisFileIsMine provides detailed debugging for production issues
 // with ThisFileIsMine returning unexpected results
-func (g *GoDepFind) DebugThisFileIsMine(mainInputFileRelativePath, fileAbsPath, event string) (bool, error) {
+func (g *GoDepFind) DebugThisFileIsMine(mainInputFileRelativePath, fileAbsPath string, event FileEvent) (bool, error) {
+	event, err := normalizeEvent(event)
+	if err != nil {
+		return false, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
 	var log strings.Builder
 
 	log.WriteString("=== DEBUG ThisFileIsMine ===\n")
@@ -26,7 +33,7 @@ func (g *GoDepFind) DebugThisFileIsMine(mainInputFileRelativePath, fileAbsPath,
 
 	// Force cache initialization and show the result
 	log.WriteString("3) Forcing cache initialization:\n")
-	err := g.ensureCacheInitialized()
+	err = g.ensureCacheInitialized()
 	if err != nil {
 		log.WriteString(fmt.Sprintf("   - ERROR during cache initialization: %v\n", err))
 		fmt.Print(log.String())
@@ -50,9 +57,7 @@ func (g *GoDepFind) DebugThisFileIsMine(mainInputFileRelativePath, fileAbsPath,
 		return false, fmt.Errorf("fileAbsPath cannot be empty")
 	}
 
-	if !filepath.IsAbs(fileAbsPath) {
-		fileAbsPath = filepath.Join(g.rootDir, fileAbsPath)
-	}
+	fileAbsPath = g.rootPath(fileAbsPath)
 	absFilePath, err := filepath.Abs(fileAbsPath)
 	if err != nil {
 		log.WriteString(fmt.Sprintf("cannot resolve fileAbsPath to absolute path: %v\n", err))
@@ -77,11 +82,12 @@ func (g *GoDepFind) DebugThisFileIsMine(mainInputFileRelativePath, fileAbsPath,
 		log.WriteString(fmt.Sprintf("   - fileName == handlerFileName: %v\n", fileName == handlerFileName))
 
 		if fileName == handlerFileName {
-			relativeFilePath := strings.TrimPrefix(fileAbsPath, g.rootDir+"/")
+			relativeFilePath, _ := g.relativeToRoot(fileAbsPath)
+			matches := sameRelativePath(relativeFilePath, handlerFile)
 			log.WriteString(fmt.Sprintf("   - relativeFilePath: %s\n", relativeFilePath))
-			log.WriteString(fmt.Sprintf("   - relativeFilePath == handlerFile: %v\n", relativeFilePath == handlerFile))
+			log.WriteString(fmt.Sprintf("   - relativeFilePath == handlerFile: %v\n", matches))
 
-			if relativeFilePath == handlerFile {
+			if matches {
 				// Successful match - don't print debug log
 				return true, nil
 			}
@@ -93,7 +99,7 @@ func (g *GoDepFind) DebugThisFileIsMine(mainInputFileRelativePath, fileAbsPath,
 	var targetPkg string
 
 	// Check filePathToPackage cache
-	if pkg, exists := g.filePathToPackage[fileAbsPath]; exists {
+	if pkg, exists := g.filePathToPackage[g.pathKey(fileAbsPath)]; exists {
 		targetPkg = pkg
 		log.WriteString(fmt.Sprintf("   - found in filePathToPackage[%s]: %s\n", fileAbsPath, pkg))
 	} else {
@@ -106,7 +112,7 @@ func (g *GoDepFind) DebugThisFileIsMine(mainInputFileRelativePath, fileAbsPath,
 	}
 
 	// Check fileToPackages cache
-	packages := g.fileToPackages[fileName]
+	packages := g.fileToPackages[g.pathKey(fileName)]
 	log.WriteString(fmt.Sprintf("   - fileToPackages[%s]: %v\n", fileName, packages))
 
 	if targetPkg == "" && len(packages) > 0 {
@@ -132,28 +138,11 @@ func (g *GoDepFind) DebugThisFileIsMine(mainInputFileRelativePath, fileAbsPath,
 
 		// DEBUG: Let's see what's happening inside matchesHandlerFile
 		log.WriteString("   - DEBUG matchesHandlerFile breakdown:\n")
-		baseName := filepath.Base(targetPkg)
-		handlerFileName := filepath.Base(handlerFile)
-		log.WriteString(fmt.Sprintf("     - baseName (from targetPkg): %s\n", baseName))
-		log.WriteString(fmt.Sprintf("     - handlerFileName: %s\n", handlerFileName))
-		log.WriteString(fmt.Sprintf("     - baseName == handlerFile: %v\n", baseName == handlerFile))
-		log.WriteString(fmt.Sprintf("     - baseName == handlerFileName: %v\n", baseName == handlerFileName))
-
-		handlerBase := strings.TrimSuffix(handlerFileName, filepath.Ext(handlerFileName))
-		log.WriteString(fmt.Sprintf("     - handlerBase (without extension): %s\n", handlerBase))
-
-		if strings.Contains(handlerBase, ".") {
-			parts := strings.Split(handlerBase, ".")
-			log.WriteString(fmt.Sprintf("     - handlerBase parts: %v\n", parts))
-			for _, part := range parts {
-				if part != "main" && part != "" {
-					contains := strings.Contains(targetPkg, part)
-					log.WriteString(fmt.Sprintf("     - strings.Contains(%s, %s): %v\n", targetPkg, part, contains))
-					if contains {
-						log.WriteString(fmt.Sprintf("     - SHOULD MATCH! Found part '%s' in targetPkg\n", part))
-					}
-				}
-			}
+		log.WriteString(fmt.Sprintf("     - handlerDir: %s\n", filepath.Dir(handlerFile)))
+		if pkg := g.packageCache[targetPkg]; pkg != nil {
+			log.WriteString(fmt.Sprintf("     - package dir (exact comparison): %s\n", pkg.Dir))
+		} else {
+			log.WriteString("     - package not cached, suffix comparison on path elements\n")
 		}
 
 		if matches {
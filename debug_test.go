@@ -13,7 +13,7 @@ func TestDebugMatchesHandlerFile(t *testing.T) {
 	// Manual replication of ThisFileIsMine logic
 	mainInputFileRelativePath := "appAserver/main.go"
 	fileAbsPath := "appBcmd/main.go"
-	event := "write"
+	event := EventWrite
 
 	// Step 1: Path resolution (from ThisFileIsMine)
 	if fileAbsPath == "" {
@@ -0,0 +1,65 @@
+package godepfind
+
+// OwnershipCase names the rule of ThisFileIsMine that decided ownership
+type OwnershipCase string
+
+const (
+	CaseOutsideRoot           OwnershipCase = "outside-root"            // the file is outside every root
+	CaseAssetRoot             OwnershipCase = "asset-root"              // routed by a RegisterAssetRoot directory
+	CaseModuleFile            OwnershipCase = "module-file"             // go.mod, go.sum or go.work of the handler's modules
+	CaseInvalidGoFile         OwnershipCase = "invalid-go-file"         // empty or partially written Go file, skipped
+	CaseHandlerMainFile       OwnershipCase = "handler-main-file"       // the file is the handler's own main file
+	CaseImportPathHandler     OwnershipCase = "import-path-handler"     // handler named by the import path of its main
+	CaseHandlerBuild          OwnershipCase = "handler-build"           // decided from the handler's own tags/target
+	CaseNoPackage             OwnershipCase = "no-package"              // the file belongs to no known package
+	CaseBuildConstraints      OwnershipCase = "build-constraints"       // excluded by its package's build constraints
+	CaseMainPackageInDir      OwnershipCase = "main-package-in-dir"     // the file's main package is in the handler's directory
+	CaseMainImportsPackage    OwnershipCase = "main-imports-package"    // the handler main imports the package, or doesn't
+	CaseExcludedFromOwnership OwnershipCase = "excluded-from-ownership" // the package matches an ExcludeFromOwnership pattern
+)
+
+// OwnershipDecision is the trace of one ownership decision: the packages
+// involved, the rule that decided and the fallbacks used on the way
+type OwnershipDecision struct {
+	Owned          bool          `json:"owned"`
+	Confidence     Confidence    `json:"confidence"`
+	File           string        `json:"file"`                      // absolute path of the file
+	TargetPackage  string        `json:"target_package,omitempty"`  // package containing the file
+	HandlerPackage string        `json:"handler_package,omitempty"` // main package of the handler
+	Case           OwnershipCase `json:"case"`
+	Fallbacks      []string      `json:"fallbacks,omitempty"` // e.g. "filename-fallback"
+}
+
+// decide records the outcome of the decision
+func (d *OwnershipDecision) decide(owned bool, confidence Confidence, c OwnershipCase) {
+	d.Owned = owned
+	d.Confidence = confidence
+	d.Case = c
+}
+
+// fallback records a fallback used while deciding, once
+func (d *OwnershipDecision) fallback(name string) {
+	if !contains(d.Fallbacks, name) {
+		d.Fallbacks = append(d.Fallbacks, name)
+	}
+}
+
+// TraceOwnership runs the ownership rules of ThisFileIsMine for a file and
+// returns the decision with the rule that fired, instead of a bare bool, so a
+// wrong routing can be diagnosed from the trace alone. It is a dry run: no
+// event is applied, so the cache is not updated (ThisFileIsMine with an event
+// on the handler's main file rescans its imports first).
+func (g *GoDepFind) TraceOwnership(mainInputFileRelativePath, fileAbsPath string) (*OwnershipDecision, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+
+	decision := &OwnershipDecision{}
+	if err := g.decideOwnership(mainInputFileRelativePath, fileAbsPath, "", decision); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
@@ -0,0 +1,85 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTraceOwnership(t *testing.T) {
+	files := chainModuleFiles()
+	files["app/templates/index.html"] = "<html></html>\n"
+	files["testdata/loose.go"] = "package loose\n" // ignored by go list
+	root := writeTestModule(t, files)
+	finder := New(root)
+	if err := finder.RegisterAssetRoot("app/main.go", "app/templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		handler, file string
+		owned         bool
+		target        string
+		ownership     OwnershipCase
+	}{
+		{"app/main.go", "app/main.go", true, "testmod/app", CaseHandlerMainFile},
+		{"app/main.go", "internal/leaf/leaf.go", true, "testmod/internal/leaf", CaseMainImportsPackage},
+		{"other/main.go", "internal/leaf/leaf.go", false, "testmod/internal/leaf", CaseMainImportsPackage},
+		{"app/main.go", "app/templates/index.html", true, "", CaseAssetRoot},
+		{"testmod/app", "mid/mid.go", true, "testmod/mid", CaseImportPathHandler},
+		{"other/main.go", "testdata/loose.go", false, "", CaseNoPackage},
+	}
+	for _, c := range cases {
+		decision, err := finder.TraceOwnership(c.handler, filepath.Join(root, c.file))
+		if err != nil {
+			t.Fatalf("TraceOwnership(%s, %s) failed: %v", c.handler, c.file, err)
+		}
+		if decision.Owned != c.owned || decision.TargetPackage != c.target || decision.Case != c.ownership {
+			t.Errorf("TraceOwnership(%s, %s) = owned %v, target %q, case %s; want %v, %q, %s",
+				c.handler, c.file, decision.Owned, decision.TargetPackage, decision.Case, c.owned, c.target, c.ownership)
+		}
+		mine, err := finder.ThisFileIsMine(c.handler, filepath.Join(root, c.file), EventWrite)
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s) failed: %v", c.handler, c.file, err)
+		}
+		if mine != decision.Owned {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, but the trace says %v", c.handler, c.file, mine, decision.Owned)
+		}
+	}
+
+	decision, err := finder.TraceOwnership("other/main.go", filepath.Join(root, "mid", "mid.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.HandlerPackage != "testmod/other" || decision.File != filepath.Join(root, "mid", "mid.go") {
+		t.Errorf("trace = %+v, want handler package testmod/other and the absolute file", decision)
+	}
+
+	if _, err := finder.TraceOwnership("missing/main.go", filepath.Join(root, "mid", "mid.go")); err == nil {
+		t.Error("expected error for a missing handler main file")
+	}
+}
+
+func TestTraceOwnershipRecordsFilenameFallback(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	if _, err := finder.ListMainPackages(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file not cached yet is placed by its name only
+	moved := filepath.Join(root, "elsewhere", "mid.go")
+	if err := os.MkdirAll(filepath.Dir(moved), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(moved, []byte("package elsewhere\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	decision, err := finder.TraceOwnership("app/main.go", moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Confidence != ConfidenceLow || !contains(decision.Fallbacks, "filename-fallback") {
+		t.Errorf("trace = %+v, want low confidence with the filename fallback", decision)
+	}
+}
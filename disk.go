@@ -0,0 +1,100 @@
+package godepfind
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiskPackagesNotListed walks every root for directories containing .go files
+// and returns those (relative to their root, slash-separated) that are not
+// represented in the package cache, e.g. packages excluded by build tags or
+// living in a nested module. Directories ./... never matches (testdata, vendor,
+// and names starting with "." or "_") are skipped, and so are packages the
+// SetIgnorePatterns patterns exclude: their directory matches, or all of
+// their Go files do.
+func (g *GoDepFind) DiskPackagesNotListed() ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	listed := make(map[string]bool, len(g.packageCache))
+	for _, pkg := range g.packageCache {
+		if pkg == nil {
+			continue
+		}
+		if absDir, err := filepath.Abs(pkg.Dir); err == nil {
+			listed[absDir] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, root := range g.roots() {
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		goFiles := make(map[string][]string)
+		err = filepath.WalkDir(rootAbs, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				name := d.Name()
+				if path != rootAbs && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(path) != ".go" {
+				return nil
+			}
+			dir := filepath.Dir(path)
+			if !listed[dir] {
+				goFiles[dir] = append(goFiles[dir], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for dir, files := range goFiles {
+			if seen[dir] || g.ignoredDir(dir, files) {
+				continue
+			}
+			seen[dir] = true
+			rel, err := filepath.Rel(rootAbs, dir)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, filepath.ToSlash(rel))
+		}
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+// ignoredDir reports whether the ignore patterns exclude the package in dir,
+// the way ignoredPackage does for loaded packages
+func (g *GoDepFind) ignoredDir(dir string, goFiles []string) bool {
+	if g.ignoredPath(dir) {
+		return true
+	}
+	sources := 0
+	for _, file := range goFiles {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		if !g.ignoredPath(file) {
+			return false
+		}
+		sources++
+	}
+	return sources > 0
+}
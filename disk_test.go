@@ -0,0 +1,87 @@
+package godepfind
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiskPackagesNotListed(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":      "module testmod\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+		"integration/it.go": `//go:build integration
+
+package integration
+`,
+		"nested/go.mod":                 "module nested\n\ngo 1.21\n",
+		"nested/nested.go":              "package nested\n",
+		"testdata/fixture.go":           "package fixture\n",
+		"vendor/example.com/dep/dep.go": "package dep\n",
+	})
+
+	finder := New(root)
+	missing, err := finder.DiskPackagesNotListed()
+	if err != nil {
+		t.Fatalf("DiskPackagesNotListed failed: %v", err)
+	}
+
+	expected := []string{"integration", "nested"}
+	if len(missing) != len(expected) || missing[0] != expected[0] || missing[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, missing)
+	}
+
+	// With the tag enabled the integration package is listed
+	finder = New(root)
+	finder.SetBuildTags([]string{"integration"})
+	missing, err = finder.DiskPackagesNotListed()
+	if err != nil {
+		t.Fatalf("DiskPackagesNotListed failed: %v", err)
+	}
+	if len(missing) != 1 || missing[0] != "nested" {
+		t.Errorf("expected only [nested] with the integration tag, got %v", missing)
+	}
+}
+
+func TestDiskPackagesNotListedRootsAndIgnores(t *testing.T) {
+	front := writeTestModule(t, map[string]string{
+		"go.mod":      "module front\n\ngo 1.21\n",
+		"cmd/main.go": "package main\n\nfunc main() {}\n",
+		"gen/gen.go": `//go:build generate
+
+package gen
+`,
+		"proto/api.pb.go": `//go:build generate
+
+package proto
+`,
+	})
+	back := writeTestModule(t, map[string]string{
+		"go.mod":      "module back\n\ngo 1.21\n",
+		"cmd/main.go": "package main\n\nfunc main() {}\n",
+		"tools/tools.go": `//go:build tools
+
+package tools
+`,
+	})
+
+	finder := NewMulti(front, back)
+	missing, err := finder.DiskPackagesNotListed()
+	if err != nil {
+		t.Fatalf("DiskPackagesNotListed failed: %v", err)
+	}
+	if want := []string{"gen", "proto", "tools"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("expected unlisted packages of both roots %v, got %v", want, missing)
+	}
+
+	// Ignored packages are not reported, by directory or by all their files
+	if err := finder.SetIgnorePatterns([]string{"gen/**", "**/*.pb.go"}); err != nil {
+		t.Fatalf("SetIgnorePatterns failed: %v", err)
+	}
+	missing, err = finder.DiskPackagesNotListed()
+	if err != nil {
+		t.Fatalf("DiskPackagesNotListed failed: %v", err)
+	}
+	if want := []string{"tools"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("expected only %v with ignore patterns, got %v", want, missing)
+	}
+}
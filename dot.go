@@ -0,0 +1,89 @@
+package godepfind
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// WriteDOT writes the cached package graph to w as a Graphviz digraph: one
+// node per package and an edge from each importer to every package it
+// imports. Main packages are drawn as boxes. The output is sorted, so equal
+// graphs produce identical files.
+func (g *GoDepFind) WriteDOT(w io.Writer) error {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	nodes := make(map[string]bool)
+	for pkgPath, deps := range g.dependencyGraph {
+		nodes[pkgPath] = true
+		for _, dep := range deps {
+			nodes[dep] = true
+		}
+	}
+	return g.writeDOT(w, nodes)
+}
+
+// WriteDOTFrom is like WriteDOT but only writes the subgraph reachable from
+// rootPkg (e.g. one main package). It returns an error when rootPkg isn't in
+// the graph.
+func (g *GoDepFind) WriteDOTFrom(w io.Writer, rootPkg string) error {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := g.dependencyGraph[rootPkg]; !ok {
+		return fmt.Errorf("package not found in dependency graph: %s", rootPkg)
+	}
+	nodes := map[string]bool{rootPkg: true}
+	stack := []string{rootPkg}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, dep := range g.dependencyGraph[current] {
+			if !nodes[dep] {
+				nodes[dep] = true
+				stack = append(stack, dep)
+			}
+		}
+	}
+	return g.writeDOT(w, nodes)
+}
+
+// writeDOT writes the given nodes and the edges between them
+func (g *GoDepFind) writeDOT(w io.Writer, nodes map[string]bool) error {
+	sorted := make([]string, 0, len(nodes))
+	for node := range nodes {
+		sorted = append(sorted, node)
+	}
+	sort.Strings(sorted)
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph godepfind {")
+	fmt.Fprintln(out, "\tnode [shape=ellipse];")
+	for _, node := range sorted {
+		if g.isMainPackage(node) {
+			fmt.Fprintf(out, "\t%s [shape=box];\n", strconv.Quote(node))
+		} else {
+			fmt.Fprintf(out, "\t%s;\n", strconv.Quote(node))
+		}
+	}
+	for _, node := range sorted {
+		deps := append([]string{}, g.dependencyGraph[node]...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if nodes[dep] {
+				fmt.Fprintf(out, "\t%s -> %s;\n", strconv.Quote(node), strconv.Quote(dep))
+			}
+		}
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
@@ -0,0 +1,53 @@
+package godepfind
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+
+	var out strings.Builder
+	if err := finder.WriteDOT(&out); err != nil {
+		t.Fatalf("WriteDOT failed: %v", err)
+	}
+	dot := out.String()
+	for _, want := range []string{
+		"digraph godepfind {",
+		`"testmod/app" [shape=box];`,
+		`"testmod/other" [shape=box];`,
+		`"testmod/mid";`,
+		`"testmod/app" -> "testmod/mid";`,
+		`"testmod/mid" -> "testmod/internal/leaf";`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("WriteDOT output lacks %q:\n%s", want, dot)
+		}
+	}
+
+	var again strings.Builder
+	if err := finder.WriteDOT(&again); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != dot {
+		t.Error("WriteDOT output is not deterministic")
+	}
+
+	out.Reset()
+	if err := finder.WriteDOTFrom(&out, "testmod/mid"); err != nil {
+		t.Fatalf("WriteDOTFrom failed: %v", err)
+	}
+	sub := out.String()
+	if !strings.Contains(sub, `"testmod/mid" -> "testmod/internal/leaf";`) {
+		t.Errorf("subgraph lacks the mid -> leaf edge:\n%s", sub)
+	}
+	if strings.Contains(sub, "testmod/app") || strings.Contains(sub, "testmod/other") {
+		t.Errorf("subgraph includes packages not reachable from mid:\n%s", sub)
+	}
+
+	if err := finder.WriteDOTFrom(&out, "testmod/missing"); err == nil {
+		t.Error("expected error for an unknown root package")
+	}
+}
@@ -0,0 +1,40 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDuplicateFileAttributions(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":      "module testmod\n\ngo 1.21\n",
+		"a/shared.go": "package shared\n\nfunc Do() {}\n",
+		"b/own.go":    "package shared\n",
+	})
+	// b/shared.go is a symlink to a/shared.go, so the same file is in both packages
+	if err := os.Symlink(filepath.Join(root, "a", "shared.go"), filepath.Join(root, "b", "shared.go")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	finder := New(root)
+	duplicates, err := finder.DuplicateFileAttributions()
+	if err != nil {
+		t.Fatalf("DuplicateFileAttributions failed: %v", err)
+	}
+
+	realPath, err := filepath.EvalSymlinks(filepath.Join(root, "a", "shared.go"))
+	if err != nil {
+		t.Fatalf("EvalSymlinks failed: %v", err)
+	}
+	pkgs, found := duplicates[realPath]
+	if !found {
+		t.Fatalf("expected %s to be reported as duplicated, got %v", realPath, duplicates)
+	}
+	if len(pkgs) != 2 || pkgs[0] != "testmod/a" || pkgs[1] != "testmod/b" {
+		t.Errorf("expected [testmod/a testmod/b], got %v", pkgs)
+	}
+	if len(duplicates) != 1 {
+		t.Errorf("expected a single duplicated file, got %v", duplicates)
+	}
+}
@@ -140,3 +140,93 @@ func main() {
 		t.Fatalf("expected db to belong to main after import; got false; mains=%v", strings.Join(mains, ","))
 	}
 }
+
+func TestMainEditRescansIncrementally(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	mainFile := filepath.Join(root, "app", "main.go")
+	leaf := filepath.Join(root, "internal", "leaf", "leaf.go")
+	if owned, err := finder.ThisFileIsMine("app/main.go", leaf, EventWrite); err != nil || !owned {
+		t.Fatalf("expected leaf.go to belong to app before the edit, got %v, %v", owned, err)
+	}
+	listed := finder.listCount
+
+	// Importing a cached package directly, dropping mid: no go list run
+	src := "package main\n\nimport \"testmod/internal/leaf\"\n\nfunc main() { leaf.Do() }\n"
+	if err := os.WriteFile(mainFile, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finder.ThisFileIsMine("app/main.go", mainFile, EventWrite); err != nil {
+		t.Fatalf("main edit failed: %v", err)
+	}
+	if finder.listCount != listed {
+		t.Errorf("expected no go list run for an edit importing cached packages, got %d", finder.listCount-listed)
+	}
+	if owned, _ := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "mid", "mid.go"), EventWrite); owned {
+		t.Error("expected mid.go to no longer belong to app")
+	}
+	if owned, _ := finder.ThisFileIsMine("app/main.go", leaf, EventWrite); !owned {
+		t.Error("expected leaf.go to still belong to app")
+	}
+	if importers := finder.reverseDeps["testmod/mid"]; contains(importers, "testmod/app") {
+		t.Errorf("expected the app → mid reverse edge to be dropped, got %v", importers)
+	}
+	if _, unreachable, _ := finder.ReachabilityChanges(); !contains(unreachable, "testmod/mid") {
+		t.Errorf("expected mid to be reported unreachable, got %v", unreachable)
+	}
+
+	// Importing a package created since the last rebuild falls back to a rebuild
+	if err := os.MkdirAll(filepath.Join(root, "fresh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fresh := filepath.Join(root, "fresh", "fresh.go")
+	if err := os.WriteFile(fresh, []byte("package fresh\n\nfunc Do() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src = "package main\n\nimport \"testmod/fresh\"\n\nfunc main() { fresh.Do() }\n"
+	if err := os.WriteFile(mainFile, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finder.ThisFileIsMine("app/main.go", mainFile, EventWrite); err != nil {
+		t.Fatalf("main edit failed: %v", err)
+	}
+	if finder.listCount == listed {
+		t.Error("expected a rebuild for an import of an uncached package")
+	}
+	if owned, _ := finder.ThisFileIsMine("app/main.go", fresh, EventWrite); !owned {
+		t.Error("expected fresh.go to belong to app after the rebuild")
+	}
+}
+
+// BenchmarkMainEditRescan measures a handler main edit that adds no import,
+// rescanned incrementally or by rebuilding the cache
+func BenchmarkMainEditRescan(b *testing.B) {
+	for _, incremental := range []bool{true, false} {
+		name := "incremental"
+		if !incremental {
+			name = "rebuild"
+		}
+		b.Run(name, func(b *testing.B) {
+			root := writeTestModule(b, syntheticModuleFiles(benchmarkPackages))
+			finder := New(root)
+			if err := finder.ensureCacheInitialized(); err != nil {
+				b.Fatalf("cache init failed: %v", err)
+			}
+			mainFile := filepath.Join(root, "cmd", "main.go")
+			start := finder.listCount
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var err error
+				if incremental {
+					err = finder.rescanMainPackageDependencies(mainFile)
+				} else {
+					err = finder.rebuildCache()
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(finder.listCount-start)/float64(b.N), "golist/op")
+		})
+	}
+}
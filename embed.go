@@ -0,0 +1,108 @@
+package godepfind
+
+import (
+	"go/build"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SetTrackEmbeds enables or disables mapping the files embedded with
+// //go:embed (templates, styles, ...) to the package embedding them, so
+// ThisFileIsMine routes an edited template to the mains that compile it in.
+// Disabled by default, so pure-Go trees don't pay for resolving the patterns.
+// Changing the setting invalidates the cache.
+func (g *GoDepFind) SetTrackEmbeds(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.trackEmbeds = enabled
+	g.cachedModule = false
+}
+
+// TracksEmbeds reports whether embedded files are mapped, see SetTrackEmbeds
+func (g *GoDepFind) TracksEmbeds() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.trackEmbeds
+}
+
+// packageEmbeds returns the absolute paths of the files pkg embeds: those go
+// list resolved its //go:embed patterns to, or, for packages imported with
+// go/build (which doesn't resolve them), embeddedFiles
+func (g *GoDepFind) packageEmbeds(pkgPath string, pkg *build.Package) []string {
+	files, listed := g.embedFiles[pkgPath]
+	if !listed {
+		return embeddedFiles(pkg)
+	}
+	dir, err := filepath.Abs(pkg.Dir)
+	if err != nil {
+		return nil
+	}
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		result = append(result, filepath.Join(dir, filepath.FromSlash(file)))
+	}
+	sort.Strings(result)
+	return result
+}
+
+// embeddedFiles resolves the //go:embed patterns of pkg to the absolute paths
+// of the files they embed, approximating the rules of the go command: a pattern
+// naming a directory embeds the files below it, except those whose name
+// starts with "." or "_" unless the pattern has the "all:" prefix. Patterns
+// matching nothing are skipped. Other rules (e.g. directories of nested
+// modules are not embedded) are not applied: it is only the fallback for
+// packages go list didn't resolve.
+func embeddedFiles(pkg *build.Package) []string {
+	seen := make(map[string]bool)
+	var result []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			result = append(result, path)
+		}
+	}
+	dir, err := filepath.Abs(pkg.Dir)
+	if err != nil {
+		return nil
+	}
+
+	for _, pattern := range pkg.EmbedPatterns {
+		all := strings.HasPrefix(pattern, "all:")
+		pattern = strings.TrimPrefix(pattern, "all:")
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				continue
+			}
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+			filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+				name := d.Name()
+				if path != match && !all && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !d.IsDir() {
+					add(path)
+				}
+				return nil
+			})
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,115 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func embedModuleFiles() map[string]string {
+	return map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+import (
+	"embed"
+
+	"testmod/ui"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+func main() { _ = templates; ui.Render() }
+`,
+		"app/templates/index.html":  "<html></html>\n",
+		"app/templates/notes.txt":   "not embedded\n",
+		"other/main.go":             "package main\n\nfunc main() {}\n",
+		"ui/ui.go":                  "package ui\n\nimport \"embed\"\n\n//go:embed static\nvar static embed.FS\n\nfunc Render() { _ = static }\n",
+		"ui/static/app.css":         "body {}\n",
+		"ui/static/nested/icon.svg": "<svg/>\n",
+		"ui/static/.hidden":         "skipped without all:\n",
+	}
+}
+
+func TestEmbeddedFiles(t *testing.T) {
+	root := writeTestModule(t, embedModuleFiles())
+	finder := New(root)
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := embeddedFiles(finder.packageCache["testmod/ui"])
+	want := []string{
+		filepath.Join(root, "ui", "static", "app.css"),
+		filepath.Join(root, "ui", "static", "nested", "icon.svg"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("embeddedFiles(ui) = %v, want %v", got, want)
+	}
+}
+
+func TestTrackEmbedsRoutesEmbeddedFiles(t *testing.T) {
+	root := writeTestModule(t, embedModuleFiles())
+	template := filepath.Join(root, "app", "templates", "index.html")
+	style := filepath.Join(root, "ui", "static", "app.css")
+
+	finder := New(root)
+	if mine, err := finder.ThisFileIsMine("app/main.go", template, EventWrite); err != nil || mine {
+		t.Fatalf("without tracking: template ownership = %v, %v; want false", mine, err)
+	}
+
+	finder.SetTrackEmbeds(true)
+	cases := []struct {
+		handler, file string
+		want          bool
+	}{
+		{"app/main.go", template, true},
+		{"app/main.go", style, true}, // embedded by a package the main imports
+		{"other/main.go", template, false},
+		{"other/main.go", style, false},
+		{"app/main.go", filepath.Join(root, "app", "templates", "notes.txt"), false},
+		{"app/main.go", filepath.Join(root, "ui", "static", ".hidden"), false},
+	}
+	for _, c := range cases {
+		mine, err := finder.ThisFileIsMine(c.handler, c.file, EventWrite)
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s) failed: %v", c.handler, c.file, err)
+		}
+		if mine != c.want {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", c.handler, c.file, mine, c.want)
+		}
+	}
+}
+
+func TestTrackEmbedsUsesGoListEmbedFiles(t *testing.T) {
+	// The go command doesn't embed the files of a nested module, which
+	// globbing the patterns would
+	files := embedModuleFiles()
+	files["ui/static/vendored/go.mod"] = "module vendored\n\ngo 1.21\n"
+	files["ui/static/vendored/lib.css"] = "p {}\n"
+	root := writeTestModule(t, files)
+	nested := filepath.Join(root, "ui", "static", "vendored", "lib.css")
+
+	finder := New(root)
+	finder.SetTrackEmbeds(true)
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"static/app.css", "static/nested/icon.svg"}
+	if got := finder.embedFiles["testmod/ui"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("go list embed files of ui = %v, want %v", got, want)
+	}
+	if mine, err := finder.ThisFileIsMine("app/main.go", nested, EventWrite); err != nil || mine {
+		t.Errorf("expected the nested module's file not to be embedded, got %v, %v", mine, err)
+	}
+	if mine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "ui", "static", "app.css"), EventWrite); err != nil || !mine {
+		t.Errorf("expected app.css to stay embedded, got %v, %v", mine, err)
+	}
+
+	// Packages imported with go/build fall back to globbing the patterns
+	finder.SetPerPackageImport(true)
+	if mine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "ui", "static", "app.css"), EventWrite); err != nil || !mine {
+		t.Errorf("expected app.css to be embedded by the fallback, got %v, %v", mine, err)
+	}
+}
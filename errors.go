@@ -0,0 +1,51 @@
+package godepfind
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// ErrGoNotFound is returned (wrapped) when the go tool cannot be found, so
+// callers can abort instead of retrying
+var ErrGoNotFound = errors.New("go binary not found")
+
+// GoListError reports a go list invocation that ran but failed, typically
+// because a file being edited doesn't compile yet: retrying after the next
+// save usually succeeds. Stderr holds the go tool output for logging.
+type GoListError struct {
+	Command []string // program followed by its arguments
+	Stderr  string
+	Err     error // underlying *exec.ExitError
+}
+
+func (e *GoListError) Error() string {
+	msg := fmt.Sprintf("%s failed: %v", strings.Join(e.Command, " "), e.Err)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+func (e *GoListError) Unwrap() error {
+	return e.Err
+}
+
+// goToolError classifies the error of a go tool invocation: a *GoListError
+// when the tool ran and failed, ErrGoNotFound when it could not be found
+func goToolError(program string, args []string, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &GoListError{
+			Command: append([]string{program}, args...),
+			Stderr:  string(exitErr.Stderr),
+			Err:     err,
+		}
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("cannot execute go binary %s: %w: %w", program, ErrGoNotFound, err)
+	}
+	return fmt.Errorf("cannot execute go binary %s: %w", program, err)
+}
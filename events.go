@@ -0,0 +1,35 @@
+package godepfind
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FileEvent is the kind of file system change reported to ThisFileIsMine and
+// the Debouncer; it drives how the cache is updated
+type FileEvent string
+
+const (
+	EventWrite  FileEvent = "write"  // file content changed
+	EventCreate FileEvent = "create" // file added
+	EventRemove FileEvent = "remove" // file deleted ("delete" is accepted as an alias)
+	EventRename FileEvent = "rename" // file moved, handled as remove + create
+)
+
+// eventDelete is the alias of EventRemove used by some file watchers
+const eventDelete FileEvent = "delete"
+
+// ErrUnknownEvent is returned (wrapped) for an event that is not one of the
+// Event constants
+var ErrUnknownEvent = errors.New("unknown file event")
+
+// normalizeEvent maps aliases to their canonical event and rejects unknown events
+func normalizeEvent(event FileEvent) (FileEvent, error) {
+	switch event {
+	case EventWrite, EventCreate, EventRemove, EventRename:
+		return event, nil
+	case eventDelete:
+		return EventRemove, nil
+	}
+	return "", fmt.Errorf("%w %q: expected %q, %q, %q or %q", ErrUnknownEvent, event, EventWrite, EventCreate, EventRemove, EventRename)
+}
@@ -0,0 +1,97 @@
+package godepfind
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeEvent(t *testing.T) {
+	tests := []struct {
+		event   FileEvent
+		want    FileEvent
+		wantErr bool
+	}{
+		{EventWrite, EventWrite, false},
+		{EventCreate, EventCreate, false},
+		{EventRemove, EventRemove, false},
+		{EventRename, EventRename, false},
+		{"delete", EventRemove, false},
+		{"check", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeEvent(tt.event)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeEvent(%q) error = %v, wantErr %v", tt.event, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrUnknownEvent) {
+			t.Errorf("normalizeEvent(%q) error %v does not wrap ErrUnknownEvent", tt.event, err)
+		}
+		if got != tt.want {
+			t.Errorf("normalizeEvent(%q) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownEventRejected(t *testing.T) {
+	finder := New("testproject")
+	module1, err := filepath.Abs(filepath.Join("testproject", "modules", "module1", "module1.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := finder.ThisFileIsMine("appAserver/main.go", module1, "modify"); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("ThisFileIsMine: expected ErrUnknownEvent, got %v", err)
+	}
+	if err := finder.updateCacheForFile(module1, "modify"); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("updateCacheForFile: expected ErrUnknownEvent, got %v", err)
+	}
+
+	isMine, err := finder.ThisFileIsMine("appAserver/main.go", module1, EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !isMine {
+		t.Error("expected appAserver to own module1.go")
+	}
+}
+
+func TestDeleteEventRemovesFileMappings(t *testing.T) {
+	for _, update := range []string{"updateCacheForFile", "updateCacheForFileWithContext"} {
+		t.Run(update, func(t *testing.T) {
+			files := chainModuleFiles()
+			files["mid/extra.go"] = "package mid\n\nfunc Extra() {}\n"
+			root := writeTestModule(t, files)
+			finder := New(root)
+			if err := finder.ensureCacheInitialized(); err != nil {
+				t.Fatalf("cache init failed: %v", err)
+			}
+			extra := filepath.Join(root, "mid", "extra.go")
+			if finder.filePathToPackage[extra] != "testmod/mid" {
+				t.Fatalf("expected extra.go mapped to testmod/mid before the delete, got %q", finder.filePathToPackage[extra])
+			}
+
+			if err := os.Remove(extra); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			if update == "updateCacheForFile" {
+				err = finder.updateCacheForFile(extra, "delete")
+			} else {
+				err = finder.updateCacheForFileWithContext(extra, "delete", "app/main.go")
+			}
+			if err != nil {
+				t.Fatalf("%s(delete) failed: %v", update, err)
+			}
+
+			if pkg, exists := finder.filePathToPackage[extra]; exists {
+				t.Errorf("expected extra.go removed from filePathToPackage, still mapped to %q", pkg)
+			}
+			if pkgs := finder.fileToPackages["extra.go"]; len(pkgs) != 0 {
+				t.Errorf("expected extra.go removed from fileToPackages, got %v", pkgs)
+			}
+		})
+	}
+}
@@ -39,8 +39,10 @@ func (g *GoDepFind) CheckFileOwnership(mainInputFileRelativePath, fileName, file
 		return "skipped", nil // File is being written or invalid
 	}
 
-	// Check ownership using existing logic
-	belongs, err := g.ThisFileIsMine(mainInputFileRelativePath, filePath, "check")
+	// Check ownership using existing logic (no event: the cache is not updated)
+	g.mu.Lock()
+	belongs, _, err := g.thisFileIsMine(mainInputFileRelativePath, filePath, "")
+	g.mu.Unlock()
 	if err != nil {
 		return "", err
 	}
@@ -53,7 +55,7 @@ func (g *GoDepFind) CheckFileOwnership(mainInputFileRelativePath, fileName, file
 
 // AnalyzeFileImpact analyzes the impact of a file change with validation
 // Yet another example showing reusability
-func (g *GoDepFind) AnalyzeFileImpact(mainInputFileRelativePath, fileName, filePath, event string) (*FileImpactResult, error) {
+func (g *GoDepFind) AnalyzeFileImpact(mainInputFileRelativePath, fileName, filePath string, event FileEvent) (*FileImpactResult, error) {
 	// Reuse centralized validation
 	shouldProcess, err := g.ValidateInputForProcessing(mainInputFileRelativePath, fileName, filePath)
 	if err != nil {
@@ -0,0 +1,247 @@
+package godepfind
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ExplainNode is one step of an ownership explanation tree. The tree reads
+// from the file down to the handler: file → package → importers → main → handler.
+type ExplainNode struct {
+	Kind     string         `json:"kind"`           // "file", "package", "import", "main" or "handler"
+	Name     string         `json:"name"`           // file path, package path or handler main file
+	Evidence string         `json:"evidence"`       // why this step holds (exact-path, import edge, dir match, ...)
+	Case     OwnershipCase  `json:"case,omitempty"` // on the file node: the rule that decided
+	Children []*ExplainNode `json:"children,omitempty"`
+}
+
+// ExplainOwnership returns a tree explaining why fileAbsPath is (or isn't)
+// owned by the handler identified by mainInputFileRelativePath. The tree is
+// built from the decision ThisFileIsMine makes (see TraceOwnership), so Owned
+// agrees with it and the file node's Case names the rule that decided. For
+// files owned through imports, every import chain from the handler's main to
+// the file's package is included. Like TraceOwnership it is a dry run: no
+// event is applied, so ownership state doesn't change and it is safe to call
+// from diagnostic UIs. The cache is still built first, or rebuilt when
+// SetAutoRefresh detects changes, as for any query.
+func (g *GoDepFind) ExplainOwnership(mainInputFileRelativePath, fileAbsPath string) (ExplainNode, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
+	if err := g.ensureCacheInitialized(); err != nil {
+		return ExplainNode{}, err
+	}
+
+	d := &OwnershipDecision{}
+	if err := g.decideOwnership(mainInputFileRelativePath, fileAbsPath, "", d); err != nil {
+		return ExplainNode{}, err
+	}
+	root := ExplainNode{Kind: "file", Name: d.File, Case: d.Case}
+	handlerNode := func(evidence string) *ExplainNode {
+		return &ExplainNode{Kind: "handler", Name: mainInputFileRelativePath, Evidence: evidence}
+	}
+
+	// Decisions from the handler's own build don't record the package
+	targetPkg, exact := d.TargetPackage, len(d.Fallbacks) == 0
+	if targetPkg == "" && d.Case == CaseHandlerBuild {
+		pkg, found, err := g.resolvePackageForFile(d.File)
+		if err != nil {
+			return ExplainNode{}, err
+		}
+		targetPkg, exact = pkg, found
+	}
+	if targetPkg == "" {
+		// Decided without a package: outside root, asset root, module file...
+		root.Evidence = string(d.Case)
+		if d.Owned {
+			root.Children = append(root.Children, handlerNode(string(d.Case)))
+		}
+		return root, nil
+	}
+	root.Evidence = "exact-path"
+	if !exact {
+		root.Evidence = "filename-fallback"
+		if len(d.Fallbacks) > 0 {
+			root.Evidence = d.Fallbacks[0]
+		}
+	}
+
+	pkgNode := &ExplainNode{Kind: "package", Name: targetPkg, Evidence: "contains file"}
+	root.Children = append(root.Children, pkgNode)
+	if !d.Owned {
+		return root, nil
+	}
+
+	// The file's package is the handler's own main package
+	if d.Case == CaseHandlerMainFile || d.Case == CaseMainPackageInDir || targetPkg == d.HandlerPackage {
+		pkgNode.Children = append(pkgNode.Children, handlerNode("dir match"))
+		return root, nil
+	}
+	if err := g.explainImportChains(pkgNode, mainInputFileRelativePath, targetPkg, handlerNode); err != nil {
+		return ExplainNode{}, err
+	}
+	if len(pkgNode.Children) == 0 {
+		// Owned without a cached chain (e.g. only the handler's own build imports it)
+		pkgNode.Children = append(pkgNode.Children, handlerNode(string(d.Case)))
+	}
+	return root, nil
+}
+
+// explainImportChains attaches to pkgNode every import chain from the
+// handler's main down to targetPkg. Handlers identified by the import path
+// of their main package start from its cached imports.
+func (g *GoDepFind) explainImportChains(pkgNode *ExplainNode, mainInputFileRelativePath, targetPkg string, handlerNode func(string) *ExplainNode) error {
+	mainName, byImportPath, err := g.handlerMainImportPath(mainInputFileRelativePath)
+	if err != nil {
+		return err
+	}
+	var imports []string
+	if byImportPath {
+		imports = g.dependencyGraph[mainName]
+	} else {
+		if imports, err = g.handlerImports(g.rootPath(mainInputFileRelativePath)); err != nil {
+			return fmt.Errorf("cannot read handler main file %s: %w", mainInputFileRelativePath, err)
+		}
+		if mainName, err = g.resolveHandlerMainPackage(mainInputFileRelativePath); err != nil {
+			return err
+		}
+	}
+
+	for _, imp := range imports {
+		chain := g.importChain(imp, targetPkg)
+		if chain == nil {
+			continue
+		}
+		// chain runs imp → ... → targetPkg; attach it upwards from the package
+		parent := pkgNode
+		for i := len(chain) - 2; i >= 0; i-- {
+			node := &ExplainNode{Kind: "import", Name: chain[i], Evidence: "imports " + chain[i+1]}
+			parent.Children = append(parent.Children, node)
+			parent = node
+		}
+		mainNode := &ExplainNode{Kind: "main", Name: mainName, Evidence: "imports " + imp}
+		mainNode.Children = append(mainNode.Children, handlerNode("handler main file"))
+		parent.Children = append(parent.Children, mainNode)
+	}
+	return nil
+}
+
+// importChain returns the shortest chain of packages from "from" to "to"
+// following the cached dependency graph, or nil when "to" is unreachable
+func (g *GoDepFind) importChain(from, to string) []string {
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			var chain []string
+			for node := to; node != ""; node = previous[node] {
+				chain = append([]string{node}, chain...)
+			}
+			return chain
+		}
+		for _, dep := range g.dependencyGraph[current] {
+			if _, seen := previous[dep]; !seen {
+				previous[dep] = current
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// Owned reports whether the tree contains a path that reaches the handler
+func (n ExplainNode) Owned() bool {
+	if n.Kind == "handler" {
+		return true
+	}
+	for _, child := range n.Children {
+		if child.Owned() {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the tree as indented text, one node per line
+func (n ExplainNode) String() string {
+	var b strings.Builder
+	n.write(&b, 0)
+	return b.String()
+}
+
+func (n *ExplainNode) write(b *strings.Builder, depth int) {
+	fmt.Fprintf(b, "%s%s %s (%s)\n", strings.Repeat("  ", depth), n.Kind, n.Name, n.Evidence)
+	for _, child := range n.Children {
+		child.write(b, depth+1)
+	}
+}
+
+// WhyDependsOn returns the shortest chain of packages from the handler's main
+// package down to the package containing fileAbsPath, following the cached
+// dependency graph, like `go mod why` at the package level. The chain starts
+// with the main package (or the handler main file itself for a build variant
+// excluded from its directory's package) and ends with the file's package. An
+// empty slice is returned when the main doesn't depend on the file, or when
+// the file's package is excluded with ExcludeFromOwnership (unless it is the
+// main package itself), matching ThisFileIsMine.
+func (g *GoDepFind) WhyDependsOn(mainInputFileRelativePath, fileAbsPath string) ([]string, error) {
+	if fileAbsPath == "" {
+		return nil, fmt.Errorf("fileAbsPath cannot be empty")
+	}
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	fileAbsPath = g.rootPath(fileAbsPath)
+	fileAbsPath, err = filepath.Abs(fileAbsPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve fileAbsPath to absolute path: %w", err)
+	}
+	targetPkg, _, err := g.resolvePackageForFile(fileAbsPath)
+	if err != nil {
+		return nil, err
+	}
+	if targetPkg == "" {
+		return []string{}, nil
+	}
+
+	mainPkg, byImportPath, err := g.handlerMainImportPath(mainInputFileRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	if !byImportPath {
+		if mainPkg, err = g.resolveHandlerMainPackage(mainInputFileRelativePath); err != nil {
+			return nil, err
+		}
+	}
+	if targetPkg != mainPkg && g.excludedFromOwnership(targetPkg) {
+		return []string{}, nil
+	}
+	if g.isMainPackage(mainPkg) {
+		if chain := g.importChain(mainPkg, targetPkg); chain != nil {
+			return chain, nil
+		}
+		return []string{}, nil
+	}
+
+	// Build variant outside the cached package: start from the file's imports
+	imports, err := g.handlerImports(mainPkg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read handler main file %s: %w", mainInputFileRelativePath, err)
+	}
+	var shortest []string
+	for _, imp := range imports {
+		if chain := g.importChain(imp, targetPkg); chain != nil && (shortest == nil || len(chain) < len(shortest)) {
+			shortest = chain
+		}
+	}
+	if shortest == nil {
+		return []string{}, nil
+	}
+	return append([]string{mainPkg}, shortest...), nil
+}
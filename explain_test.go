@@ -0,0 +1,191 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func chainModuleFiles() map[string]string {
+	return map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+import "testmod/mid"
+
+func main() { mid.Do() }
+`,
+		"mid/mid.go": `package mid
+
+import "testmod/internal/leaf"
+
+func Do() { leaf.Do() }
+`,
+		"internal/leaf/leaf.go": "package leaf\n\nfunc Do() {}\n",
+		"other/main.go":         "package main\n\nfunc main() {}\n",
+	}
+}
+
+func TestExplainOwnershipImportChain(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+
+	tree, err := finder.ExplainOwnership("app/main.go", filepath.Join(root, "internal", "leaf", "leaf.go"))
+	if err != nil {
+		t.Fatalf("ExplainOwnership failed: %v", err)
+	}
+	if !tree.Owned() {
+		t.Fatalf("expected leaf.go to be explained as owned:\n%s", tree)
+	}
+
+	// file → package → import mid → main → handler
+	expected := []struct{ kind, name, evidence string }{
+		{"file", filepath.Join(root, "internal", "leaf", "leaf.go"), "exact-path"},
+		{"package", "testmod/internal/leaf", "contains file"},
+		{"import", "testmod/mid", "imports testmod/internal/leaf"},
+		{"main", "testmod/app", "imports testmod/mid"},
+		{"handler", "app/main.go", "handler main file"},
+	}
+	node := &tree
+	for i, want := range expected {
+		if node.Kind != want.kind || node.Name != want.name || node.Evidence != want.evidence {
+			t.Fatalf("level %d: expected %s %s (%s), got %s %s (%s)\n%s", i, want.kind, want.name, want.evidence, node.Kind, node.Name, node.Evidence, tree)
+		}
+		if i < len(expected)-1 {
+			if len(node.Children) != 1 {
+				t.Fatalf("level %d: expected exactly one child, got %d\n%s", i, len(node.Children), tree)
+			}
+			node = node.Children[0]
+		}
+	}
+}
+
+func TestExplainOwnershipNotOwned(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+
+	tree, err := finder.ExplainOwnership("other/main.go", filepath.Join(root, "internal", "leaf", "leaf.go"))
+	if err != nil {
+		t.Fatalf("ExplainOwnership failed: %v", err)
+	}
+	if tree.Owned() {
+		t.Errorf("expected leaf.go not to be owned by other/main.go:\n%s", tree)
+	}
+
+	tree, err = finder.ExplainOwnership("app/main.go", filepath.Join(root, "app", "main.go"))
+	if err != nil {
+		t.Fatalf("ExplainOwnership failed: %v", err)
+	}
+	if !tree.Owned() || tree.Children[0].Children[0].Evidence != "dir match" {
+		t.Errorf("expected handler main file to be owned by dir match:\n%s", tree)
+	}
+}
+
+// explainAgreesWithThisFileIsMine fails when ExplainOwnership and
+// ThisFileIsMine disagree on any handler/file pair
+func explainAgreesWithThisFileIsMine(t *testing.T, finder *GoDepFind, handlers, files []string) {
+	t.Helper()
+	for _, handler := range handlers {
+		for _, file := range files {
+			tree, err := finder.ExplainOwnership(handler, file)
+			if err != nil {
+				t.Fatalf("ExplainOwnership(%s, %s) failed: %v", handler, file, err)
+			}
+			owned, err := finder.ThisFileIsMine(handler, file, EventWrite)
+			if err != nil {
+				t.Fatalf("ThisFileIsMine(%s, %s) failed: %v", handler, file, err)
+			}
+			if tree.Owned() != owned {
+				t.Errorf("%s / %s: ExplainOwnership owned=%v, ThisFileIsMine=%v\n%s", handler, file, tree.Owned(), owned, tree)
+			}
+		}
+	}
+}
+
+func TestExplainOwnershipAgreesWithThisFileIsMine(t *testing.T) {
+	files := chainModuleFiles()
+	files["app/assets/style.css"] = "body {}\n"
+	files["other/other_tagged.go"] = "//go:build admin\n\npackage main\n"
+	root := writeTestModule(t, files)
+	finder := New(root)
+	if err := finder.RegisterAssetRoot("other/main.go", "app/assets"); err != nil {
+		t.Fatalf("RegisterAssetRoot failed: %v", err)
+	}
+	finder.SetHandlerBuildTags("other/main.go", []string{"admin"})
+
+	explainAgreesWithThisFileIsMine(t, finder, []string{"app/main.go", "other/main.go"}, []string{
+		filepath.Join(root, "internal", "leaf", "leaf.go"),
+		filepath.Join(root, "mid", "mid.go"),
+		filepath.Join(root, "app", "main.go"),
+		filepath.Join(root, "app", "assets", "style.css"),
+		filepath.Join(root, "other", "main.go"),
+		filepath.Join(root, "other", "other_tagged.go"),
+		filepath.Join(root, "go.mod"),
+		filepath.Join(t.TempDir(), "outside.go"),
+	})
+
+	// The asset root routes style.css to other/main.go although app imports nothing of it
+	tree, err := finder.ExplainOwnership("other/main.go", filepath.Join(root, "app", "assets", "style.css"))
+	if err != nil {
+		t.Fatalf("ExplainOwnership failed: %v", err)
+	}
+	if !tree.Owned() || tree.Case != CaseAssetRoot {
+		t.Errorf("expected style.css to be owned through the asset root:\n%s", tree)
+	}
+}
+
+func TestExplainOwnershipImportPathHandler(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	leafFile := filepath.Join(root, "internal", "leaf", "leaf.go")
+
+	tree, err := finder.ExplainOwnership("testmod/app", leafFile)
+	if err != nil {
+		t.Fatalf("ExplainOwnership failed: %v", err)
+	}
+	if !tree.Owned() || tree.Case != CaseImportPathHandler {
+		t.Fatalf("expected leaf.go to be owned by testmod/app:\n%s", tree)
+	}
+	mainNode := tree.Children[0].Children[0].Children[0]
+	if mainNode.Kind != "main" || mainNode.Name != "testmod/app" || mainNode.Evidence != "imports testmod/mid" {
+		t.Errorf("expected the chain to reach main testmod/app through testmod/mid:\n%s", tree)
+	}
+
+	explainAgreesWithThisFileIsMine(t, finder, []string{"testmod/app", "testmod/other"}, []string{
+		leafFile,
+		filepath.Join(root, "app", "main.go"),
+		filepath.Join(root, "other", "main.go"),
+	})
+}
+
+func TestWhyDependsOn(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	leafFile := filepath.Join(root, "internal", "leaf", "leaf.go")
+
+	chain, err := finder.WhyDependsOn("app/main.go", leafFile)
+	if err != nil {
+		t.Fatalf("WhyDependsOn failed: %v", err)
+	}
+	if want := []string{"testmod/app", "testmod/mid", "testmod/internal/leaf"}; !reflect.DeepEqual(chain, want) {
+		t.Errorf("WhyDependsOn = %v, want %v", chain, want)
+	}
+
+	// Handlers identified by import path explain the same chain
+	chain, err = finder.WhyDependsOn("testmod/app", "internal/leaf/leaf.go")
+	if err != nil {
+		t.Fatalf("WhyDependsOn failed: %v", err)
+	}
+	if len(chain) != 3 {
+		t.Errorf("expected a 3-package chain, got %v", chain)
+	}
+
+	// No path: empty slice, no error
+	chain, err = finder.WhyDependsOn("other/main.go", leafFile)
+	if err != nil {
+		t.Fatalf("WhyDependsOn failed: %v", err)
+	}
+	if chain == nil || len(chain) != 0 {
+		t.Errorf("expected an empty chain, got %#v", chain)
+	}
+}
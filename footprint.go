@@ -0,0 +1,144 @@
+package godepfind
+
+import (
+	"go/build"
+	"unsafe"
+)
+
+// Rough per-value sizes used to estimate the cache footprint
+const (
+	stringHeaderBytes = int64(unsafe.Sizeof(""))
+	sliceHeaderBytes  = int64(unsafe.Sizeof([]string(nil)))
+	mapEntryBytes     = 16 // bucket bookkeeping per entry (tophash, overflow share)
+)
+
+// CacheStats holds entry counts and estimated memory use of the cache
+type CacheStats struct {
+	Packages             int   // packageCache entries
+	PackageBytes         int64 // estimated bytes of packageCache
+	DependencyEdges      int   // edges in dependencyGraph
+	DependencyGraphBytes int64 // estimated bytes of dependencyGraph
+	ReverseEdges         int   // edges in reverseDeps
+	ReverseDepsBytes     int64 // estimated bytes of reverseDeps
+	FilePaths            int   // filePathToPackage entries
+	FileNames            int   // fileToPackages entries
+	TestFiles            int   // fileToTestPackages entries
+	FileMapBytes         int64 // estimated bytes of the three file maps
+	ExternalPackages     int   // externalPackages entries (SetIncludeExternalModules)
+	ExternalBytes        int64 // estimated bytes of externalPackages
+	AuxiliaryBytes       int64 // estimated bytes of embedFiles, lineCounts, importHashes, duplicateFiles, reachability and listMemo
+	TotalBytes           int64 // sum of all estimates
+}
+
+// CacheFootprint returns entry counts and estimated memory use of the cache,
+// computed from map sizes, slice lengths and string lengths. Every map a
+// rebuild fills is counted; listMemo entries count their keys and pointers
+// only, since the packages they point to are those of packageCache. Estimates
+// ignore allocator overhead and strings shared between structures, so they
+// are a trend indicator rather than an exact measure. The cache is not
+// initialized.
+func (g *GoDepFind) CacheFootprint() CacheStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var stats CacheStats
+
+	stats.Packages = len(g.packageCache)
+	for pkgPath, pkg := range g.packageCache {
+		stats.PackageBytes += mapEntryBytes + stringBytes(pkgPath) + int64(unsafe.Sizeof(pkg))
+		if pkg != nil {
+			stats.PackageBytes += packageBytes(pkg)
+		}
+	}
+
+	stats.DependencyEdges, stats.DependencyGraphBytes = adjacencyFootprint(g.dependencyGraph)
+	stats.ReverseEdges, stats.ReverseDepsBytes = adjacencyFootprint(g.reverseDeps)
+
+	stats.FilePaths = len(g.filePathToPackage)
+	for path, pkg := range g.filePathToPackage {
+		stats.FileMapBytes += mapEntryBytes + stringBytes(path) + stringBytes(pkg)
+	}
+	stats.FileNames = len(g.fileToPackages)
+	for name, pkgs := range g.fileToPackages {
+		stats.FileMapBytes += mapEntryBytes + stringBytes(name) + stringsBytes(pkgs)
+	}
+
+	stats.TestFiles = len(g.fileToTestPackages)
+	for path, pkgs := range g.fileToTestPackages {
+		stats.FileMapBytes += mapEntryBytes + stringBytes(path) + stringsBytes(pkgs)
+	}
+
+	stats.ExternalPackages = len(g.externalPackages)
+	for pkgPath, pkg := range g.externalPackages {
+		stats.ExternalBytes += mapEntryBytes + stringBytes(pkgPath) + int64(unsafe.Sizeof(pkg))
+		if pkg != nil {
+			stats.ExternalBytes += packageBytes(pkg)
+		}
+	}
+
+	_, embedBytes := adjacencyFootprint(g.embedFiles)
+	_, duplicateBytes := adjacencyFootprint(g.duplicateFiles)
+	stats.AuxiliaryBytes = embedBytes + duplicateBytes
+	for pkgPath := range g.lineCounts {
+		stats.AuxiliaryBytes += mapEntryBytes + stringBytes(pkgPath) + int64(unsafe.Sizeof(int(0)))
+	}
+	for pkgPath := range g.importHashes {
+		stats.AuxiliaryBytes += mapEntryBytes + stringBytes(pkgPath) + int64(unsafe.Sizeof(uint64(0)))
+	}
+	for pkgPath := range g.reachable {
+		stats.AuxiliaryBytes += mapEntryBytes + stringBytes(pkgPath) + 1
+	}
+	for _, list := range [][]string{g.nowReachable, g.nowUnreachable} {
+		if list != nil {
+			stats.AuxiliaryBytes += stringsBytes(list)
+		}
+	}
+	for pattern, memo := range g.listMemo {
+		stats.AuxiliaryBytes += mapEntryBytes + stringBytes(pattern)
+		for pkgPath, pkg := range memo {
+			stats.AuxiliaryBytes += mapEntryBytes + stringBytes(pkgPath) + int64(unsafe.Sizeof(pkg))
+		}
+	}
+
+	stats.TotalBytes = stats.PackageBytes + stats.DependencyGraphBytes + stats.ReverseDepsBytes + stats.FileMapBytes +
+		stats.ExternalBytes + stats.AuxiliaryBytes
+	return stats
+}
+
+// adjacencyFootprint returns the edge count and estimated bytes of an adjacency map
+func adjacencyFootprint(graph map[string][]string) (edges int, bytes int64) {
+	for pkgPath, deps := range graph {
+		edges += len(deps)
+		bytes += mapEntryBytes + stringBytes(pkgPath) + stringsBytes(deps)
+	}
+	return edges, bytes
+}
+
+// packageBytes estimates the memory held by a build.Package
+func packageBytes(pkg *build.Package) int64 {
+	bytes := int64(unsafe.Sizeof(*pkg))
+	for _, s := range []string{pkg.Dir, pkg.Name, pkg.ImportComment, pkg.Doc, pkg.ImportPath, pkg.Root, pkg.SrcRoot, pkg.PkgRoot, pkg.PkgTargetRoot, pkg.BinDir, pkg.PkgObj} {
+		bytes += int64(len(s))
+	}
+	for _, list := range [][]string{
+		pkg.GoFiles, pkg.CgoFiles, pkg.IgnoredGoFiles, pkg.InvalidGoFiles, pkg.IgnoredOtherFiles,
+		pkg.TestGoFiles, pkg.XTestGoFiles, pkg.Imports, pkg.TestImports, pkg.XTestImports,
+		pkg.EmbedPatterns, pkg.TestEmbedPatterns, pkg.XTestEmbedPatterns, pkg.AllTags,
+	} {
+		bytes += stringsBytes(list) - sliceHeaderBytes // headers already counted in Sizeof
+	}
+	return bytes
+}
+
+// stringBytes estimates the memory held by a string
+func stringBytes(s string) int64 {
+	return stringHeaderBytes + int64(len(s))
+}
+
+// stringsBytes estimates the memory held by a slice of strings
+func stringsBytes(list []string) int64 {
+	bytes := sliceHeaderBytes
+	for _, s := range list {
+		bytes += stringBytes(s)
+	}
+	return bytes
+}
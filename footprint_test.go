@@ -0,0 +1,64 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheFootprint(t *testing.T) {
+	finder := New("testproject")
+	if stats := finder.CacheFootprint(); stats.TotalBytes != 0 || stats.Packages != 0 {
+		t.Errorf("expected empty footprint before the first rebuild, got %+v", stats)
+	}
+
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+	before := finder.CacheFootprint()
+	if before.Packages != 7 {
+		t.Errorf("expected 7 packages, got %d", before.Packages)
+	}
+	if before.PackageBytes == 0 || before.DependencyEdges == 0 || before.DependencyGraphBytes == 0 ||
+		before.ReverseEdges == 0 || before.ReverseDepsBytes == 0 || before.FilePaths == 0 ||
+		before.FileNames == 0 || before.FileMapBytes == 0 || before.AuxiliaryBytes == 0 {
+		t.Errorf("expected non-zero stats after rebuild, got %+v", before)
+	}
+	if sum := before.PackageBytes + before.DependencyGraphBytes + before.ReverseDepsBytes + before.FileMapBytes +
+		before.ExternalBytes + before.AuxiliaryBytes; sum != before.TotalBytes {
+		t.Errorf("TotalBytes = %d, want sum %d", before.TotalBytes, sum)
+	}
+
+	// Invalidating a package drops it from the package cache and graphs
+	if err := finder.invalidatePackageCache(filepath.Join("testproject", "modules", "module2", "module2.go")); err != nil {
+		t.Fatalf("invalidation failed: %v", err)
+	}
+	after := finder.CacheFootprint()
+	if after.Packages != before.Packages-1 {
+		t.Errorf("expected %d packages after invalidation, got %d", before.Packages-1, after.Packages)
+	}
+	if after.TotalBytes >= before.TotalBytes || after.PackageBytes >= before.PackageBytes {
+		t.Errorf("expected footprint to shrink: before %+v, after %+v", before, after)
+	}
+}
+
+func TestCacheFootprintCountsEveryMap(t *testing.T) {
+	files := chainModuleFiles()
+	files["internal/leaf/leaf_test.go"] = "package leaf\n"
+	finder := New(writeTestModule(t, files))
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+	before := finder.CacheFootprint()
+	if before.TestFiles != 1 {
+		t.Errorf("expected 1 test file, got %d", before.TestFiles)
+	}
+
+	// Line counts are computed lazily and show up once requested
+	if _, err := finder.PackageLineCounts(); err != nil {
+		t.Fatalf("PackageLineCounts failed: %v", err)
+	}
+	after := finder.CacheFootprint()
+	if after.AuxiliaryBytes <= before.AuxiliaryBytes || after.TotalBytes <= before.TotalBytes {
+		t.Errorf("expected line counts to grow the footprint: before %+v, after %+v", before, after)
+	}
+}
@@ -0,0 +1,262 @@
+package godepfind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
+	"os"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syntheticModuleFiles builds a module with n library packages chained by imports
+// and a main importing the last one
+func syntheticModuleFiles(n int) map[string]string {
+	files := map[string]string{"go.mod": "module synth\n\ngo 1.21\n"}
+	for i := 0; i < n; i++ {
+		src := fmt.Sprintf("package pkg%d\n\nfunc F() {}\n", i)
+		if i > 0 {
+			src = fmt.Sprintf("package pkg%d\n\nimport \"synth/pkg%d\"\n\nfunc F() { pkg%d.F() }\n", i, i-1, i-1)
+		}
+		files[fmt.Sprintf("pkg%d/pkg.go", i)] = src
+	}
+	files["cmd/main.go"] = fmt.Sprintf("package main\n\nimport \"synth/pkg%d\"\n\nfunc main() { pkg%d.F() }\n", n-1, n-1)
+	return files
+}
+
+func TestGetPackagesParallelMatchesSequential(t *testing.T) {
+	finder := New("testproject")
+	paths, err := finder.listPackages("./...")
+	if err != nil {
+		t.Fatalf("listPackages failed: %v", err)
+	}
+
+	sequential, err := finder.getPackagesWithWorkers(context.Background(), paths, 1)
+	if err != nil {
+		t.Fatalf("sequential getPackages failed: %v", err)
+	}
+	parallel, err := finder.getPackagesWithWorkers(context.Background(), paths, 8)
+	if err != nil {
+		t.Fatalf("parallel getPackages failed: %v", err)
+	}
+
+	if len(sequential) != len(parallel) {
+		t.Fatalf("result size mismatch: sequential %d, parallel %d", len(sequential), len(parallel))
+	}
+	for path, seqPkg := range sequential {
+		parPkg, ok := parallel[path]
+		if !ok {
+			t.Errorf("parallel result missing %s", path)
+			continue
+		}
+		if seqPkg.Dir != parPkg.Dir || seqPkg.Name != parPkg.Name || !reflect.DeepEqual(seqPkg.Imports, parPkg.Imports) {
+			t.Errorf("package %s differs between sequential and parallel import", path)
+		}
+	}
+}
+
+func TestGetPackagesParallelReturnsError(t *testing.T) {
+	finder := New("testproject")
+	paths := []string{"testproject/appAserver", "example.invalid/does/not/exist", "testproject/appBcmd"}
+
+	if _, err := finder.getPackagesWithWorkers(context.Background(), paths, 4); err == nil {
+		t.Error("expected error for unresolvable package path")
+	}
+}
+
+// benchmarkPackages is the size of the synthetic tree used to compare serial
+// and parallel imports
+const benchmarkPackages = 200
+
+func BenchmarkGetPackagesSequential(b *testing.B) {
+	finder := New(writeTestModule(b, syntheticModuleFiles(benchmarkPackages)))
+	paths, err := finder.listPackages("./...")
+	if err != nil {
+		b.Fatalf("listPackages failed: %v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := finder.getPackagesWithWorkers(context.Background(), paths, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetPackagesParallel(b *testing.B) {
+	finder := New(writeTestModule(b, syntheticModuleFiles(benchmarkPackages)))
+	paths, err := finder.listPackages("./...")
+	if err != nil {
+		b.Fatalf("listPackages failed: %v", err)
+	}
+	b.ResetTimer()
+	b.ReportMetric(float64(finder.parallelism()), "workers")
+	for i := 0; i < b.N; i++ {
+		if _, err := finder.getPackages(paths); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSetMaxParallelismBoundsWorkers(t *testing.T) {
+	finder := New(writeTestModule(t, syntheticModuleFiles(30)))
+	finder.SetMaxParallelism(3)
+	finder.SetPerPackageImport(true)
+
+	var mu sync.Mutex
+	running, peak, calls := 0, 0, 0
+	finder.importer = func(path string) (*build.Package, error) {
+		mu.Lock()
+		running++
+		calls++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(2 * time.Millisecond)
+		pkg, err := finder.importPackage(path)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return pkg, err
+	}
+
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatalf("rebuildCache failed: %v", err)
+	}
+	if calls != 31 {
+		t.Errorf("expected 31 package imports, got %d", calls)
+	}
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent workers, observed %d", peak)
+	}
+
+	finder.SetMaxParallelism(0)
+	if finder.parallelism() != runtime.GOMAXPROCS(0) {
+		t.Errorf("expected default parallelism GOMAXPROCS, got %d", finder.parallelism())
+	}
+}
+
+func TestRebuildCacheContextCancelledLeavesCache(t *testing.T) {
+	finder := New("testproject")
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+	packagesBefore := len(finder.packageCache)
+	mainsBefore := append([]string{}, finder.mainPackages...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := finder.RebuildCacheContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !finder.cachedModule || len(finder.packageCache) != packagesBefore || !reflect.DeepEqual(finder.mainPackages, mainsBefore) {
+		t.Error("expected a cancelled rebuild to leave the cache untouched")
+	}
+}
+
+func TestRebuildCacheContextStopsImporting(t *testing.T) {
+	finder := New(writeTestModule(t, syntheticModuleFiles(30)))
+	finder.SetMaxParallelism(1)
+	finder.SetPerPackageImport(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	imported := 0
+	finder.importer = func(path string) (*build.Package, error) {
+		mu.Lock()
+		imported++
+		if imported == 3 {
+			cancel()
+		}
+		mu.Unlock()
+		return finder.importPackage(path)
+	}
+
+	if err := finder.RebuildCacheContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if imported >= 31 {
+		t.Errorf("expected imports to stop after cancellation, imported %d packages", imported)
+	}
+	if finder.cachedModule || len(finder.packageCache) != 0 {
+		t.Error("expected the cache to remain unbuilt after a cancelled rebuild")
+	}
+
+	// A later rebuild without cancellation succeeds
+	finder.importer = nil
+	if err := finder.RebuildCacheContext(context.Background()); err != nil {
+		t.Fatalf("RebuildCacheContext failed: %v", err)
+	}
+	if len(finder.mainPackages) != 1 {
+		t.Errorf("expected 1 main package, got %v", finder.mainPackages)
+	}
+}
+
+func BenchmarkLoadPackagesListAndImport(b *testing.B) {
+	finder := New(writeTestModule(b, syntheticModuleFiles(benchmarkPackages)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		paths, err := finder.listPackages("./...")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := finder.getPackages(paths); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadPackagesJSON(b *testing.B) {
+	finder := New(writeTestModule(b, syntheticModuleFiles(benchmarkPackages)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := finder.listPackagesJSON(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDirectoriesWithoutGoFilesAreSkipped(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":              "module testmod\n\ngo 1.21\n",
+		"app/main.go":         "package main\n\nimport _ \"testmod/nested/lib\"\n\nfunc main() {}\n",
+		"nested/lib/lib.go":   "package lib\n", // nested holds only a directory
+		"empty/.keep":         "",
+		"onlytests/x_test.go": "package onlytests\n",
+		"special/special.go":  "//go:build special\n\npackage special\n",
+	})
+	// go list sees the tag from GOFLAGS, go/build doesn't: special is listed
+	// but has no buildable file for the import context
+	t.Setenv("GOFLAGS", os.Getenv("GOFLAGS")+" -tags=special")
+
+	for _, jsonListing := range []bool{true, false} {
+		finder := New(root)
+		finder.SetPerPackageImport(!jsonListing)
+		if err := finder.rebuildCache(); err != nil {
+			t.Fatalf("json=%v: rebuildCache failed: %v", jsonListing, err)
+		}
+		if !reflect.DeepEqual(finder.mainPackages, []string{"testmod/app"}) {
+			t.Errorf("json=%v: expected main testmod/app, got %v", jsonListing, finder.mainPackages)
+		}
+		if finder.packageCache["testmod/nested/lib"] == nil {
+			t.Errorf("json=%v: expected testmod/nested/lib to be cached", jsonListing)
+		}
+	}
+
+	// Importing a directory without Go files directly is skipped too
+	finder := New(root)
+	packages, err := finder.getPackagesWithWorkers(context.Background(), []string{"testmod/empty", "testmod/nested/lib"}, 2)
+	if err != nil {
+		t.Fatalf("getPackagesWithWorkers failed: %v", err)
+	}
+	if len(packages) != 1 || packages["testmod/nested/lib"] == nil {
+		t.Errorf("expected only testmod/nested/lib, got %v", packages)
+	}
+}
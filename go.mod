@@ -1,3 +1,7 @@
 module github.com/cdvelop/godepfind
 
 go 1.24.4
+
+require github.com/fsnotify/fsnotify v1.9.0
+
+require golang.org/x/sys v0.13.0 // indirect
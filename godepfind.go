@@ -1,26 +1,94 @@
 package godepfind
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"go/build"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type GoDepFind struct {
-	rootDir     string
-	testImports bool
+	// mu guards the cache and the configuration: queries hold the read lock,
+	// events, rebuilds and setters hold the write lock
+	mu sync.RWMutex
+
+	rootDir      string
+	extraRoots   []string // peer module roots analyzed with rootDir (NewMulti)
+	testImports  bool     // imports of in-package _test files
+	xtestImports bool     // imports of external (package foo_test) _test files
+	buildTags    []string
+	goos         string // target platform; empty uses the host default
+	goarch       string
+	goBinary     string // go tool executable; empty means "go" from PATH
+
+	autoRefresh       bool      // rebuild when the tree changed since builtModTime (SetAutoRefresh)
+	builtModTime      time.Time // latest directory modification time when the cache was built
+	includeExternal   bool      // load packages of external modules into the graph
+	excludeStdlib     bool      // leave standard library imports out of the graph
+	trackEmbeds       bool      // map //go:embed files to their package
+	caseFoldPaths     bool      // file mappings keyed by lowercased paths and names
+	ignorePatterns    []string  // globs of packages and files left out of the analysis
+	ownershipExcludes []string  // globs of packages never owned through imports (ExcludeFromOwnership)
+
+	// Import path resolution
+	moduleMode ModuleMode
+	resolution *moduleResolution // detected at the last rebuild
+
+	// Concurrency
+	maxParallelism   int                                       // 0 means runtime.GOMAXPROCS(0)
+	perPackageImport bool                                      // list paths, then import each directory (SetPerPackageImport)
+	importer         func(path string) (*build.Package, error) // overrides importPackage in that loader (tests)
+
+	// go list bookkeeping, written under the read lock
+	listMu          sync.Mutex                           // guards the fields below
+	lastListCommand []string                             // program followed by its arguments
+	lastListDir     string                               // absolute directory it ran in
+	lastListEnv     []string                             // Go variables of its environment
+	listCount       int                                  // go list invocations so far
+	listMemo        map[string]map[string]*build.Package // pattern -> imported packages, for the current cache
+
+	// Update bookkeeping
+	updateStats UpdateStats
+	onRebuild   func(stats UpdateStats)
 
 	// Cache fields
-	cachedModule      bool
-	packageCache      map[string]*build.Package
-	dependencyGraph   map[string][]string // pkg -> dependencies
-	reverseDeps       map[string][]string // pkg -> reverse dependencies
-	filePathToPackage map[string]string   // absolute file path -> package path (NEW: unique mapping)
-	fileToPackages    map[string][]string // filename -> list of package paths (NEW: multiple packages per filename)
-	mainPackages      []string
+	cachedModule       bool
+	packageCache       map[string]*build.Package
+	dependencyGraph    map[string][]string // pkg -> dependencies
+	reverseDeps        map[string][]string // pkg -> reverse dependencies
+	filePathToPackage  map[string]string   // absolute file path -> package path (NEW: unique mapping)
+	fileToPackages     map[string][]string // filename -> list of package paths (NEW: multiple packages per filename)
+	fileToTestPackages map[string][]string // absolute _test.go path -> packages under test (always tracked)
+	mainPackages       []string
+	lineCounts         map[string]int            // pkg -> source lines in GoFiles (computed lazily)
+	importHashes       map[string]uint64         // pkg -> importsHash of its last go/build import (write fast path)
+	duplicateFiles     map[string][]string       // real file path -> packages listing it (only when > 1)
+	externalPackages   map[string]*build.Package // third-party packages (SetIncludeExternalModules)
+	embedFiles         map[string][]string       // pkg -> files embedded per go list -json, relative to its Dir
+
+	// Reachability tracking across rebuilds
+	reachable      map[string]bool // packages reachable from any main at the last rebuild
+	nowReachable   []string
+	nowUnreachable []string
+
+	// Handler registration
+	strictHandlers bool
+	handlers       []string               // registered handler main files
+	assetRoots     map[string][]string    // handler -> asset directories (relative to rootDir)
+	handlerTags    map[string][]string    // handler -> extra build tags of its main
+	handlerTargets map[string]buildTarget // handler -> GOOS/GOARCH of its main
+
+	// Build output -> source main resolution
+	artifactResolver func(artifactPath string) (mainPkg string, ok bool)
 }
 
 // New creates a new GoDepFind instance with the specified root directory
@@ -29,18 +97,40 @@ func New(rootDir string) *GoDepFind {
 		rootDir = "."
 	}
 	return &GoDepFind{
-		rootDir:           rootDir,
-		testImports:       false,
-		cachedModule:      false,
-		packageCache:      make(map[string]*build.Package),
-		dependencyGraph:   make(map[string][]string),
-		reverseDeps:       make(map[string][]string),
-		filePathToPackage: make(map[string]string),
-		fileToPackages:    make(map[string][]string),
-		mainPackages:      []string{},
+		rootDir:            rootDir,
+		testImports:        false,
+		cachedModule:       false,
+		packageCache:       make(map[string]*build.Package),
+		dependencyGraph:    make(map[string][]string),
+		reverseDeps:        make(map[string][]string),
+		filePathToPackage:  make(map[string]string),
+		fileToPackages:     make(map[string][]string),
+		fileToTestPackages: make(map[string][]string),
+		mainPackages:       []string{},
+		lineCounts:         make(map[string]int),
+		importHashes:       make(map[string]uint64),
+		assetRoots:         make(map[string][]string),
+		handlerTags:        make(map[string][]string),
+		handlerTargets:     make(map[string]buildTarget),
 	}
 }
 
+// NewMulti creates a GoDepFind analyzing several peer module roots (e.g. a
+// frontend and a backend module without a go.work) as a single tree. The
+// first root plays the role of rootDir; packages of every root are listed,
+// cached and routed together. Paths relative to "the root" are resolved
+// against the first root under which they exist. When two roots declare the
+// same import path, it resolves to the directory of the first root declaring
+// it, while files are always mapped by their absolute path.
+func NewMulti(roots ...string) *GoDepFind {
+	if len(roots) == 0 {
+		return New("")
+	}
+	g := New(roots[0])
+	g.extraRoots = append([]string{}, roots[1:]...)
+	return g
+}
+
 // ThisFileIsMine decides whether the provided handler (identified by its
 // main file path relative to the module root) should handle an event for the
 // given file. It normalizes paths, validates the handler main file exists,
@@ -56,117 +146,484 @@ func New(rootDir string) *GoDepFind {
 // (and its build tags/imports) determines ownership.
 //
 // Inputs:
-//   - mainInputFileRelativePath: handler main file (e.g. "pwa/main.server.go")
+//   - mainInputFileRelativePath: handler main file (e.g. "pwa/main.server.go"),
+//     or the import path of its main package (e.g. "testproject/appAserver"),
+//     which is matched exactly against the main packages
 //   - fileAbsPath: target file path (absolute or relative to module root)
-//   - event: one of "write","create","remove","rename" (drives cache ops)
+//   - event: EventWrite, EventCreate, EventRemove or EventRename (drives
+//     cache ops); "delete" is accepted as EventRemove, unknown events fail
 //
 // Returns: (bool, error) — true when the handler should process the file.
-func (g *GoDepFind) ThisFileIsMine(mainInputFileRelativePath, fileAbsPath, event string) (bool, error) {
+func (g *GoDepFind) ThisFileIsMine(mainInputFileRelativePath, fileAbsPath string, event FileEvent) (bool, error) {
+	event, err := normalizeEvent(event)
+	if err != nil {
+		return false, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
+	isMine, _, err := g.thisFileIsMine(mainInputFileRelativePath, fileAbsPath, event)
+	return isMine, err
+}
+
+// ThisFileIsMineWithConfidence behaves like ThisFileIsMine but also reports how
+// reliable the decision is: ConfidenceHigh when the file was resolved by its
+// exact path (or is the handler main file), ConfidenceLow when the package was
+// guessed from the file name alone. Strict callers can reject low-confidence
+// decisions and retry once the file is registered (e.g. after a create event).
+func (g *GoDepFind) ThisFileIsMineWithConfidence(mainInputFileRelativePath, fileAbsPath string, event FileEvent) (bool, Confidence, error) {
+	event, err := normalizeEvent(event)
+	if err != nil {
+		return false, ConfidenceHigh, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
+	return g.thisFileIsMine(mainInputFileRelativePath, fileAbsPath, event)
+}
+
+// thisFileIsMine implements ThisFileIsMine, reporting the decision confidence.
+// An empty event queries ownership without updating the cache.
+func (g *GoDepFind) thisFileIsMine(mainInputFileRelativePath, fileAbsPath string, event FileEvent) (bool, Confidence, error) {
+	decision := &OwnershipDecision{}
+	if err := g.decideOwnership(mainInputFileRelativePath, fileAbsPath, event, decision); err != nil {
+		return false, ConfidenceHigh, err
+	}
+	return decision.Owned, decision.Confidence, nil
+}
+
+// decideOwnership applies the ownership rules, recording in d the packages
+// involved and the rule that decided
+func (g *GoDepFind) decideOwnership(mainInputFileRelativePath, fileAbsPath string, event FileEvent, d *OwnershipDecision) error {
 	// 1. Basic input validation
 	if fileAbsPath == "" {
-		return false, fmt.Errorf("fileAbsPath cannot be empty")
+		return fmt.Errorf("fileAbsPath cannot be empty")
 	}
 	if mainInputFileRelativePath == "" {
-		return false, fmt.Errorf("handler mainInputFileRelativePath cannot be empty")
+		return fmt.Errorf("handler mainInputFileRelativePath cannot be empty")
 	}
 
 	// 2. Normalize file path to absolute
-	if !filepath.IsAbs(fileAbsPath) {
-		fileAbsPath = filepath.Join(g.rootDir, fileAbsPath)
-	}
+	fileAbsPath = g.rootPath(fileAbsPath)
 	absFilePath, err := filepath.Abs(fileAbsPath)
 	if err != nil {
-		return false, fmt.Errorf("cannot resolve fileAbsPath to absolute path: %w", err)
+		return fmt.Errorf("cannot resolve fileAbsPath to absolute path: %w", err)
 	}
 	fileAbsPath = absFilePath
+	d.File = fileAbsPath
 
-	// 3. CRITICAL: Verify handler's main file exists
-	handlerMainAbsPath := mainInputFileRelativePath
-	if !filepath.IsAbs(handlerMainAbsPath) {
-		handlerMainAbsPath = filepath.Join(g.rootDir, mainInputFileRelativePath)
+	// 3. CRITICAL: Verify handler's main file exists (unless the handler is
+	// identified by the import path of its main package)
+	handlerMainPkg, byImportPath, err := g.handlerMainImportPath(mainInputFileRelativePath)
+	if err != nil {
+		return err
 	}
-	if _, err := os.Stat(handlerMainAbsPath); err != nil {
-		if os.IsNotExist(err) {
-			return false, fmt.Errorf("handler main file does not exist: %s", mainInputFileRelativePath)
+	if !byImportPath {
+		handlerMainAbsPath := g.rootPath(mainInputFileRelativePath)
+		if _, err := os.Stat(handlerMainAbsPath); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("handler main file does not exist: %s", mainInputFileRelativePath)
+			}
+			return fmt.Errorf("cannot access handler main file %s: %w", mainInputFileRelativePath, err)
 		}
-		return false, fmt.Errorf("cannot access handler main file %s: %w", mainInputFileRelativePath, err)
+		if absHandler, err := filepath.Abs(handlerMainAbsPath); err == nil {
+			handlerMainPkg, _ = g.lookupFilePath(absHandler)
+		}
+	}
+	d.HandlerPackage = handlerMainPkg
+
+	// 4. Files outside rootDir (e.g. on another volume) never belong to a
+	// handler, unless they are in a directory a go.mod replace points to or
+	// in the module rootDir is a subdirectory of
+	relativeFilePath, insideRoot := g.relativeToRoot(fileAbsPath)
+	if !insideRoot && !g.currentResolution().inReplacedDir(fileAbsPath) && !g.inModuleAboveRoot(fileAbsPath) {
+		d.decide(false, ConfidenceHigh, CaseOutsideRoot)
+		return nil
 	}
 
-	// 4. Validate target file (skip if file doesn't exist or is being written)
+	// 5. Module files reshape the package graph: any event rebuilds the cache,
+	// and the handler is affected when its main builds packages they govern
+	if isModuleFile(fileAbsPath) {
+		if event != "" {
+			if err := g.rebuildCache(); err != nil {
+				return fmt.Errorf("cache update failed: %w", err)
+			}
+			if !byImportPath {
+				if absHandler, err := filepath.Abs(g.rootPath(mainInputFileRelativePath)); err == nil {
+					handlerMainPkg, _ = g.lookupFilePath(absHandler)
+				}
+				d.HandlerPackage = handlerMainPkg
+			}
+		}
+		d.decide(g.moduleFileAffectsMain(fileAbsPath, handlerMainPkg), ConfidenceHigh, CaseModuleFile)
+		return nil
+	}
+
+	// 6. Files under an asset directory declared by the handler are routed by
+	// path containment, independent of Go import analysis
+	// (asset directories are under the root, replacement directories never are)
+	if owned, isAssetHandler := g.matchesAssetRoot(mainInputFileRelativePath, relativeFilePath); owned || isAssetHandler {
+		d.decide(owned && insideRoot, ConfidenceHigh, CaseAssetRoot)
+		return nil
+	}
+
+	// 7. Validate target file (skip if file doesn't exist or is being written).
+	// A created or renamed file may not exist or be empty yet: it is pending
+	// and resolved by its directory instead
+	pending := false
 	if filepath.Ext(fileAbsPath) == ".go" {
 		validator := NewGoFileValidator()
-		if isValid, err := validator.IsValidGoFile(fileAbsPath); err != nil {
-			return false, fmt.Errorf("file validation failed: %w", err)
-		} else if !isValid {
+		isValid, err := validator.IsValidGoFile(fileAbsPath)
+		switch {
+		case (err != nil || !isValid) && (event == EventCreate || event == EventRename):
+			// Without a cache (go list may refuse the unparseable file) there
+			// is no directory to resolve it by: skip it like a partial write
+			if cacheErr := g.ensureCacheInitialized(); cacheErr != nil {
+				d.decide(false, ConfidenceHigh, CaseInvalidGoFile)
+				return nil
+			}
+			pending = true
+		case err != nil:
+			return fmt.Errorf("file validation failed: %w", err)
+		case !isValid:
 			// File is invalid/empty/being written - skip processing
-			return false, nil
+			d.decide(false, ConfidenceHigh, CaseInvalidGoFile)
+			return nil
 		}
 	}
 
-	// 5. Direct file comparison - is this the handler's own main file?
-	relativeFilePath := strings.TrimPrefix(fileAbsPath, g.rootDir+"/")
-	isHandlerMainFile := relativeFilePath == mainInputFileRelativePath
+	// Handlers identified by import path match their main package exactly
+	if byImportPath {
+		return g.checkImportPathOwnership(handlerMainPkg, fileAbsPath, event, pending, d)
+	}
+
+	// 8. Direct file comparison - is this the handler's own main file?
+	isHandlerMainFile := sameRelativePath(g.pathKey(relativeFilePath), g.pathKey(mainInputFileRelativePath))
 
 	if isHandlerMainFile {
-		// 6. CRITICAL: If this is the handler's main file, update cache for dynamic dependencies
+		// 9. CRITICAL: If this is the handler's main file, update cache for dynamic dependencies
 		// This handles cases where main.go is modified to add/remove imports
-		if err := g.updateCacheForFileWithContext(fileAbsPath, event, mainInputFileRelativePath); err != nil {
-			return false, fmt.Errorf("cache update failed: %w", err)
+		// (an ownership query without event leaves the cache untouched)
+		if event != "" {
+			if err := g.updateCacheForFileWithContext(fileAbsPath, event, mainInputFileRelativePath); err != nil {
+				return fmt.Errorf("cache update failed: %w", err)
+			}
+		}
+		d.TargetPackage = handlerMainPkg
+		d.decide(true, ConfidenceHigh, CaseHandlerMainFile)
+		return nil
+	}
+
+	// 10. Handlers built with their own tags or platform decide from their own build
+	if g.hasHandlerBuild(mainInputFileRelativePath) {
+		owned, confidence, err := g.checkTaggedOwnership(mainInputFileRelativePath, fileAbsPath)
+		if err != nil {
+			return err
 		}
-		return true, nil
+		if targetPkg := g.packageForDir(filepath.Dir(fileAbsPath)); owned && targetPkg != handlerMainPkg && g.excludedFromOwnership(targetPkg) {
+			d.TargetPackage = targetPkg
+			d.decide(false, ConfidenceHigh, CaseExcludedFromOwnership)
+			return nil
+		}
+		d.decide(owned, confidence, CaseHandlerBuild)
+		return nil
+	}
+
+	// 11. For non-main files, check package-based ownership (cache already initialized if needed)
+	return g.checkPackageBasedOwnership(mainInputFileRelativePath, fileAbsPath, pending, d)
+}
+
+// relativeToRoot returns fileAbsPath relative to rootDir (or to the root
+// containing it, see NewMulti). The second result is false when the file is
+// outside every root or cannot be expressed relative to it at all (e.g. a
+// different volume on Windows).
+func (g *GoDepFind) relativeToRoot(fileAbsPath string) (string, bool) {
+	_, rel, inside := g.containingRoot(fileAbsPath)
+	return rel, inside
+}
+
+// containingRoot returns the root containing fileAbsPath and the path relative
+// to it. A file reached through a symlink to a root (or a root reached through
+// one) still counts as inside.
+func (g *GoDepFind) containingRoot(fileAbsPath string) (string, string, bool) {
+	for _, root := range g.roots() {
+		if rel, inside := relativeToDir(root, fileAbsPath); inside {
+			return root, rel, true
+		}
+	}
+	return "", "", false
+}
+
+// relativeToDir returns fileAbsPath relative to the root directory root, when
+// it is inside it
+func relativeToDir(root, fileAbsPath string) (string, bool) {
+	rootAbs, err := filepath.Abs(root)
+	if err != nil {
+		return "", false
+	}
+	if rel, inside := relativeInside(rootAbs, fileAbsPath); inside {
+		return rel, true
+	}
+	rootReal, err := canonicalPath(rootAbs)
+	if err != nil {
+		return "", false
+	}
+	fileReal, err := canonicalPath(fileAbsPath)
+	if err != nil {
+		return "", false
 	}
+	if rootReal == rootAbs && fileReal == fileAbsPath {
+		return "", false // no symlink involved
+	}
+	return relativeInside(rootReal, fileReal)
+}
+
+// sameRelativePath reports whether two root-relative paths name the same file,
+// whatever their separators ("app\main.go" on Windows, "app/main.go" from
+// a config file) or redundant elements ("./app/main.go")
+func sameRelativePath(a, b string) bool {
+	return filepath.ToSlash(filepath.Clean(a)) == filepath.ToSlash(filepath.Clean(b))
+}
 
-	// 7. For non-main files, check package-based ownership (cache already initialized if needed)
-	return g.checkPackageBasedOwnership(mainInputFileRelativePath, fileAbsPath)
+// relativeInside returns target relative to base when it is inside base
+func relativeInside(base, target string) (string, bool) {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return "", false
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
 }
 
-// checkPackageBasedOwnership determines ownership based on Go package dependencies
-func (g *GoDepFind) checkPackageBasedOwnership(mainInputFileRelativePath, fileAbsPath string) (bool, error) {
+// checkPackageBasedOwnership determines ownership based on Go package
+// dependencies. A pending file (created or renamed, not parseable yet) belongs
+// to the package of its directory.
+func (g *GoDepFind) checkPackageBasedOwnership(mainInputFileRelativePath, fileAbsPath string, pending bool, d *OwnershipDecision) error {
 	// Find which package contains the target file
-	targetPkg, err := g.findPackageForFile(fileAbsPath)
+	targetPkg, exact, err := g.resolveDecisionPackage(fileAbsPath, pending, d)
 	if err != nil {
-		return false, err
+		return err
 	}
 	if targetPkg == "" {
-		return false, nil // File not found in any package
+		d.decide(false, ConfidenceHigh, CaseNoPackage) // File not found in any package
+		return nil
+	}
+	d.TargetPackage = targetPkg
+
+	confidence := ConfidenceHigh
+	if !exact {
+		confidence = ConfidenceLow
+		d.fallback("filename-fallback")
+	}
+
+	// Files excluded by the target's build constraints are never compiled
+	if g.isExcludedByConstraints(fileAbsPath) {
+		d.decide(false, ConfidenceHigh, CaseBuildConstraints)
+		return nil
 	}
 
 	// Check if target package should belong to this handler
-	return g.doesPackageBelongToHandler(targetPkg, mainInputFileRelativePath), nil
+	owned, ownershipCase := g.packageBelongsToHandler(targetPkg, mainInputFileRelativePath, d)
+	d.decide(owned, confidence, ownershipCase)
+	return nil
+}
+
+// resolveDecisionPackage is resolvePackageForFile for an ownership decision:
+// a pending file not known by its exact path falls back to the package of its
+// directory, which holds every file created in it
+func (g *GoDepFind) resolveDecisionPackage(fileAbsPath string, pending bool, d *OwnershipDecision) (string, bool, error) {
+	targetPkg, exact, err := g.resolvePackageForFile(fileAbsPath)
+	if err != nil || exact || !pending {
+		return targetPkg, exact, err
+	}
+	if dirPkg := g.packageForDir(filepath.Dir(fileAbsPath)); dirPkg != "" {
+		d.fallback("directory-fallback")
+		return dirPkg, true, nil
+	}
+	return targetPkg, exact, nil
+}
+
+// PackageForFile returns the import path of the package containing a file
+// (absolute or relative to the root), resolved from the cache by exact path
+// first and by file name as a last resort, like ThisFileIsMine does. A file in
+// no package yields an empty string and a nil error.
+func (g *GoDepFind) PackageForFile(fileAbsPath string) (string, error) {
+	if fileAbsPath == "" {
+		return "", fmt.Errorf("fileAbsPath cannot be empty")
+	}
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	absPath, err := filepath.Abs(g.rootPath(fileAbsPath))
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve fileAbsPath to absolute path: %w", err)
+	}
+	return g.findPackageForFile(absPath)
+}
+
+// PackagesContainingFile returns the sorted packages holding a file named
+// fileName (a base name, e.g. "handler.go"): the candidates GoFileComesFromMain
+// considers. More than one package means filename-based routing is ambiguous
+// for that name. An unknown file yields an empty slice.
+func (g *GoDepFind) PackagesContainingFile(fileName string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result := []string{}
+	for _, pkg := range g.fileToPackages[g.pathKey(fileName)] {
+		if !contains(result, pkg) {
+			result = append(result, pkg)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// PackageDir returns the absolute directory of a package, e.g. to open it
+// from a UI: packages of the cache (module and, with
+// SetIncludeExternalModules, external ones) are resolved without running the
+// go tool, others through go list -f {{.Dir}}. An unknown package (or one
+// without a directory) is an error.
+func (g *GoDepFind) PackageDir(pkgPath string) (string, error) {
+	if pkgPath == "" {
+		return "", fmt.Errorf("pkgPath cannot be empty")
+	}
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		pkg = g.externalPackages[pkgPath]
+	}
+	dir := ""
+	if pkg != nil {
+		dir = pkg.Dir
+	} else {
+		listed, err := g.listPackageDir(pkgPath)
+		if err != nil {
+			return "", fmt.Errorf("package not found: %s: %w", pkgPath, err)
+		}
+		dir = listed
+	}
+	if dir == "" {
+		return "", fmt.Errorf("package has no directory: %s", pkgPath)
+	}
+	return filepath.Abs(dir)
 }
 
 // findPackageForFile finds which package contains the given file
 func (g *GoDepFind) findPackageForFile(fileAbsPath string) (string, error) {
+	pkg, _, err := g.resolvePackageForFile(fileAbsPath)
+	return pkg, err
+}
+
+// resolvePackageForFile finds which package contains the given file. exact is
+// false when the package was guessed from the file name only.
+func (g *GoDepFind) resolvePackageForFile(fileAbsPath string) (pkg string, exact bool, err error) {
 	// Ensure cache is initialized
 	if err := g.ensureCacheInitialized(); err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	// Try exact path lookup first (most reliable)
-	if pkg, exists := g.filePathToPackage[fileAbsPath]; exists {
-		return pkg, nil
+	if pkg, exists := g.lookupFilePath(fileAbsPath); exists {
+		return pkg, true, nil
+	}
+
+	// Last resort: filename-based lookup, only reached when the path itself
+	// isn't mapped (e.g. a file not cached yet). Several packages may hold a
+	// file of that name: prefer the one whose directory contains the path,
+	// then the lexicographically smallest (the slices are kept sorted), so
+	// the answer is stable across runs.
+	fileName := filepath.Base(fileAbsPath)
+	packages := g.fileToPackages[g.pathKey(fileName)]
+	if len(packages) == 0 {
+		return "", false, nil
+	}
+	if len(packages) > 1 && filepath.IsAbs(fileAbsPath) {
+		if pkg := g.deepestContainingPackage(packages, fileAbsPath); pkg != "" {
+			return pkg, false, nil
+		}
+	}
+	return packages[0], false, nil
+}
+
+// deepestContainingPackage returns the candidate whose directory contains
+// fileAbsPath, preferring the deepest directory, or "" if none does
+func (g *GoDepFind) deepestContainingPackage(candidates []string, fileAbsPath string) string {
+	best, bestLen := "", -1
+	for _, candidate := range candidates {
+		pkg := g.packageCache[candidate]
+		if pkg == nil {
+			continue
+		}
+		dir, err := filepath.Abs(pkg.Dir)
+		if err != nil {
+			continue
+		}
+		if _, inside := relativeInside(dir, fileAbsPath); inside && len(dir) > bestLen {
+			best, bestLen = candidate, len(dir)
+		}
+	}
+	return best
+}
+
+// isExcludedByConstraints reports whether the file is ignored by the build
+// constraints (GOOS/GOARCH suffixes, build tags) of the package in its directory
+func (g *GoDepFind) isExcludedByConstraints(fileAbsPath string) bool {
+	pkgPath := g.packageForDir(filepath.Dir(fileAbsPath))
+	if pkgPath == "" {
+		return false
+	}
+	pkg := g.packageCache[pkgPath]
+	return pkg != nil && contains(pkg.IgnoredGoFiles, filepath.Base(fileAbsPath))
+}
+
+// lookupFilePath resolves a file path to its package using only the exact path
+// mappings (absolute first, then relative to the working directory)
+func (g *GoDepFind) lookupFilePath(fileAbsPath string) (string, bool) {
+	if pkg, exists := g.filePathToPackage[g.pathKey(fileAbsPath)]; exists {
+		return pkg, true
+	}
+
+	// The path may reach the file through a symlinked directory
+	if realPath, err := canonicalPath(fileAbsPath); err == nil && realPath != fileAbsPath {
+		if pkg, exists := g.filePathToPackage[g.pathKey(realPath)]; exists {
+			return pkg, true
+		}
 	}
 
 	// Fallback: try relative path lookup
 	if cwd, err := os.Getwd(); err == nil {
 		if relPath, err := filepath.Rel(cwd, fileAbsPath); err == nil {
-			if pkg, exists := g.filePathToPackage[relPath]; exists {
-				return pkg, nil
+			if pkg, exists := g.filePathToPackage[g.pathKey(relPath)]; exists {
+				return pkg, true
 			}
 		}
 	}
-
-	// Last resort: filename-based lookup (may be ambiguous)
-	fileName := filepath.Base(fileAbsPath)
-	if packages := g.fileToPackages[fileName]; len(packages) > 0 {
-		return packages[0], nil
-	}
-
-	return "", nil
+	return "", false
 }
 
 // doesPackageBelongToHandler determines if a package should be handled by this handler
 func (g *GoDepFind) doesPackageBelongToHandler(targetPkg, mainInputFileRelativePath string) bool {
+	owned, _ := g.packageBelongsToHandler(targetPkg, mainInputFileRelativePath, &OwnershipDecision{})
+	return owned
+}
+
+// packageBelongsToHandler implements doesPackageBelongToHandler, reporting
+// which case decided and recording the fallbacks used in d
+func (g *GoDepFind) packageBelongsToHandler(targetPkg, mainInputFileRelativePath string, d *OwnershipDecision) (bool, OwnershipCase) {
 	handlerDir := filepath.Dir(mainInputFileRelativePath)
 
 	// Case 1: If target is a main package in the same directory as handler
@@ -176,18 +633,24 @@ func (g *GoDepFind) doesPackageBelongToHandler(targetPkg, mainInputFileRelativeP
 			if mainPkg == targetPkg {
 				if pkg, exists := g.packageCache[mainPkg]; exists && pkg != nil {
 					if relPkgDir, err := filepath.Rel(g.rootDir, pkg.Dir); err == nil {
-						return filepath.Clean(relPkgDir) == filepath.Clean(handlerDir)
+						return filepath.Clean(relPkgDir) == filepath.Clean(handlerDir), CaseMainPackageInDir
 					}
 				}
 				// Fallback: compare package name with handler directory
-				return filepath.Base(targetPkg) == filepath.Base(handlerDir)
+				d.fallback("package-name-dir-match")
+				return filepath.Base(targetPkg) == filepath.Base(handlerDir), CaseMainPackageInDir
 			}
 		}
 	}
 
+	// Packages excluded from ownership are never owned through imports
+	if g.excludedFromOwnership(targetPkg) {
+		return false, CaseExcludedFromOwnership
+	}
+
 	// Case 2: Check if the SPECIFIC handler file imports this target package
 	// This is more precise than checking if any main package in the directory imports it
-	return g.handlerFileImportsPackage(mainInputFileRelativePath, targetPkg)
+	return g.handlerFileImportsPackage(mainInputFileRelativePath, targetPkg), CaseMainImportsPackage
 }
 
 // handlerFileImportsPackage checks if a specific handler file imports the given package
@@ -198,13 +661,10 @@ func (g *GoDepFind) handlerFileImportsPackage(handlerFileRelativePath, targetPkg
 	}
 
 	// Build the absolute path to the handler file
-	handlerAbsPath := handlerFileRelativePath
-	if !filepath.IsAbs(handlerAbsPath) {
-		handlerAbsPath = filepath.Join(g.rootDir, handlerFileRelativePath)
-	}
+	handlerAbsPath := g.rootPath(handlerFileRelativePath)
 
-	// Parse the handler file to extract its imports
-	imports, err := g.parseFileImports(handlerAbsPath)
+	// Collect the imports of the handler file (and its active package siblings)
+	imports, err := g.handlerImports(handlerAbsPath)
 	if err != nil {
 		return false
 	}
@@ -226,6 +686,34 @@ func (g *GoDepFind) handlerFileImportsPackage(handlerFileRelativePath, targetPkg
 	return false
 }
 
+// handlerImports returns the imports of a handler main file. When the file is
+// part of a cached package under the configured build tags, the imports of the
+// package's other active files (e.g. a tools.go guarded by `//go:build tools`)
+// are included too, since they are compiled into the same binary. Files that
+// are excluded by build constraints (e.g. main.wasm.go) only contribute their
+// own imports.
+func (g *GoDepFind) handlerImports(handlerAbsPath string) ([]string, error) {
+	imports, err := g.parseFileImports(handlerAbsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(handlerAbsPath)
+	if err != nil {
+		return imports, nil
+	}
+	if pkgPath, exists := g.lookupFilePath(absPath); exists {
+		if pkg := g.packageCache[pkgPath]; pkg != nil {
+			for _, imp := range pkg.Imports {
+				if !contains(imports, imp) {
+					imports = append(imports, imp)
+				}
+			}
+		}
+	}
+	return imports, nil
+}
+
 // parseFileImports extracts the import statements from a specific Go file
 func (g *GoDepFind) parseFileImports(filePath string) ([]string, error) {
 	// For now, use a simple file parsing approach
@@ -314,19 +802,213 @@ func extractImportPath(line string) string {
 	return line[start+1 : end]
 }
 
-// SetTestImports enables or disables inclusion of test imports
+// SetTestImports enables or disables inclusion of test imports, of both
+// in-package and external test files (see SetExternalTestImports)
 func (g *GoDepFind) SetTestImports(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.testImports = enabled
+	g.xtestImports = enabled
+}
+
+// SetExternalTestImports enables or disables inclusion of the imports of
+// external test files (package foo_test) alone. Calling it after
+// SetTestImports(true) with false keeps only in-package test imports, e.g. so
+// black-box tests don't count as importers of the production code.
+func (g *GoDepFind) SetExternalTestImports(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.xtestImports = enabled
+}
+
+// SetBuildTags sets the build tags used both by the go list invocation and
+// when importing packages, so both agree on which files are active.
+// Changing the tags invalidates the cache.
+func (g *GoDepFind) SetBuildTags(tags []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.buildTags = append([]string{}, tags...)
+	g.cachedModule = false
+}
+
+// SetTargetPlatform sets the GOOS/GOARCH used to evaluate build constraints
+// when listing and importing packages (e.g. "linux", "amd64" or "js", "wasm").
+// Empty values keep the host default. Changing the platform invalidates the cache.
+func (g *GoDepFind) SetTargetPlatform(goos, goarch string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.goos = goos
+	g.goarch = goarch
+	g.cachedModule = false
+}
+
+// LastListCommand returns the program and arguments of the most recent go list
+// invocation, so it can be pasted into a shell to reproduce analysis issues.
+// The directory and environment it ran with are reported by LastListEnv.
+func (g *GoDepFind) LastListCommand() (string, []string) {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+	if len(g.lastListCommand) == 0 {
+		return "", nil
+	}
+	return g.lastListCommand[0], append([]string{}, g.lastListCommand[1:]...)
+}
+
+// LastListEnv completes LastListCommand with the absolute directory the most
+// recent go list invocation ran in and the Go variables of its environment
+// (GOFLAGS, GOOS, GOARCH, GO111MODULE, ... as "NAME=value", sorted by name),
+// whether inherited or set by godepfind: running the command there with
+// these variables reproduces it. Other variables are left out.
+func (g *GoDepFind) LastListEnv() (dir string, env []string) {
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+	return g.lastListDir, append([]string(nil), g.lastListEnv...)
+}
+
+// recordListCommand records a go list invocation for LastListCommand and
+// LastListEnv, and counts it
+func (g *GoDepFind) recordListCommand(cmd *exec.Cmd) {
+	dir, err := filepath.Abs(cmd.Dir)
+	if err != nil {
+		dir = cmd.Dir
+	}
+	g.listMu.Lock()
+	defer g.listMu.Unlock()
+	g.lastListCommand = append([]string{}, cmd.Args...)
+	g.lastListDir = dir
+	g.lastListEnv = goEnviron(cmd.Env)
+	g.listCount++
+}
+
+// goEnviron returns the variables of env read by the go command (GO* and
+// CGO_*), the last value of each winning as in exec.Cmd, sorted by name
+func goEnviron(env []string) []string {
+	values := make(map[string]string)
+	for _, kv := range env {
+		name, _, ok := strings.Cut(kv, "=")
+		if ok && (strings.HasPrefix(name, "GO") || strings.HasPrefix(name, "CGO_")) {
+			values[name] = kv
+		}
+	}
+	result := make([]string, 0, len(values))
+	for _, kv := range values {
+		result = append(result, kv)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// SetGoBinary selects the go tool used to list packages (e.g.
+// "/usr/local/go1.22/bin/go"), for environments where go is not on PATH or a
+// specific toolchain is required. The default is "go". An error is returned
+// when the binary cannot be found or is not executable. Changing the binary
+// invalidates the cache.
+func (g *GoDepFind) SetGoBinary(path string) error {
+	if path == "" {
+		return fmt.Errorf("go binary path cannot be empty")
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		return fmt.Errorf("cannot use go binary %s: %w", path, err)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.goBinary = path
+	g.cachedModule = false
+	return nil
+}
+
+// goProgram returns the go tool executable to run
+func (g *GoDepFind) goProgram() string {
+	if g.goBinary != "" {
+		return g.goBinary
+	}
+	return "go"
+}
+
+// buildContext returns the build context used to import packages
+func (g *GoDepFind) buildContext() *build.Context {
+	ctx := build.Default
+	ctx.BuildTags = append([]string{}, g.buildTags...)
+	if res := g.currentResolution(); res.mode == ModuleModeGOPATH && res.gopath != "" {
+		ctx.GOPATH = res.gopath
+	}
+	if g.goos != "" {
+		ctx.GOOS = g.goos
+	}
+	if g.goarch != "" {
+		ctx.GOARCH = g.goarch
+	}
+	return &ctx
+}
+
+// listEnv returns the environment for go list, honoring the target platform
+func (g *GoDepFind) listEnv() []string {
+	env := append(os.Environ(), g.currentResolution().environ()...)
+	if g.goos != "" {
+		env = append(env, "GOOS="+g.goos)
+	}
+	if g.goarch != "" {
+		env = append(env, "GOARCH="+g.goarch)
+	}
+	if g.maxParallelism > 0 {
+		env = append(env, "GOMAXPROCS="+strconv.Itoa(g.maxParallelism))
+	}
+	return env
+}
+
+// listCommand returns the program and arguments used to run go list for path,
+// with extra flags (e.g. "-json") after the build tags. "./..." stands for the
+// whole project, which spans every module of a go.work workspace.
+func (g *GoDepFind) listCommand(path string, flags ...string) (string, []string) {
+	args := []string{"list"}
+	if len(g.buildTags) > 0 {
+		args = append(args, "-tags", strings.Join(g.buildTags, ","))
+	}
+	args = append(args, flags...)
+	if path == "./..." {
+		return g.goProgram(), append(args, g.currentResolution().listPatterns()...)
+	}
+	return g.goProgram(), append(args, path)
 }
 
 // listPackages returns the result of running "go list" with the specified path
 // It tolerates build constraint errors (e.g., WASM packages) and returns whatever packages
 // it can successfully list, only returning error if no packages are found at all
 func (g *GoDepFind) listPackages(path string) ([]string, error) {
-	cmd := exec.Command("go", "list", path)
-	cmd.Dir = g.rootDir
-	// Don't redirect stderr to os.Stderr to avoid polluting logs with build constraint warnings
+	return g.listPackagesContext(context.Background(), path)
+}
+
+// listPackagesContext is listPackages killing go list when ctx is cancelled,
+// in which case ctx.Err() is returned. The roots of NewMulti are listed one
+// by one, since each is a module of its own.
+func (g *GoDepFind) listPackagesContext(ctx context.Context, path string) ([]string, error) {
+	res := g.currentResolution()
+	if path != "./..." || !res.peers {
+		return g.listPackagesIn(ctx, g.rootDir, path)
+	}
+	var packages []string
+	for _, module := range res.workspace {
+		listed, err := g.listPackagesIn(ctx, module.dir, path)
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, listed...)
+	}
+	return packages, nil
+}
+
+// listPackagesIn runs go list for path in the directory dir
+func (g *GoDepFind) listPackagesIn(ctx context.Context, dir, path string) ([]string, error) {
+	program, args := g.listCommand(path)
+	cmd := exec.CommandContext(ctx, program, args...)
+	cmd.Dir = dir
+	cmd.Env = g.listEnv()
+	g.recordListCommand(cmd)
+	// stderr is captured (not written to os.Stderr) and reported in the error
 	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err() // output of a killed go list is incomplete
+	}
 
 	// Parse the output even if the command failed
 	packages := strings.Fields(string(out))
@@ -339,103 +1021,290 @@ func (g *GoDepFind) listPackages(path string) ([]string, error) {
 
 	// Only return error if we couldn't list any packages
 	if err != nil {
-		return nil, err
+		return nil, goToolError(program, args, err)
 	}
 
 	return packages, nil
 }
 
-// getPackages imports and returns a build.Package for each listed package
+// SetMaxParallelism bounds the number of concurrent workers (and go tool
+// subprocesses) used while building the cache, so godepfind doesn't
+// oversubscribe CPU on shared machines: the go tool runs with GOMAXPROCS=n,
+// which bounds the default go list -json loader, and SetPerPackageImport
+// imports with at most n workers. n <= 0 restores the default of
+// runtime.GOMAXPROCS(0).
+func (g *GoDepFind) SetMaxParallelism(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	g.maxParallelism = n
+}
+
+// parallelism returns the configured maximum number of concurrent workers
+func (g *GoDepFind) parallelism() int {
+	if g.maxParallelism > 0 {
+		return g.maxParallelism
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// getPackages imports and returns a build.Package for each listed package.
+// Directories are imported concurrently by a bounded pool of workers.
 func (g *GoDepFind) getPackages(paths []string) (map[string]*build.Package, error) {
-	packages := make(map[string]*build.Package)
-	for _, path := range paths {
-		var pkg *build.Package
-		var err error
-
-		// For module paths like "testproject/appAserver", we need to convert them to relative directory paths
-		// First, try to determine if this is a local module path
-		if strings.Contains(path, "/") {
-			// Extract the relative path from the module path
-			// For "testproject/appAserver", we want just "appAserver"
-			parts := strings.Split(path, "/")
-			if len(parts) >= 2 {
-				// Try to construct the relative path from the module root
-				relativePath := strings.Join(parts[1:], "/")
-				fullPath := filepath.Join(g.rootDir, relativePath)
-
-				// Check if this directory exists
-				if _, err := os.Stat(fullPath); err == nil {
-					pkg, err = build.ImportDir(fullPath, 0)
-					if err == nil {
-						packages[path] = pkg
-						continue
-					}
-				}
-			}
-		}
+	return g.getPackagesWithWorkers(context.Background(), paths, g.parallelism())
+}
 
-		// Fallback: try ImportDir with the full path as relative
-		fullPath := filepath.Join(g.rootDir, path)
-		if _, err := os.Stat(fullPath); err == nil {
-			pkg, err = build.ImportDir(fullPath, 0)
-			if err == nil {
-				packages[path] = pkg
-				continue
+// getPackagesWithWorkers imports the listed packages using at most workers
+// goroutines. The result is identical to importing them sequentially and, on
+// failure, the error of the first failing path (in input order) is returned.
+// Once ctx is cancelled no further package is imported and ctx.Err() is
+// returned.
+func (g *GoDepFind) getPackagesWithWorkers(ctx context.Context, paths []string, workers int) (map[string]*build.Package, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	importer := g.importer
+	if importer == nil {
+		importer = g.importPackage
+	}
+
+	results := make([]*build.Package, len(paths))
+	errs := make([]error, len(paths))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = importer(paths[i])
 			}
+		}()
+	}
+feed:
+	for i := range paths {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
 		}
+	}
+	close(jobs)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 
-		// Last resort: try build.Import (for standard library packages)
-		pkg, err = build.Import(path, g.rootDir, 0)
-		if err != nil {
-			return nil, err
+	packages := make(map[string]*build.Package, len(paths))
+	for i, path := range paths {
+		// A listed directory without buildable Go files for this context
+		// (e.g. only files for other build tags) is skipped, not fatal
+		var noGo *build.NoGoError
+		if errors.As(errs[i], &noGo) {
+			continue
+		}
+		if errs[i] != nil {
+			return nil, errs[i]
 		}
-		packages[path] = pkg
+		if g.ignoredPackage(results[i]) {
+			continue
+		}
+		packages[path] = results[i]
 	}
 	return packages, nil
 }
 
-// imports returns true if path imports any of the packages in "any", transitively
-func (g *GoDepFind) imports(path string, packages map[string]*build.Package, any map[string]bool) bool {
-	if any[path] {
-		return true
-	}
-	pkg, ok := packages[path]
-	if !ok || pkg == nil {
-		return false
-	}
+// importPackage resolves a listed package path to its directory, following
+// the module mode resolution strategy, and imports it
+func (g *GoDepFind) importPackage(path string) (*build.Package, error) {
+	ctx := g.buildContext()
 
-	// Check test imports if enabled
-	if g.testImports {
-		for _, imp := range pkg.TestImports {
-			if any[imp] {
-				return true
+	if dir, ok := g.currentResolution().importPathDir(path); ok {
+		if _, err := os.Stat(dir); err == nil {
+			pkg, err := ctx.ImportDir(g.rootRelativeDir(dir), 0)
+			if err == nil {
+				return pkg, nil
+			}
+			var noGo *build.NoGoError
+			if errors.As(err, &noGo) {
+				return nil, err // the directory is the package, it just has no files to build
 			}
 		}
-		for _, imp := range pkg.XTestImports {
+	}
+
+	// Last resort: try build.Import (for standard library packages)
+	return ctx.Import(path, g.rootDir, 0)
+}
+
+// imports returns true if path imports any of the packages in "any",
+// transitively. The walk uses an explicit stack and visits each package once,
+// so deep chains don't grow the goroutine stack and shared subtrees (diamonds)
+// aren't traversed again; import cycles terminate. On a match, path and the
+// packages leading from it to the target are added to any, so later calls
+// stop at them. unreachable memoizes the negative results: packages known not
+// to import any target are skipped, and every package visited by a walk
+// finding no target is added to it. Pass the same map (or nil) for calls
+// sharing the same packages and targets, so each package is walked once.
+func (g *GoDepFind) imports(path string, packages map[string]*build.Package, any, unreachable map[string]bool) bool {
+	// hit reports whether pkgPath is a target or, with test imports, imports
+	// one from its tests
+	hit := func(pkgPath string) bool {
+		if any[pkgPath] {
+			return true
+		}
+		pkg := packages[pkgPath]
+		if pkg == nil {
+			return false
+		}
+		for _, imp := range g.enabledTestImports(pkg) {
 			if any[imp] {
 				return true
 			}
 		}
+		return false
+	}
+	if hit(path) {
+		return true
+	}
+	if unreachable[path] {
+		return false
 	}
 
-	// Check regular imports
-	for _, imp := range pkg.Imports {
-		if g.imports(imp, packages, any) {
-			any[path] = true
+	type frame struct {
+		path string
+		next int // index of the next regular import to visit
+	}
+	visited := map[string]bool{path: true}
+	stack := []frame{{path: path}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		pkg := packages[top.path]
+		if pkg == nil || top.next >= len(pkg.Imports) {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		imp := pkg.Imports[top.next]
+		top.next++
+		if visited[imp] || unreachable[imp] {
+			continue
+		}
+		visited[imp] = true
+		if hit(imp) {
+			// Every package on the stack imports the target through imp
+			for _, f := range stack {
+				any[f.path] = true
+			}
 			return true
 		}
+		stack = append(stack, frame{path: imp})
+	}
+	// Everything reachable from path was visited without a match. (After a
+	// match nothing is recorded: a package left early may reach the target
+	// through one still on the stack.)
+	if unreachable != nil {
+		for visitedPath := range visited {
+			unreachable[visitedPath] = true
+		}
 	}
 	return false
 }
 
+// importedTargets is imports reporting which targets path is or imports,
+// sorted. Results are memoized per package in memo, which also guards
+// against import cycles.
+func (g *GoDepFind) importedTargets(path string, packages map[string]*build.Package, targets map[string]bool, memo map[string][]string) []string {
+	if found, done := memo[path]; done {
+		return found
+	}
+	memo[path] = nil // in progress
+
+	matched := make(map[string]bool)
+	if targets[path] {
+		matched[path] = true
+	}
+	if pkg := packages[path]; pkg != nil {
+		// Check test imports if enabled
+		for _, imp := range g.enabledTestImports(pkg) {
+			if targets[imp] {
+				matched[imp] = true
+			}
+		}
+		// Check regular imports
+		for _, imp := range pkg.Imports {
+			for _, target := range g.importedTargets(imp, packages, targets, memo) {
+				matched[target] = true
+			}
+		}
+	}
+
+	var found []string
+	for target := range matched {
+		found = append(found, target)
+	}
+	sort.Strings(found)
+	memo[path] = found
+	return found
+}
+
 // FindReverseDeps finds packages in sourcePath that import any of the targetPaths
 func (g *GoDepFind) FindReverseDeps(sourcePath string, targetPaths []string) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	packages, targets, err := g.reverseDepsInputs(sourcePath, targetPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find packages that import targets, computing each package's
+	// reachability once across the sources
+	var result []string
+	unreachable := make(map[string]bool)
+	for path := range packages {
+		if g.imports(path, packages, targets, unreachable) {
+			result = append(result, path)
+		}
+	}
+
+	return result, nil
+}
+
+// FindReverseDepsDetailed is FindReverseDeps reporting, for each package in
+// sourcePath that imports any of the targetPaths, the sorted targets it
+// imports (directly or transitively), i.e. which change forces its rebuild
+func (g *GoDepFind) FindReverseDepsDetailed(sourcePath string, targetPaths []string) (map[string][]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	packages, targets, err := g.reverseDepsInputs(sourcePath, targetPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]string)
+	memo := make(map[string][]string)
+	for path := range packages {
+		if found := g.importedTargets(path, packages, targets, memo); len(found) > 0 {
+			result[path] = append([]string{}, found...)
+		}
+	}
+	return result, nil
+}
+
+// reverseDepsInputs lists the target packages matched by targetPaths and
+// imports the packages matched by sourcePath
+func (g *GoDepFind) reverseDepsInputs(sourcePath string, targetPaths []string) (map[string]*build.Package, map[string]bool, error) {
 	// Build target map
 	targets := make(map[string]bool)
 	for _, targetPath := range targetPaths {
 		packages, err := g.listPackages(targetPath)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		for _, path := range packages {
 			targets[path] = true
@@ -445,23 +1314,14 @@ func (g *GoDepFind) FindReverseDeps(sourcePath string, targetPaths []string) ([]
 	// Get source packages
 	paths, err := g.listPackages(sourcePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	packages, err := g.getPackages(paths)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	// Find packages that import targets
-	var result []string
-	for path := range packages {
-		if g.imports(path, packages, targets) {
-			result = append(result, path)
-		}
-	}
-
-	return result, nil
+	return packages, targets, nil
 }
 
 // GoFileComesFromMain finds which main packages depend on the given file (cached version)
@@ -469,12 +1329,14 @@ func (g *GoDepFind) FindReverseDeps(sourcePath string, targetPaths []string) ([]
 // Returns: slice of main package paths that depend on this file
 func (g *GoDepFind) GoFileComesFromMain(fileName string) ([]string, error) {
 	// Ensure cache is initialized
-	if err := g.ensureCacheInitialized(); err != nil {
+	unlock, err := g.lockQuery()
+	if err != nil {
 		return nil, err
 	}
+	defer unlock()
 
 	// Find packages containing the file using new cache structure
-	candidatePackages := g.fileToPackages[fileName]
+	candidatePackages := g.fileToPackages[g.pathKey(fileName)]
 	if len(candidatePackages) == 0 {
 		return []string{}, nil // File not found in any package
 	}
@@ -503,61 +1365,50 @@ func (g *GoDepFind) isMainPackage(pkgPath string) bool {
 	return false
 }
 
-// matchesHandlerFile determines whether a main package path corresponds to the
-// handler file provided by the watcher. The logic is intentionally simple and
-// path-based: it checks whether the handler's directory matches the package
-// directory (using the package cache when available) or if the package name
-// matches the handler directory basename.
+// matchesHandlerFile reports whether a main package is the one built from the
+// handler file: the package's directory is the handler file's directory.
+// Packages in the cache are compared by their directory on disk, exactly, so
+// "test/pwa" and "pwa" never match each other. Packages unknown to the cache
+// fall back to comparing the import path with the handler directory on whole
+// path elements (package "testproject/test/pwa" matches handler directory
+// "test/pwa" or "pwa", but "testproject/myserver" doesn't match "server").
 func (g *GoDepFind) matchesHandlerFile(mainPkg, handlerFile string) bool {
 	if handlerFile == "" || mainPkg == "" {
 		return false
 	}
 
-	// Normalize handler directory relative to rootDir when possible
-	handlerDir := filepath.Dir(handlerFile)
-	if filepath.IsAbs(handlerFile) {
-		// Convert to relative from rootDir to compare with package paths
-		if rel, err := filepath.Rel(g.rootDir, handlerFile); err == nil {
-			handlerDir = filepath.Dir(rel)
-		}
-	}
-	handlerDir = filepath.ToSlash(handlerDir)
-
-	// 1) Quick base-name match: package base == handler directory base
-	if filepath.Base(mainPkg) == filepath.Base(handlerDir) {
-		return true
+	// Handler directory relative to its root
+	handlerAbsPath, err := filepath.Abs(g.rootPath(filepath.Clean(handlerFile)))
+	if err != nil {
+		return false
 	}
-
-	// 2) Suffix match: package path ends with handlerDir (covers cases like
-	//    "testproject/test/pwa" vs handlerDir "test/pwa" or "pwa")
-	if handlerDir != "." && handlerDir != "" {
-		if strings.HasSuffix(filepath.ToSlash(mainPkg), handlerDir) {
-			return true
-		}
+	handlerRoot, handlerRel, inside := g.containingRoot(handlerAbsPath)
+	if !inside {
+		return false
 	}
+	handlerDir := filepath.ToSlash(filepath.Dir(handlerRel))
 
-	// 3) Fall back to packageCache lookup (if available) to compare actual
-	// package directory on disk with handlerDir.
+	// 1) Exact comparison with the package directory on disk
 	if pkg, ok := g.packageCache[mainPkg]; ok && pkg != nil {
-		if relPkgDir, err := filepath.Rel(g.rootDir, pkg.Dir); err == nil {
-			relPkgDir = filepath.ToSlash(relPkgDir)
-			if relPkgDir == handlerDir || strings.HasSuffix(filepath.ToSlash(mainPkg), relPkgDir) {
-				return true
-			}
+		pkgDir, err := filepath.Abs(pkg.Dir)
+		if err != nil {
+			return false
 		}
+		pkgRoot, relPkgDir, inside := g.containingRoot(pkgDir)
+		return inside && pkgRoot == handlerRoot && filepath.ToSlash(relPkgDir) == handlerDir
 	}
 
-	return false
+	// 2) Suffix match on whole path elements for packages not in the cache
+	if handlerDir == "." || handlerDir == "" {
+		return false
+	}
+	mainPkg = filepath.ToSlash(mainPkg)
+	return mainPkg == handlerDir || strings.HasSuffix(mainPkg, "/"+handlerDir)
 }
 
 // findMainPackages finds all packages with main function
 func (g *GoDepFind) findMainPackages() ([]string, error) {
-	allPaths, err := g.listPackages("./...")
-	if err != nil {
-		return nil, err
-	}
-
-	packages, err := g.getPackages(allPaths)
+	packages, err := g.listedPackages("./...")
 	if err != nil {
 		return nil, err
 	}
@@ -574,12 +1425,7 @@ func (g *GoDepFind) findMainPackages() ([]string, error) {
 
 // findPackageContainingFile finds which package contains the given file
 func (g *GoDepFind) findPackageContainingFile(fileName string) (string, error) {
-	allPaths, err := g.listPackages("./...")
-	if err != nil {
-		return "", err
-	}
-
-	packages, err := g.getPackages(allPaths)
+	packages, err := g.listedPackages("./...")
 	if err != nil {
 		return "", err
 	}
@@ -591,17 +1437,10 @@ func (g *GoDepFind) findPackageContainingFile(fileName string) (string, error) {
 				return path, nil
 			}
 		}
-		// Check TestGoFiles if testImports is enabled
-		if g.testImports {
-			for _, file := range pkg.TestGoFiles {
-				if filepath.Base(file) == fileName {
-					return path, nil
-				}
-			}
-			for _, file := range pkg.XTestGoFiles {
-				if filepath.Base(file) == fileName {
-					return path, nil
-				}
+		// Check the test files whose imports are enabled
+		for _, file := range g.enabledTestGoFiles(pkg) {
+			if filepath.Base(file) == fileName {
+				return path, nil
 			}
 		}
 	}
@@ -611,14 +1450,16 @@ func (g *GoDepFind) findPackageContainingFile(fileName string) (string, error) {
 
 // findPackageContainingFileByPath finds which package contains the given file path.
 // It first tries the cached package info (packageCache) and falls back to
-// scanning packages if cache is not available.
+// scanning packages if cache is not available. Paths are compared after
+// resolving symlinks, so a file reached through a symlinked directory still
+// matches the package listed under the real one (and vice versa).
 func (g *GoDepFind) findPackageContainingFileByPath(filePath string) (string, error) {
 	// Ensure cache is initialized
 	if err := g.ensureCacheInitialized(); err != nil {
 		return "", err
 	}
 
-	absPath, err := filepath.Abs(filePath)
+	absPath, err := canonicalPath(filePath)
 	if err != nil {
 		return "", err
 	}
@@ -629,56 +1470,17 @@ func (g *GoDepFind) findPackageContainingFileByPath(filePath string) (string, er
 			if pkg == nil {
 				continue
 			}
-			for _, file := range pkg.GoFiles {
-				candidate := file
-				if !filepath.IsAbs(candidate) {
-					candidate = filepath.Join(pkg.Dir, file)
-				}
-				candAbs, err := filepath.Abs(candidate)
-				if err != nil {
-					continue
-				}
-				if candAbs == absPath {
-					return pkgPath, nil
-				}
+			if packageListsFile(pkg, pkg.GoFiles, absPath) {
+				return pkgPath, nil
 			}
-			if g.testImports {
-				for _, file := range pkg.TestGoFiles {
-					candidate := file
-					if !filepath.IsAbs(candidate) {
-						candidate = filepath.Join(pkg.Dir, file)
-					}
-					candAbs, err := filepath.Abs(candidate)
-					if err != nil {
-						continue
-					}
-					if candAbs == absPath {
-						return pkgPath, nil
-					}
-				}
-				for _, file := range pkg.XTestGoFiles {
-					candidate := file
-					if !filepath.IsAbs(candidate) {
-						candidate = filepath.Join(pkg.Dir, file)
-					}
-					candAbs, err := filepath.Abs(candidate)
-					if err != nil {
-						continue
-					}
-					if candAbs == absPath {
-						return pkgPath, nil
-					}
-				}
+			if packageListsFile(pkg, g.enabledTestGoFiles(pkg), absPath) {
+				return pkgPath, nil
 			}
 		}
 	}
 
 	// Fallback: scan all packages
-	allPaths, err := g.listPackages("./...")
-	if err != nil {
-		return "", err
-	}
-	packages, err := g.getPackages(allPaths)
+	packages, err := g.listedPackages("./...")
 	if err != nil {
 		return "", err
 	}
@@ -686,20 +1488,41 @@ func (g *GoDepFind) findPackageContainingFileByPath(filePath string) (string, er
 		if pkg == nil {
 			continue
 		}
-		for _, file := range pkg.GoFiles {
-			candidate := file
-			if !filepath.IsAbs(candidate) {
-				candidate = filepath.Join(pkg.Dir, file)
-			}
-			candAbs, err := filepath.Abs(candidate)
-			if err != nil {
-				continue
-			}
-			if candAbs == absPath {
-				return path, nil
-			}
+		if packageListsFile(pkg, pkg.GoFiles, absPath) {
+			return path, nil
+		}
+		if packageListsFile(pkg, g.enabledTestGoFiles(pkg), absPath) {
+			return path, nil
 		}
 	}
 
 	return "", nil
 }
+
+// packageListsFile reports whether one of the files of pkg resolves to the
+// canonical path absPath
+func packageListsFile(pkg *build.Package, files []string, absPath string) bool {
+	for _, file := range files {
+		candidate := file
+		if !filepath.IsAbs(candidate) {
+			candidate = filepath.Join(pkg.Dir, file)
+		}
+		if candAbs, err := canonicalPath(candidate); err == nil && candAbs == absPath {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalPath returns the absolute form of a path with symlinks resolved.
+// Paths that do not exist (e.g. a file already removed) keep their absolute form.
+func canonicalPath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		return resolved, nil
+	}
+	return absPath, nil
+}
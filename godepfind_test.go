@@ -1,6 +1,8 @@
 package godepfind
 
 import (
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -72,3 +74,160 @@ func TestFindReverseDepsWithTests(t *testing.T) {
 		t.Logf("Package: %s", dep)
 	}
 }
+
+func TestFindReverseDepsDetailed(t *testing.T) {
+	g := New("testproject")
+
+	deps, err := g.FindReverseDepsDetailed("./...", []string{"testproject/modules/module1", "testproject/modules/module2"})
+	if err != nil {
+		t.Fatalf("FindReverseDepsDetailed failed: %v", err)
+	}
+
+	want := map[string][]string{
+		"testproject/appAserver": {"testproject/modules/module1", "testproject/modules/module2"},
+		"testproject/appBcmd":    {"testproject/modules/module1"},
+	}
+	for importer, targets := range want {
+		if !reflect.DeepEqual(deps[importer], targets) {
+			t.Errorf("%s: expected targets %v, got %v", importer, targets, deps[importer])
+		}
+	}
+	if _, found := deps["testproject/appCwasm"]; found {
+		t.Errorf("expected appCwasm not to import any target, got %v", deps["testproject/appCwasm"])
+	}
+
+	// Same importers as FindReverseDeps
+	plain, err := g.FindReverseDeps("./...", []string{"testproject/modules/module1", "testproject/modules/module2"})
+	if err != nil {
+		t.Fatalf("FindReverseDeps failed: %v", err)
+	}
+	if len(plain) != len(deps) {
+		t.Errorf("expected %d importers like FindReverseDeps (%v), got %v", len(plain), plain, deps)
+	}
+}
+
+func TestPackageForFile(t *testing.T) {
+	g := New("testproject")
+	module1, err := filepath.Abs(filepath.Join("testproject", "modules", "module1", "module1.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{module1, "testproject/modules/module1"},
+		{filepath.Join("modules", "module1", "module1.go"), "testproject/modules/module1"}, // relative to the root
+		{filepath.Join("appBcmd", "main.go"), "testproject/appBcmd"},
+		{filepath.Join("modules", "nowhere", "missing_file.go"), ""},
+	}
+	for _, tt := range tests {
+		pkg, err := g.PackageForFile(tt.file)
+		if err != nil {
+			t.Fatalf("PackageForFile(%s) failed: %v", tt.file, err)
+		}
+		if pkg != tt.want {
+			t.Errorf("PackageForFile(%s) = %q, want %q", tt.file, pkg, tt.want)
+		}
+	}
+
+	if _, err := g.PackageForFile(""); err == nil {
+		t.Error("expected an error for an empty path")
+	}
+}
+
+func TestSameBasenameFallbackIsDeterministic(t *testing.T) {
+	files := map[string]string{"go.mod": "module testmod\n\ngo 1.21\n"}
+	for _, name := range []string{"zeta", "alpha", "mid"} {
+		files[name+"/handler.go"] = "package " + name + "\n\nfunc Handle() {}\n"
+	}
+	root := writeTestModule(t, files)
+
+	finder := New(root)
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"testmod/alpha", "testmod/mid", "testmod/zeta"}
+	if got := finder.fileToPackages["handler.go"]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("fileToPackages[handler.go] = %v, want %v", got, want)
+	}
+
+	// Drop the exact mappings so the lookups fall back to the file name
+	for _, name := range []string{"zeta", "alpha", "mid"} {
+		delete(finder.filePathToPackage, filepath.Join(root, name, "handler.go"))
+	}
+	pkg, exact, err := finder.resolvePackageForFile(filepath.Join(root, "zeta", "handler.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg != "testmod/zeta" || exact {
+		t.Errorf("fallback for zeta/handler.go = %q (exact %v), want testmod/zeta by directory", pkg, exact)
+	}
+	pkg, _, err = finder.resolvePackageForFile(filepath.Join(root, "elsewhere", "handler.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg != "testmod/alpha" {
+		t.Errorf("fallback outside every package = %q, want the smallest testmod/alpha", pkg)
+	}
+}
+
+func TestPackagesContainingFile(t *testing.T) {
+	g := New("testproject")
+
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"main.go", []string{"testproject/appAserver", "testproject/appBcmd", "testproject/appCwasm"}},
+		{"module1.go", []string{"testproject/modules/module1"}},
+		{"missing_file.go", []string{}},
+	}
+	for _, tt := range tests {
+		pkgs, err := g.PackagesContainingFile(tt.file)
+		if err != nil {
+			t.Fatalf("PackagesContainingFile(%s) failed: %v", tt.file, err)
+		}
+		if !reflect.DeepEqual(pkgs, tt.want) {
+			t.Errorf("PackagesContainingFile(%s) = %v, want %v", tt.file, pkgs, tt.want)
+		}
+	}
+
+	// The result is a copy: editing it leaves the cache intact
+	pkgs, _ := g.PackagesContainingFile("main.go")
+	pkgs[0] = "changed"
+	if again, _ := g.PackagesContainingFile("main.go"); again[0] != "testproject/appAserver" {
+		t.Errorf("expected the cache to be unaffected by edits to the result, got %v", again)
+	}
+}
+
+func TestPackageDir(t *testing.T) {
+	g := New("testproject")
+	module1, err := filepath.Abs(filepath.Join("testproject", "modules", "module1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir, err := g.PackageDir("testproject/modules/module1"); err != nil || dir != module1 {
+		t.Errorf("PackageDir(module1) = %q, %v; want %s", dir, err, module1)
+	}
+
+	// Packages outside the cache are resolved by go list
+	listsBefore := g.listCount
+	dir, err := g.PackageDir("fmt")
+	if err != nil {
+		t.Fatalf("PackageDir(fmt) failed: %v", err)
+	}
+	if !filepath.IsAbs(dir) || filepath.Base(dir) != "fmt" {
+		t.Errorf("PackageDir(fmt) = %q, want the absolute fmt directory", dir)
+	}
+	if g.listCount != listsBefore+1 {
+		t.Errorf("expected a single go list run for fmt, got %d", g.listCount-listsBefore)
+	}
+
+	for _, pkgPath := range []string{"testproject/modules/missing", ""} {
+		if dir, err := g.PackageDir(pkgPath); err == nil {
+			t.Errorf("PackageDir(%q) = %q, expected an error", pkgPath, dir)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package godepfind
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// listJSONFields are the fields requested from go list: asking only for the
+// fields used lets go list skip computing the others
+const listJSONFields = "Dir,ImportPath,Name,Doc,Goroot,GoFiles,CgoFiles,IgnoredGoFiles," +
+	"TestGoFiles,XTestGoFiles,EmbedPatterns,EmbedFiles,Imports,TestImports,XTestImports,Error"
+
+// listedPackage is one object of the stream written by `go list -json`
+type listedPackage struct {
+	ListPackage
+	Error *struct{ Err string }
+}
+
+// SetPerPackageImport selects the loader building the cache. By default a
+// single `go list -e -json` run provides every package with its files and
+// imports. Enabled, the import paths are listed with go list and each package
+// is imported from its directory with go/build, by at most SetMaxParallelism
+// workers: the loader the JSON listing falls back to when go list cannot load
+// every package. Changing it invalidates the cache.
+func (g *GoDepFind) SetPerPackageImport(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.perPackageImport = enabled
+	g.cachedModule = false
+}
+
+// listAndImportPackages lists the packages of the project and imports them.
+// A single `go list -e -json` run provides every package with its files and
+// imports, so no directory is imported again. When the JSON listing is not
+// usable (or SetPerPackageImport is enabled), the import paths are listed and
+// each package is imported from its directory instead. It returns the listed
+// paths, including packages dropped by the ignore patterns, and the files
+// embedded by each package per the JSON listing (nil when imported).
+func (g *GoDepFind) listAndImportPackages(ctx context.Context) ([]string, map[string]*build.Package, map[string][]string, error) {
+	if !g.perPackageImport {
+		if paths, packages, embeds, err := g.listPackagesJSON(ctx); err == nil {
+			return paths, packages, embeds, nil
+		} else if ctx.Err() != nil {
+			return nil, nil, nil, ctx.Err()
+		}
+	}
+
+	paths, err := g.listPackagesContext(ctx, "./...")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	packages, err := g.getPackagesWithWorkers(ctx, paths, g.parallelism())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return paths, packages, nil, nil
+}
+
+// listPackagesJSON runs `go list -e -json` for the whole project (once per
+// root of NewMulti) and converts the listed packages. A package go list could
+// not load (e.g. an import cycle or a syntax error) fails the JSON listing, so
+// the caller falls back to the plain listing and reports its errors the usual
+// way. The files go list resolved the //go:embed patterns to are returned
+// apart, keyed by package, since build.Package has no field for them.
+func (g *GoDepFind) listPackagesJSON(ctx context.Context) ([]string, map[string]*build.Package, map[string][]string, error) {
+	dirs := []string{g.rootDir}
+	if res := g.currentResolution(); res.peers {
+		dirs = dirs[:0]
+		for _, module := range res.workspace {
+			dirs = append(dirs, module.dir)
+		}
+	}
+
+	var paths []string
+	packages := make(map[string]*build.Package)
+	embeds := make(map[string][]string)
+	for _, dir := range dirs {
+		listed, err := g.listJSONIn(ctx, dir)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, lp := range listed {
+			if lp.Error != nil {
+				return nil, nil, nil, fmt.Errorf("go list: %s: %s", lp.ImportPath, lp.Error.Err)
+			}
+			if _, seen := packages[lp.ImportPath]; seen {
+				continue
+			}
+			paths = append(paths, lp.ImportPath)
+			if pkg := g.buildPackage(lp); !g.ignoredPackage(pkg) {
+				packages[lp.ImportPath] = pkg
+				if len(lp.EmbedPatterns) > 0 {
+					embeds[lp.ImportPath] = lp.EmbedFiles
+				}
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil, nil, errors.New("go list -json listed no packages")
+	}
+	return paths, packages, embeds, nil
+}
+
+// listPackageDir runs go list -f {{.Dir}} for a single package from rootDir
+// and returns its directory
+func (g *GoDepFind) listPackageDir(pkgPath string) (string, error) {
+	program, args := g.listCommand(pkgPath, "-f", "{{.Dir}}")
+	cmd := exec.Command(program, args...)
+	cmd.Dir = g.rootDir
+	cmd.Env = g.listEnv()
+	g.recordListCommand(cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", goToolError(program, args, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// listJSONIn runs go list -e -json for the project in the directory dir and
+// decodes its output
+func (g *GoDepFind) listJSONIn(ctx context.Context, dir string) ([]listedPackage, error) {
+	program, args := g.listCommand("./...", "-e", "-json="+listJSONFields)
+	cmd := exec.CommandContext(ctx, program, args...)
+	cmd.Dir = dir
+	cmd.Env = g.listEnv()
+	g.recordListCommand(cmd)
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, goToolError(program, args, err)
+	}
+
+	var listed []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var lp listedPackage
+		if err := dec.Decode(&lp); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		listed = append(listed, lp)
+	}
+	return listed, nil
+}
+
+// buildPackage converts a listed package to the build.Package importing its
+// directory would return. The directory is kept relative to a relative root,
+// like imported packages.
+func (g *GoDepFind) buildPackage(lp listedPackage) *build.Package {
+	return &build.Package{
+		Dir:            g.rootRelativeDir(lp.Dir),
+		Name:           lp.Name,
+		ImportPath:     lp.ImportPath,
+		Doc:            lp.Doc,
+		Goroot:         lp.Goroot,
+		GoFiles:        lp.GoFiles,
+		CgoFiles:       lp.CgoFiles,
+		IgnoredGoFiles: lp.IgnoredGoFiles,
+		TestGoFiles:    lp.TestGoFiles,
+		XTestGoFiles:   lp.XTestGoFiles,
+		EmbedPatterns:  lp.EmbedPatterns,
+		Imports:        lp.Imports,
+		TestImports:    lp.TestImports,
+		XTestImports:   lp.XTestImports,
+	}
+}
@@ -0,0 +1,121 @@
+package godepfind
+
+import (
+	"context"
+	"errors"
+	"go/build"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRebuildUsesSingleJSONListing(t *testing.T) {
+	files := chainModuleFiles()
+	files["mid/mid_test.go"] = "package mid\n\nimport \"testing\"\n\nfunc TestDo(t *testing.T) { Do() }\n"
+	root := writeTestModule(t, files)
+	finder := New(root)
+
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatal(err)
+	}
+	if finder.listCount != 1 {
+		t.Errorf("expected a single go list run, got %d", finder.listCount)
+	}
+	if _, args := finder.LastListCommand(); !strings.HasPrefix(args[len(args)-2], "-json=") {
+		t.Errorf("expected a go list -json run, got %v", args)
+	}
+
+	// The listed packages match the ones imported from their directories
+	paths, err := finder.listPackages("./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := finder.getPackagesWithWorkers(context.Background(), paths, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != len(finder.packageCache) {
+		t.Fatalf("listed %d packages, imported %d", len(finder.packageCache), len(imported))
+	}
+	for pkgPath, want := range imported {
+		got := finder.packageCache[pkgPath]
+		if got == nil {
+			t.Errorf("%s missing from the JSON listing", pkgPath)
+			continue
+		}
+		sameList := func(a, b []string) bool { return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b) }
+		if got.Dir != want.Dir || got.Name != want.Name || !sameList(got.GoFiles, want.GoFiles) ||
+			!sameList(got.Imports, want.Imports) || !sameList(got.TestGoFiles, want.TestGoFiles) ||
+			!sameList(got.TestImports, want.TestImports) || !sameList(got.IgnoredGoFiles, want.IgnoredGoFiles) {
+			t.Errorf("%s: listed %+v, imported %+v", pkgPath, got, want)
+		}
+	}
+}
+
+func TestLoaderChoice(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	finder.SetMaxParallelism(2)
+	var mu sync.Mutex
+	imported := 0
+	finder.importer = func(path string) (*build.Package, error) {
+		mu.Lock()
+		imported++
+		mu.Unlock()
+		return finder.importPackage(path)
+	}
+
+	// The default loader is the JSON listing, whatever the importer
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatal(err)
+	}
+	if imported != 0 || finder.listCount != 1 || len(finder.packageCache) != 4 {
+		t.Errorf("expected one go list -json run and no imports, got %d runs, %d imports, %d packages", finder.listCount, imported, len(finder.packageCache))
+	}
+	// go list itself is bounded by SetMaxParallelism
+	if env := finder.listEnv(); env[len(env)-1] != "GOMAXPROCS=2" {
+		t.Errorf("expected go list to run with GOMAXPROCS=2, got %v", env[len(env)-1])
+	}
+
+	finder.SetPerPackageImport(true)
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatal(err)
+	}
+	if imported != 4 || len(finder.packageCache) != 4 {
+		t.Errorf("expected the 4 packages to be imported one by one, got %d imports, %d packages", imported, len(finder.packageCache))
+	}
+}
+
+func TestJSONListingMatchesPlainListingOnErrors(t *testing.T) {
+	// A missing import only affects the importers' dependencies: both listings
+	// keep the packages
+	root := writeTestModule(t, map[string]string{
+		"go.mod":      "module testmod\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":  "package lib\n\nimport \"testmod/missing\"\n\nfunc Do() { missing.Do() }\n",
+	})
+	paths, _, _, err := New(root).listPackagesJSON(context.Background())
+	if err != nil {
+		t.Fatalf("listPackagesJSON failed: %v", err)
+	}
+	if want := []string{"testmod/app", "testmod/lib"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("listed %v, want %v", paths, want)
+	}
+
+	// A package go list cannot load sends the rebuild to the plain listing,
+	// which reports the failure
+	root = writeTestModule(t, map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"a/a.go": "package a\n\nimport \"testmod/b\"\n\nfunc A() { b.B() }\n",
+		"b/b.go": "package b\n\nimport \"testmod/a\"\n\nfunc B() { a.A() }\n",
+	})
+	finder := New(root)
+	if _, _, _, err := finder.listPackagesJSON(context.Background()); err == nil {
+		t.Fatal("expected the JSON listing to reject an import cycle")
+	}
+	var listErr *GoListError
+	if _, _, _, err := finder.listAndImportPackages(context.Background()); !errors.As(err, &listErr) {
+		t.Errorf("expected the plain listing's *GoListError, got %v", err)
+	}
+}
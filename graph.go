@@ -0,0 +1,279 @@
+package godepfind
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrStopIteration can be returned by a ForEachEdge callback to stop the
+// iteration early without ForEachEdge reporting an error
+var ErrStopIteration = errors.New("stop iteration")
+
+// ForEachEdge calls fn for every edge (importer → imported) of the cached
+// dependency graph in a deterministic order: sorted by importer, then by
+// imported package. Iteration stops at the first error returned by fn, which
+// is returned unless it is ErrStopIteration.
+func (g *GoDepFind) ForEachEdge(fn func(from, to string) error) error {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return err
+	}
+	froms := make([]string, 0, len(g.dependencyGraph))
+	edges := make(map[string][]string, len(g.dependencyGraph))
+	for from, tos := range g.dependencyGraph {
+		froms = append(froms, from)
+		edges[from] = append([]string{}, tos...)
+	}
+	// fn runs without the lock so it may call back into the finder
+	unlock()
+	sort.Strings(froms)
+
+	for _, from := range froms {
+		tos := edges[from]
+		sort.Strings(tos)
+		for _, to := range tos {
+			if err := fn(from, to); err != nil {
+				if errors.Is(err, ErrStopIteration) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DependencyGraph returns a copy of the cached dependency graph, mapping each
+// package to the import paths it imports. The copy shares no slices with the
+// cache, so later file events don't modify it.
+func (g *GoDepFind) DependencyGraph() (map[string][]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	graph := make(map[string][]string, len(g.dependencyGraph))
+	for pkgPath, deps := range g.dependencyGraph {
+		graph[pkgPath] = append([]string{}, deps...)
+	}
+	return graph, nil
+}
+
+// ImportsOf returns the sorted packages pkgPath imports directly from its
+// production files, as recorded in the cached graph. It returns an error for
+// a package that isn't in the graph.
+func (g *GoDepFind) ImportsOf(pkgPath string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	deps, ok := g.dependencyGraph[pkgPath]
+	if !ok {
+		return nil, fmt.Errorf("package not found in dependency graph: %s", pkgPath)
+	}
+	result := append([]string{}, deps...)
+	sort.Strings(result)
+	return result, nil
+}
+
+// ImportsOfWithTests is like ImportsOf but also includes the imports of the
+// package's _test files (in-package and external, as enabled by
+// SetTestImports and SetExternalTestImports), minus the package itself.
+// Requires test imports to be enabled.
+func (g *GoDepFind) ImportsOfWithTests(pkgPath string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if !g.tracksTestImports() {
+		return nil, fmt.Errorf("test imports are disabled: enable SetTestImports to track test edges")
+	}
+
+	pkg := g.packageCache[pkgPath]
+	if pkg == nil {
+		return nil, fmt.Errorf("package not found in module: %s", pkgPath)
+	}
+	result := []string{}
+	for _, imports := range [][]string{pkg.Imports, g.enabledTestImports(pkg)} {
+		for _, imp := range imports {
+			if imp != pkgPath && !contains(result, imp) {
+				result = append(result, imp)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// DirectImporters returns the sorted packages importing pkgPath directly,
+// without following their own importers. A package nobody imports yields an
+// empty slice.
+func (g *GoDepFind) DirectImporters(pkgPath string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result := []string{}
+	for _, importer := range g.reverseDeps[pkgPath] {
+		if !contains(result, importer) {
+			result = append(result, importer)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// ReverseDependencies returns the sorted packages importing pkgPath directly
+// or transitively, i.e. everything to rebuild when pkgPath changes. A package
+// nobody imports yields an empty slice.
+func (g *GoDepFind) ReverseDependencies(pkgPath string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	visited := map[string]bool{pkgPath: true}
+	stack := []string{pkgPath}
+	result := []string{}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, importer := range g.reverseDeps[current] {
+			if visited[importer] {
+				continue
+			}
+			visited[importer] = true
+			result = append(result, importer)
+			stack = append(stack, importer)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// FindImportersOfModule returns the sorted packages of the tree depending,
+// directly or transitively, on a package of modulePath: the module path
+// itself or any import path below it (e.g. "github.com/foo/bar/baz" for
+// "github.com/foo/bar"), to gauge the blast radius of upgrading the module.
+// Packages of external modules (loaded with SetIncludeExternalModules) are
+// traversed but not reported.
+func (g *GoDepFind) FindImportersOfModule(modulePath string) ([]string, error) {
+	modulePath = strings.TrimSuffix(modulePath, "/")
+	if modulePath == "" {
+		return nil, fmt.Errorf("modulePath cannot be empty")
+	}
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	inModule := func(pkgPath string) bool {
+		return pkgPath == modulePath || strings.HasPrefix(pkgPath, modulePath+"/")
+	}
+	visited := make(map[string]bool)
+	var stack []string
+	for imported := range g.reverseDeps {
+		if inModule(imported) {
+			visited[imported] = true
+			stack = append(stack, imported)
+		}
+	}
+	result := []string{}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, importer := range g.reverseDeps[current] {
+			if visited[importer] {
+				continue
+			}
+			visited[importer] = true
+			stack = append(stack, importer)
+			if g.packageCache[importer] != nil && !inModule(importer) {
+				result = append(result, importer)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// DetectCycles returns the import cycles of the cached dependency graph, each
+// as the packages along the cycle in import order (a imports b, ..., the last
+// imports a), starting from its smallest package path. Valid Go has none, but
+// they show up while go list reports a tree broken mid-edit. Cycles are
+// sorted by first package; a graph without cycles yields an empty slice.
+func (g *GoDepFind) DetectCycles() ([][]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	pkgPaths := make([]string, 0, len(g.dependencyGraph))
+	for pkgPath := range g.dependencyGraph {
+		pkgPaths = append(pkgPaths, pkgPath)
+	}
+	sort.Strings(pkgPaths)
+
+	// Depth-first search: an edge back to a package on the current path
+	// closes a cycle; visited packages are never walked twice
+	visited := make(map[string]bool)
+	onPath := make(map[string]int) // package -> index in path
+	var path []string
+	seen := make(map[string]bool)
+	cycles := [][]string{}
+
+	var walk func(pkgPath string)
+	walk = func(pkgPath string) {
+		visited[pkgPath] = true
+		onPath[pkgPath] = len(path)
+		path = append(path, pkgPath)
+		for _, dep := range g.dependencyGraph[pkgPath] {
+			if start, inPath := onPath[dep]; inPath {
+				cycle := rotateToSmallest(path[start:])
+				if key := strings.Join(cycle, " "); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+				continue
+			}
+			if !visited[dep] {
+				walk(dep)
+			}
+		}
+		path = path[:len(path)-1]
+		delete(onPath, pkgPath)
+	}
+	for _, pkgPath := range pkgPaths {
+		if !visited[pkgPath] {
+			walk(pkgPath)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i], " ") < strings.Join(cycles[j], " ")
+	})
+	return cycles, nil
+}
+
+// rotateToSmallest returns a copy of cycle starting at its smallest element,
+// keeping the order of the elements
+func rotateToSmallest(cycle []string) []string {
+	smallest := 0
+	for i, pkgPath := range cycle {
+		if pkgPath < cycle[smallest] {
+			smallest = i
+		}
+	}
+	return append(append([]string{}, cycle[smallest:]...), cycle[:smallest]...)
+}
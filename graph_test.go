@@ -0,0 +1,244 @@
+package godepfind
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestForEachEdge(t *testing.T) {
+	finder := New("testproject")
+
+	var edges []string
+	err := finder.ForEachEdge(func(from, to string) error {
+		edges = append(edges, from+" -> "+to)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachEdge failed: %v", err)
+	}
+
+	expected := []string{
+		"testproject/appAserver -> testproject/modules/module1",
+		"testproject/appAserver -> testproject/modules/module2",
+		"testproject/appBcmd -> testproject/modules/module1",
+		"testproject/appCwasm -> testproject/modules/module3",
+	}
+	if !reflect.DeepEqual(edges, expected) {
+		t.Errorf("expected edges %v, got %v", expected, edges)
+	}
+}
+
+func TestForEachEdgeEarlyTermination(t *testing.T) {
+	finder := New("testproject")
+
+	visited := 0
+	err := finder.ForEachEdge(func(from, to string) error {
+		visited++
+		return ErrStopIteration
+	})
+	if err != nil {
+		t.Errorf("expected ErrStopIteration to end iteration cleanly, got %v", err)
+	}
+	if visited != 1 {
+		t.Errorf("expected 1 edge visited, got %d", visited)
+	}
+
+	failure := errors.New("consumer failed")
+	visited = 0
+	err = finder.ForEachEdge(func(from, to string) error {
+		visited++
+		if visited == 2 {
+			return failure
+		}
+		return nil
+	})
+	if !errors.Is(err, failure) || visited != 2 {
+		t.Errorf("expected consumer error after 2 edges, got %v after %d", err, visited)
+	}
+}
+
+func TestDependencyGraphIsDeepCopy(t *testing.T) {
+	finder := New("testproject")
+
+	graph, err := finder.DependencyGraph()
+	if err != nil {
+		t.Fatalf("DependencyGraph failed: %v", err)
+	}
+	expected := []string{"testproject/modules/module1", "testproject/modules/module2"}
+	if !reflect.DeepEqual(graph["testproject/appAserver"], expected) {
+		t.Errorf("expected appAserver imports %v, got %v", expected, graph["testproject/appAserver"])
+	}
+
+	// Mutating the copy must not affect the cache
+	graph["testproject/appAserver"][0] = "mutated"
+	delete(graph, "testproject/appBcmd")
+
+	again, err := finder.DependencyGraph()
+	if err != nil {
+		t.Fatalf("DependencyGraph failed: %v", err)
+	}
+	if !reflect.DeepEqual(again["testproject/appAserver"], expected) {
+		t.Errorf("cache was mutated through the returned copy: %v", again["testproject/appAserver"])
+	}
+	if _, exists := again["testproject/appBcmd"]; !exists {
+		t.Error("expected appBcmd to remain in the cached graph")
+	}
+}
+
+func TestReverseDependenciesTransitive(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+
+	importers, err := finder.ReverseDependencies("testmod/internal/leaf")
+	if err != nil {
+		t.Fatalf("ReverseDependencies failed: %v", err)
+	}
+	expected := []string{"testmod/app", "testmod/mid"}
+	if !reflect.DeepEqual(importers, expected) {
+		t.Errorf("expected %v, got %v", expected, importers)
+	}
+
+	importers, err = finder.ReverseDependencies("testmod/app")
+	if err != nil {
+		t.Fatalf("ReverseDependencies failed: %v", err)
+	}
+	if len(importers) != 0 {
+		t.Errorf("expected no importers of a main package, got %v", importers)
+	}
+}
+
+func TestDirectImporters(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+
+	importers, err := finder.DirectImporters("testmod/internal/leaf")
+	if err != nil {
+		t.Fatalf("DirectImporters failed: %v", err)
+	}
+	expected := []string{"testmod/mid"}
+	if !reflect.DeepEqual(importers, expected) {
+		t.Errorf("expected %v, got %v", expected, importers)
+	}
+
+	importers, err = finder.DirectImporters("testmod/app")
+	if err != nil {
+		t.Fatalf("DirectImporters failed: %v", err)
+	}
+	if importers == nil || len(importers) != 0 {
+		t.Errorf("expected an empty non-nil slice, got %#v", importers)
+	}
+}
+
+func TestImportsOf(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":        "module testmod\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport (\n\t\"fmt\"\n\n\t\"testmod/lib\"\n)\n\nfunc main() { fmt.Println(lib.Do()) }\n",
+		"lib/lib.go":    "package lib\n\nimport \"strings\"\n\nfunc Do() string { return strings.ToUpper(\"x\") }\n",
+		"lib/x_test.go": "package lib_test\n\nimport (\n\t\"testing\"\n\n\t\"testmod/lib\"\n\t\"testmod/util\"\n)\n\nfunc TestX(t *testing.T) { lib.Do(); util.Help() }\n",
+		"util/util.go":  "package util\n\nfunc Help() {}\n",
+	})
+	finder := New(root)
+
+	imports, err := finder.ImportsOf("testmod/app")
+	if err != nil {
+		t.Fatalf("ImportsOf failed: %v", err)
+	}
+	if want := []string{"fmt", "testmod/lib"}; !reflect.DeepEqual(imports, want) {
+		t.Errorf("ImportsOf(app) = %v, want %v", imports, want)
+	}
+	if _, err := finder.ImportsOf("testmod/missing"); err == nil {
+		t.Error("expected error for a package outside the graph")
+	}
+	if _, err := finder.ImportsOfWithTests("testmod/lib"); err == nil {
+		t.Error("expected error when test imports are disabled")
+	}
+
+	finder.SetTestImports(true)
+	imports, err = finder.ImportsOfWithTests("testmod/lib")
+	if err != nil {
+		t.Fatalf("ImportsOfWithTests failed: %v", err)
+	}
+	if want := []string{"strings", "testing", "testmod/util"}; !reflect.DeepEqual(imports, want) {
+		t.Errorf("ImportsOfWithTests(lib) = %v, want %v", imports, want)
+	}
+	imports, err = finder.ImportsOf("testmod/lib")
+	if err != nil {
+		t.Fatalf("ImportsOf failed: %v", err)
+	}
+	if want := []string{"strings"}; !reflect.DeepEqual(imports, want) {
+		t.Errorf("ImportsOf(lib) with test imports = %v, want %v", imports, want)
+	}
+}
+
+func TestDetectCycles(t *testing.T) {
+	// go list refuses modules with import cycles, so inject a graph as left
+	// by a tree broken mid-edit
+	finder := New(t.TempDir())
+	finder.dependencyGraph = map[string][]string{
+		"testmod/top":  {"testmod/b", "testmod/x"},
+		"testmod/b":    {"testmod/c", "fmt"},
+		"testmod/c":    {"testmod/a"},
+		"testmod/a":    {"testmod/b"},
+		"testmod/x":    {"testmod/x"}, // self import
+		"testmod/leaf": {},
+	}
+	finder.cachedModule = true
+
+	cycles, err := finder.DetectCycles()
+	if err != nil {
+		t.Fatalf("DetectCycles failed: %v", err)
+	}
+	want := [][]string{
+		{"testmod/a", "testmod/b", "testmod/c"},
+		{"testmod/x"},
+	}
+	if !reflect.DeepEqual(cycles, want) {
+		t.Errorf("DetectCycles = %v, want %v", cycles, want)
+	}
+
+	// A valid tree has none
+	finder = New("testproject")
+	cycles, err = finder.DetectCycles()
+	if err != nil {
+		t.Fatalf("DetectCycles failed: %v", err)
+	}
+	if cycles == nil || len(cycles) != 0 {
+		t.Errorf("expected an empty slice, got %#v", cycles)
+	}
+}
+
+func TestFindImportersOfModule(t *testing.T) {
+	files := externalModuleFiles()
+	files["app/internal/wrap/wrap.go"] = "package wrap\n\nimport \"example.com/extlib/inner\"\n\nconst Name = inner.Name\n"
+	files["app/cmd/tool/main.go"] = "package main\n\nimport _ \"testmod/internal/wrap\"\n\nfunc main() {}\n"
+	root := writeTestModule(t, files)
+
+	for _, external := range []bool{false, true} {
+		finder := New(filepath.Join(root, "app"))
+		finder.SetIncludeExternalModules(external)
+
+		importers, err := finder.FindImportersOfModule("example.com/extlib")
+		if err != nil {
+			t.Fatalf("FindImportersOfModule failed: %v", err)
+		}
+		want := []string{"testmod/cmd/server", "testmod/cmd/tool", "testmod/internal/wrap"}
+		if !reflect.DeepEqual(importers, want) {
+			t.Errorf("external=%v: expected %v, got %v", external, want, importers)
+		}
+
+		// Only whole path elements match
+		importers, err = finder.FindImportersOfModule("example.com/ext")
+		if err != nil {
+			t.Fatalf("FindImportersOfModule failed: %v", err)
+		}
+		if len(importers) != 0 {
+			t.Errorf("external=%v: expected no importers of a partial path, got %v", external, importers)
+		}
+	}
+
+	if _, err := New(filepath.Join(root, "app")).FindImportersOfModule(""); err == nil {
+		t.Error("expected an error for an empty module path")
+	}
+}
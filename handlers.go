@@ -0,0 +1,390 @@
+package godepfind
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetStrictHandlers enables or disables uniqueness enforcement in RegisterHandlers.
+// When enabled, registering two handlers that resolve to the same main package
+// is rejected with an error instead of silently causing duplicate rebuilds.
+func (g *GoDepFind) SetStrictHandlers(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.strictHandlers = enabled
+}
+
+// RegisterHandlers records the main files of the handlers that will route
+// events through this finder (e.g. "appAserver/main.go"), or the import paths
+// of their main packages. ThisFileIsMineAny evaluates the registered handlers
+// when called without any.
+//
+// In strict mode every handler must exist and resolve to a distinct main
+// package. Main files in the same directory that are excluded from the
+// default build by constraints (e.g. main.wasm.go next to main.server.go) are
+// treated as separate build variants and do not conflict.
+func (g *GoDepFind) RegisterHandlers(mainInputFileRelativePaths ...string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.strictHandlers {
+		g.handlers = append(g.handlers, mainInputFileRelativePaths...)
+		return nil
+	}
+
+	if err := g.ensureCacheInitialized(); err != nil {
+		return err
+	}
+
+	// Resolve already registered handlers first so conflicts across calls are detected
+	owners := make(map[string]string)
+	all := append(append([]string{}, g.handlers...), mainInputFileRelativePaths...)
+	for _, handlerFile := range all {
+		key, err := g.resolveHandlerMainPackage(handlerFile)
+		if err != nil {
+			return err
+		}
+		if other, exists := owners[key]; exists {
+			return fmt.Errorf("handlers %s and %s resolve to the same main package: %s", other, handlerFile, key)
+		}
+		owners[key] = handlerFile
+	}
+
+	g.handlers = all
+	return nil
+}
+
+// resolveHandlerMainPackage returns the identity of the binary built from a handler
+// main file: its package path, or the file itself when the file is a build
+// variant not included in the cached package. A handler identified by the
+// import path of its main package resolves to that path.
+func (g *GoDepFind) resolveHandlerMainPackage(mainInputFileRelativePath string) (string, error) {
+	if mainInputFileRelativePath == "" {
+		return "", fmt.Errorf("handler mainInputFileRelativePath cannot be empty")
+	}
+	if mainPkg, byImportPath, err := g.handlerMainImportPath(mainInputFileRelativePath); err != nil || byImportPath {
+		return mainPkg, err
+	}
+
+	handlerAbsPath := g.rootPath(mainInputFileRelativePath)
+	handlerAbsPath, err := filepath.Abs(handlerAbsPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve handler main file %s: %w", mainInputFileRelativePath, err)
+	}
+	if _, err := os.Stat(handlerAbsPath); err != nil {
+		return "", fmt.Errorf("handler main file does not exist: %s", mainInputFileRelativePath)
+	}
+
+	if pkg, exists := g.lookupFilePath(handlerAbsPath); exists {
+		return pkg, nil
+	}
+	return handlerAbsPath, nil
+}
+
+// ThisFileIsMineAny evaluates ThisFileIsMine for a set of handlers, given by
+// their mainInputFileRelativePath, at once, under a single lock over the same
+// cache, and returns the handlers owning the file in their input order (an
+// empty slice when none does). A file of a package imported by several mains
+// belongs to each of their handlers. Without handlers, the ones registered
+// with RegisterHandlers are evaluated. The first error stops the evaluation
+// and names the handler.
+func (g *GoDepFind) ThisFileIsMineAny(mainInputFileRelativePaths []string, fileAbsPath string, event FileEvent) ([]string, error) {
+	event, err := normalizeEvent(event)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
+	if len(mainInputFileRelativePaths) == 0 {
+		mainInputFileRelativePaths = g.handlers
+	}
+
+	matched := []string{}
+	for _, handler := range mainInputFileRelativePaths {
+		isMine, _, err := g.thisFileIsMine(handler, fileAbsPath, event)
+		if err != nil {
+			return nil, fmt.Errorf("handler %s: %w", handler, err)
+		}
+		if isMine {
+			matched = append(matched, handler)
+		}
+	}
+	return matched, nil
+}
+
+// ResolveOwner behaves like ThisFileIsMine, with the same arguments, but also
+// returns the main package that claimed the file: the package built from the
+// handler's main file, whose import path build variants of the main file
+// (e.g. main.wasm.go) share. The package is empty when the file is not
+// owned, or is owned through an asset directory.
+func (g *GoDepFind) ResolveOwner(mainInputFileRelativePath, fileAbsPath string, event FileEvent) (owned bool, mainPkg string, err error) {
+	event, err = normalizeEvent(event)
+	if err != nil {
+		return false, "", err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
+
+	decision := &OwnershipDecision{}
+	if err := g.decideOwnership(mainInputFileRelativePath, fileAbsPath, event, decision); err != nil {
+		return false, "", err
+	}
+	if !decision.Owned {
+		return false, "", nil
+	}
+	mainPkg = decision.HandlerPackage
+	if mainPkg == "" && decision.Case != CaseAssetRoot {
+		// A build variant excluded from the cached package (e.g. main.wasm.go)
+		// shares the import path of its directory
+		if err := g.ensureCacheInitialized(); err != nil {
+			return false, "", err
+		}
+		mainPkg = g.packageForDir(filepath.Dir(g.rootPath(mainInputFileRelativePath)))
+	}
+	return true, mainPkg, nil
+}
+
+// RegisterAssetRoot declares a directory of non-Go assets (templates, styles,
+// ...) owned by a handler. Any file under the directory, recursively, is routed
+// to the handler by ThisFileIsMine without Go import analysis.
+//
+// A handler without a main package can use the asset directory itself as its
+// mainInputFileRelativePath (e.g. RegisterAssetRoot("web/templates", "web/templates")).
+// Such asset-only handlers never own files outside their asset directories.
+func (g *GoDepFind) RegisterAssetRoot(mainInputFileRelativePath, assetDirRelativePath string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if mainInputFileRelativePath == "" {
+		return fmt.Errorf("handler mainInputFileRelativePath cannot be empty")
+	}
+	assetAbsPath := g.rootPath(assetDirRelativePath)
+	info, err := os.Stat(assetAbsPath)
+	if err != nil {
+		return fmt.Errorf("asset directory does not exist: %s", assetDirRelativePath)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("asset path is not a directory: %s", assetDirRelativePath)
+	}
+
+	relDir, inside := g.relativeToRoot(assetAbsPath)
+	if !inside {
+		return fmt.Errorf("asset directory is outside rootDir: %s", assetDirRelativePath)
+	}
+
+	key := filepath.Clean(mainInputFileRelativePath)
+	if !contains(g.assetRoots[key], relDir) {
+		g.assetRoots[key] = append(g.assetRoots[key], relDir)
+	}
+	return nil
+}
+
+// matchesAssetRoot reports whether relativeFilePath is under one of the asset
+// directories of the handler. isAssetHandler is true when the handler is an
+// asset-only handler (its identifier is one of its asset directories).
+func (g *GoDepFind) matchesAssetRoot(mainInputFileRelativePath, relativeFilePath string) (owned, isAssetHandler bool) {
+	key := filepath.Clean(mainInputFileRelativePath)
+	roots := g.assetRoots[key]
+	isAssetHandler = contains(roots, key)
+	for _, root := range roots {
+		if root == "." || relativeFilePath == root || strings.HasPrefix(relativeFilePath, root+string(filepath.Separator)) {
+			return true, isAssetHandler
+		}
+	}
+	return false, isAssetHandler
+}
+
+// SetHandlerBuildTags sets the build tags a handler's main is compiled with,
+// on top of the tags set with SetBuildTags. This supports several binaries
+// selected by tags in one directory (e.g. pwa/main.server.go built without
+// tags and pwa/main.wasm.go built with "wasm"): each handler only owns the
+// files of its directory included in its own build, plus the packages that
+// build imports. An empty, non-nil tags slice selects the untagged build (the
+// server main above); nil removes the handler's tag set.
+func (g *GoDepFind) SetHandlerBuildTags(mainInputFileRelativePath string, tags []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := filepath.Clean(mainInputFileRelativePath)
+	if tags == nil {
+		delete(g.handlerTags, key)
+		return
+	}
+	g.handlerTags[key] = append([]string{}, tags...)
+}
+
+// buildTarget is the GOOS/GOARCH pair a handler's main is built for
+type buildTarget struct {
+	goos, goarch string
+}
+
+// SetHandlerBuildTarget sets the GOOS/GOARCH the handler's main is built for,
+// overriding SetTargetPlatform for that handler only. Its main directory and
+// every module package it imports are then evaluated under that platform's
+// build constraints, so a wasm main next to a server main (e.g.
+// pwa/main.wasm.go built for "js", "wasm") owns the js/wasm-only files and the
+// server main doesn't. Empty goos and goarch remove the handler's target.
+func (g *GoDepFind) SetHandlerBuildTarget(mainInputFileRelativePath, goos, goarch string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := filepath.Clean(mainInputFileRelativePath)
+	if goos == "" && goarch == "" {
+		delete(g.handlerTargets, key)
+		return
+	}
+	g.handlerTargets[key] = buildTarget{goos: goos, goarch: goarch}
+}
+
+// hasHandlerBuild reports whether the handler has its own build tags or target
+func (g *GoDepFind) hasHandlerBuild(mainInputFileRelativePath string) bool {
+	key := filepath.Clean(mainInputFileRelativePath)
+	_, tagged := g.handlerTags[key]
+	_, targeted := g.handlerTargets[key]
+	return tagged || targeted
+}
+
+// handlerContext returns the build context of the handler's main: the global
+// context plus the handler's build tags and target
+func (g *GoDepFind) handlerContext(mainInputFileRelativePath string) *build.Context {
+	key := filepath.Clean(mainInputFileRelativePath)
+	ctx := g.buildContext()
+	ctx.BuildTags = append(ctx.BuildTags, g.handlerTags[key]...)
+	if target, targeted := g.handlerTargets[key]; targeted {
+		if target.goos != "" {
+			ctx.GOOS = target.goos
+		}
+		if target.goarch != "" {
+			ctx.GOARCH = target.goarch
+		}
+	}
+	return ctx
+}
+
+// handlerBuild imports the handler's main directory under the handler's build context
+func (g *GoDepFind) handlerBuild(mainInputFileRelativePath string) (*build.Package, error) {
+	handlerAbsPath := g.rootPath(mainInputFileRelativePath)
+	pkg, err := g.handlerContext(mainInputFileRelativePath).ImportDir(filepath.Dir(handlerAbsPath), 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load handler main %s with its build tags: %w", mainInputFileRelativePath, err)
+	}
+	return pkg, nil
+}
+
+// handlerClosure imports, under the handler's build context, the module
+// packages the handler's main imports transitively, keyed by absolute
+// directory. Packages without files for that build are skipped.
+func (g *GoDepFind) handlerClosure(ctx *build.Context, main *build.Package) map[string]*build.Package {
+	closure := make(map[string]*build.Package)
+	seen := make(map[string]bool)
+	queue := append([]string{}, main.Imports...)
+	res := g.currentResolution()
+	for len(queue) > 0 {
+		imp := queue[0]
+		queue = queue[1:]
+		if seen[imp] {
+			continue
+		}
+		seen[imp] = true
+		dir, inModule := res.importPathDir(imp)
+		if !inModule {
+			continue // standard library or external module
+		}
+		pkg, err := ctx.ImportDir(dir, 0)
+		if err != nil {
+			continue
+		}
+		if absDir, err := filepath.Abs(dir); err == nil {
+			closure[absDir] = pkg
+		}
+		queue = append(queue, pkg.Imports...)
+	}
+	return closure
+}
+
+// checkTaggedOwnership decides ownership for a handler with its own build
+// tags or target. A file is owned when it is compiled into the handler's own
+// build: a file of the main directory included by the handler's build, or a
+// file of a module package that build imports, directly or transitively,
+// included under the same constraints.
+func (g *GoDepFind) checkTaggedOwnership(mainInputFileRelativePath, fileAbsPath string) (bool, Confidence, error) {
+	main, err := g.handlerBuild(mainInputFileRelativePath)
+	if err != nil {
+		return false, ConfidenceHigh, err
+	}
+
+	fileDir, fileName := filepath.Dir(fileAbsPath), filepath.Base(fileAbsPath)
+	pkg := main
+	if mainDir, err := filepath.Abs(main.Dir); err != nil || fileDir != mainDir {
+		pkg = g.handlerClosure(g.handlerContext(mainInputFileRelativePath), main)[fileDir]
+		if pkg == nil {
+			return false, ConfidenceHigh, nil
+		}
+	}
+
+	owned := contains(pkg.GoFiles, fileName) || contains(pkg.CgoFiles, fileName) ||
+		contains(g.enabledTestGoFiles(pkg), fileName)
+	return owned, ConfidenceHigh, nil
+}
+
+// handlerMainImportPath reports whether a handler identifier is the import
+// path of a main package (e.g. "testproject/appAserver") rather than a main
+// file path. Identifiers naming an existing file are always file paths.
+func (g *GoDepFind) handlerMainImportPath(mainInputFileRelativePath string) (string, bool, error) {
+	if filepath.IsAbs(mainInputFileRelativePath) || filepath.Ext(mainInputFileRelativePath) == ".go" {
+		return "", false, nil
+	}
+	if _, err := os.Stat(g.rootPath(mainInputFileRelativePath)); err == nil {
+		return "", false, nil
+	}
+	if err := g.ensureCacheInitialized(); err != nil {
+		return "", false, err
+	}
+	importPath := filepath.ToSlash(mainInputFileRelativePath)
+	if g.isMainPackage(importPath) {
+		return importPath, true, nil
+	}
+	return "", false, nil
+}
+
+// checkImportPathOwnership decides ownership for a handler identified by the
+// import path of its main package: the file must belong to that exact main
+// package or to a package it imports. Writes to the main package's files
+// rescan its dependencies, like writes to a handler main file.
+func (g *GoDepFind) checkImportPathOwnership(mainPkg, fileAbsPath string, event FileEvent, pending bool, d *OwnershipDecision) error {
+	targetPkg, exact, err := g.resolveDecisionPackage(fileAbsPath, pending, d)
+	if err != nil {
+		return err
+	}
+	if targetPkg == "" {
+		d.decide(false, ConfidenceHigh, CaseNoPackage)
+		return nil
+	}
+	d.TargetPackage = targetPkg
+	if g.isExcludedByConstraints(fileAbsPath) {
+		d.decide(false, ConfidenceHigh, CaseBuildConstraints)
+		return nil
+	}
+
+	confidence := ConfidenceHigh
+	if !exact {
+		confidence = ConfidenceLow
+		d.fallback("filename-fallback")
+	}
+	if targetPkg == mainPkg {
+		if event != "" {
+			if err := g.updateCacheForFileWithContext(fileAbsPath, event, fileAbsPath); err != nil {
+				return fmt.Errorf("cache update failed: %w", err)
+			}
+		}
+		d.decide(true, confidence, CaseImportPathHandler)
+		return nil
+	}
+	if g.excludedFromOwnership(targetPkg) {
+		d.decide(false, confidence, CaseExcludedFromOwnership)
+		return nil
+	}
+	d.decide(g.cachedMainImportsPackage(mainPkg, targetPkg), confidence, CaseImportPathHandler)
+	return nil
+}
@@ -0,0 +1,347 @@
+package godepfind
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisterHandlersStrictRejectsConflicts(t *testing.T) {
+	finder := New("testproject")
+	finder.SetStrictHandlers(true)
+
+	// Two spellings of the same main file resolve to the same main package
+	err := finder.RegisterHandlers("appAserver/main.go", "./appAserver/main.go")
+	if err == nil {
+		t.Fatal("expected strict registration to reject handlers resolving to the same main package")
+	}
+	if !strings.Contains(err.Error(), "same main package") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	if len(finder.handlers) != 0 {
+		t.Errorf("expected no handlers registered after rejection, got %v", finder.handlers)
+	}
+}
+
+func TestRegisterHandlersStrictAcrossCalls(t *testing.T) {
+	finder := New("testproject")
+	finder.SetStrictHandlers(true)
+
+	if err := finder.RegisterHandlers("appAserver/main.go", "appBcmd/main.go"); err != nil {
+		t.Fatalf("expected distinct handlers to register, got: %v", err)
+	}
+	if err := finder.RegisterHandlers("appBcmd/main.go"); err == nil {
+		t.Fatal("expected conflict with previously registered handler")
+	}
+	if err := finder.RegisterHandlers("appCwasm/main.go"); err != nil {
+		t.Fatalf("expected third distinct handler to register, got: %v", err)
+	}
+	if len(finder.handlers) != 3 {
+		t.Errorf("expected 3 registered handlers, got %v", finder.handlers)
+	}
+}
+
+func TestRegisterHandlersStrictImportPaths(t *testing.T) {
+	finder := New("testproject")
+	finder.SetStrictHandlers(true)
+
+	if err := finder.RegisterHandlers("testproject/appAserver", "appBcmd/main.go"); err != nil {
+		t.Fatalf("expected an import-path handler to register, got: %v", err)
+	}
+	if err := finder.RegisterHandlers("appAserver/main.go"); err == nil {
+		t.Fatal("expected the main file of a handler registered by import path to conflict")
+	}
+}
+
+func TestThisFileIsMineAnyRegisteredHandlers(t *testing.T) {
+	finder := New("testproject")
+	if err := finder.RegisterHandlers("appAserver/main.go", "appBcmd/main.go", "appCwasm/main.go"); err != nil {
+		t.Fatalf("RegisterHandlers failed: %v", err)
+	}
+
+	matched, err := finder.ThisFileIsMineAny(nil, "modules/module1/module1.go", EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMineAny failed: %v", err)
+	}
+	if want := []string{"appAserver/main.go", "appBcmd/main.go"}; !reflect.DeepEqual(matched, want) {
+		t.Errorf("expected the registered handlers importing module1 %v, got %v", want, matched)
+	}
+}
+
+func TestRegisterHandlersNonStrictAcceptsConflicts(t *testing.T) {
+	finder := New("testproject")
+
+	if err := finder.RegisterHandlers("appAserver/main.go", "./appAserver/main.go"); err != nil {
+		t.Fatalf("expected non-strict registration to accept duplicates, got: %v", err)
+	}
+}
+
+func TestAssetOnlyHandlerOwnsTemplates(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":                          "module testmod\n\ngo 1.21\n",
+		"app/main.go":                     "package main\n\nfunc main() {}\n",
+		"web/templates/index.html":        "<html></html>\n",
+		"web/templates/partials/nav.html": "<nav></nav>\n",
+		"web/static/app.css":              "body {}\n",
+	})
+
+	finder := New(root)
+	if err := finder.RegisterAssetRoot("web/templates", "web/templates"); err != nil {
+		t.Fatalf("RegisterAssetRoot failed: %v", err)
+	}
+
+	tests := []struct {
+		handler  string
+		file     string
+		expected bool
+	}{
+		{"web/templates", "web/templates/index.html", true},
+		{"web/templates", "web/templates/partials/nav.html", true},
+		{"web/templates", "web/static/app.css", false},
+		{"web/templates", "app/main.go", false},
+		{"app/main.go", "web/templates/index.html", false},
+	}
+	for _, tt := range tests {
+		isMine, err := finder.ThisFileIsMine(tt.handler, filepath.Join(root, tt.file), "write")
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s) failed: %v", tt.handler, tt.file, err)
+		}
+		if isMine != tt.expected {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", tt.handler, tt.file, isMine, tt.expected)
+		}
+	}
+}
+
+func TestMainHandlerWithAssetRoot(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":                  "module testmod\n\ngo 1.21\n",
+		"app/main.go":             "package main\n\nfunc main() {}\n",
+		"app/templates/home.html": "<html></html>\n",
+	})
+
+	finder := New(root)
+	if err := finder.RegisterAssetRoot("app/main.go", "app/templates"); err != nil {
+		t.Fatalf("RegisterAssetRoot failed: %v", err)
+	}
+	if err := finder.RegisterAssetRoot("app/main.go", "missing"); err == nil {
+		t.Error("expected error for missing asset directory")
+	}
+
+	isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "app", "templates", "home.html"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !isMine {
+		t.Error("expected main handler to own a template under its asset root")
+	}
+
+	// Go ownership still applies to the main handler
+	isMine, err = finder.ThisFileIsMine("app/main.go", filepath.Join(root, "app", "main.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !isMine {
+		t.Error("expected main handler to still own its main file")
+	}
+}
+
+// pwaModuleFiles mirrors a pwa/ directory building a server and a wasm binary
+// from tag-selected main files
+func pwaModuleFiles() map[string]string {
+	return map[string]string{
+		"go.mod":               "module testmod\n\ngo 1.21\n",
+		"pwa/main.server.go":   "//go:build !wasm\n\npackage main\n\nimport \"testmod/backend\"\n\nfunc main() { backend.Serve(routes()) }\n",
+		"pwa/main.wasm.go":     "//go:build wasm\n\npackage main\n\nimport \"testmod/frontend\"\n\nfunc main() { frontend.Mount(routes()) }\n",
+		"pwa/routes.go":        "package main\n\nfunc routes() []string { return []string{\"/\"} }\n",
+		"backend/backend.go":   "package backend\n\nfunc Serve(routes []string) {}\n",
+		"frontend/frontend.go": "package frontend\n\nfunc Mount(routes []string) {}\n",
+	}
+}
+
+func TestHandlerBuildTagsSelectMainVariant(t *testing.T) {
+	root := writeTestModule(t, pwaModuleFiles())
+	finder := New(root)
+	finder.SetHandlerBuildTags("pwa/main.server.go", []string{})
+	finder.SetHandlerBuildTags("pwa/main.wasm.go", []string{"wasm"})
+
+	tests := []struct {
+		handler  string
+		file     string
+		expected bool
+	}{
+		{"pwa/main.server.go", "pwa/main.server.go", true},
+		{"pwa/main.server.go", "pwa/main.wasm.go", false},
+		{"pwa/main.server.go", "pwa/routes.go", true},
+		{"pwa/main.server.go", "backend/backend.go", true},
+		{"pwa/main.server.go", "frontend/frontend.go", false},
+		{"pwa/main.wasm.go", "pwa/main.wasm.go", true},
+		{"pwa/main.wasm.go", "pwa/main.server.go", false},
+		{"pwa/main.wasm.go", "pwa/routes.go", true},
+		{"pwa/main.wasm.go", "frontend/frontend.go", true},
+		{"pwa/main.wasm.go", "backend/backend.go", false},
+	}
+	for _, tt := range tests {
+		isMine, err := finder.ThisFileIsMine(tt.handler, filepath.Join(root, tt.file), "write")
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s) failed: %v", tt.handler, tt.file, err)
+		}
+		if isMine != tt.expected {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", tt.handler, tt.file, isMine, tt.expected)
+		}
+	}
+}
+
+func TestImportPathHandlerIdentifier(t *testing.T) {
+	finder := New("testproject")
+
+	tests := []struct {
+		handler  string
+		file     string
+		expected bool
+	}{
+		{"testproject/appAserver", "appAserver/main.go", true},
+		{"testproject/appAserver", "modules/module1/module1.go", true},
+		{"testproject/appAserver", "modules/module3/module3.go", false},
+		{"testproject/appAserver", "appBcmd/main.go", false},
+		{"testproject/appBcmd", "appBcmd/main.go", true},
+		{"testproject/appBcmd", "appAserver/main.go", false},
+		{"testproject/appBcmd", "modules/module1/module1.go", true},
+		{"testproject/appBcmd", "modules/module2/module2.go", false},
+	}
+	for _, tt := range tests {
+		isMine, err := finder.ThisFileIsMine(tt.handler, tt.file, "write")
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s) failed: %v", tt.handler, tt.file, err)
+		}
+		if isMine != tt.expected {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", tt.handler, tt.file, isMine, tt.expected)
+		}
+	}
+
+	// Import paths that are not main packages are not handler identifiers
+	if _, err := finder.ThisFileIsMine("testproject/modules/module1", "appAserver/main.go", "write"); err == nil {
+		t.Error("expected error for a handler identifier that is neither a file nor a main package")
+	}
+}
+
+func TestHandlerBuildTargetSelectsPlatformFiles(t *testing.T) {
+	files := pwaModuleFiles()
+	files["pwa/routes.go"] = "package main\n\nimport \"testmod/shared\"\n\nfunc routes() []string { return shared.Routes() }\n"
+	files["shared/shared.go"] = "package shared\n\nfunc Routes() []string { return []string{load()} }\n"
+	files["shared/storage_js.go"] = "package shared\n\nfunc load() string { return \"localStorage\" }\n"
+	files["shared/storage_other.go"] = "//go:build !js\n\npackage shared\n\nfunc load() string { return \"disk\" }\n"
+	files["frontend/dom.go"] = "//go:build js && wasm\n\npackage frontend\n\nfunc Render() {}\n"
+	root := writeTestModule(t, files)
+
+	finder := New(root)
+	finder.SetHandlerBuildTarget("pwa/main.server.go", "linux", "amd64")
+	finder.SetHandlerBuildTarget("pwa/main.wasm.go", "js", "wasm")
+
+	tests := []struct {
+		file   string
+		server bool
+		wasm   bool
+	}{
+		{"pwa/main.server.go", true, false},
+		{"pwa/main.wasm.go", false, true},
+		{"pwa/routes.go", true, true},
+		{"shared/shared.go", true, true},
+		{"shared/storage_js.go", false, true},
+		{"shared/storage_other.go", true, false},
+		{"backend/backend.go", true, false},
+		{"frontend/frontend.go", false, true},
+		{"frontend/dom.go", false, true},
+	}
+	for _, tt := range tests {
+		for handler, expected := range map[string]bool{"pwa/main.server.go": tt.server, "pwa/main.wasm.go": tt.wasm} {
+			isMine, err := finder.ThisFileIsMine(handler, filepath.Join(root, tt.file), "write")
+			if err != nil {
+				t.Fatalf("ThisFileIsMine(%s, %s) failed: %v", handler, tt.file, err)
+			}
+			if isMine != expected {
+				t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", handler, tt.file, isMine, expected)
+			}
+		}
+	}
+
+	// Removing the target falls back to the shared cache
+	finder.SetHandlerBuildTarget("pwa/main.wasm.go", "", "")
+	if finder.hasHandlerBuild("pwa/main.wasm.go") {
+		t.Error("expected the wasm handler target to be removed")
+	}
+}
+
+func TestThisFileIsMineAny(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":           "module testmod\n\ngo 1.21\n",
+		"models/models.go": "package models\n\ntype User struct{}\n",
+		"api/main.go":      "package main\n\nimport _ \"testmod/models\"\n\nfunc main() {}\n",
+		"worker/main.go":   "package main\n\nimport _ \"testmod/models\"\n\nfunc main() {}\n",
+		"admin/main.go":    "package main\n\nimport _ \"testmod/models\"\n\nfunc main() {}\n",
+		"tool/main.go":     "package main\n\nfunc main() {}\n",
+	})
+	finder := New(root)
+	handlers := []string{"tool/main.go", "api/main.go", "worker/main.go", "admin/main.go"}
+
+	matched, err := finder.ThisFileIsMineAny(handlers, filepath.Join(root, "models", "models.go"), EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMineAny failed: %v", err)
+	}
+	if len(matched) != 3 || matched[0] != handlers[1] || matched[1] != handlers[2] || matched[2] != handlers[3] {
+		t.Errorf("expected the api, worker and admin handlers in input order, got %v", matched)
+	}
+
+	matched, err = finder.ThisFileIsMineAny(handlers, filepath.Join(root, "tool", "main.go"), EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMineAny failed: %v", err)
+	}
+	if len(matched) != 1 || matched[0] != handlers[0] {
+		t.Errorf("expected only the tool handler to own its main file, got %v", matched)
+	}
+
+	// A handler whose main file is missing names itself in the error
+	_, err = finder.ThisFileIsMineAny([]string{"gone/main.go"}, filepath.Join(root, "models", "models.go"), EventWrite)
+	if err == nil || !strings.Contains(err.Error(), "gone/main.go") {
+		t.Errorf("expected an error naming the missing handler, got %v", err)
+	}
+	if _, err := finder.ThisFileIsMineAny(handlers, filepath.Join(root, "models", "models.go"), "bogus"); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("expected ErrUnknownEvent, got %v", err)
+	}
+}
+
+func TestResolveOwner(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	leaf := filepath.Join(root, "internal", "leaf", "leaf.go")
+
+	owned, mainPkg, err := finder.ResolveOwner("app/main.go", leaf, EventWrite)
+	if err != nil {
+		t.Fatalf("ResolveOwner failed: %v", err)
+	}
+	if !owned || mainPkg != "testmod/app" {
+		t.Errorf("expected leaf.go to be claimed by testmod/app, got %v, %q", owned, mainPkg)
+	}
+	owned, mainPkg, err = finder.ResolveOwner("other/main.go", leaf, EventWrite)
+	if err != nil {
+		t.Fatalf("ResolveOwner failed: %v", err)
+	}
+	if owned || mainPkg != "" {
+		t.Errorf("expected leaf.go not to be claimed through other, got %v, %q", owned, mainPkg)
+	}
+}
+
+func TestResolveOwnerBuildVariant(t *testing.T) {
+	root := writeTestModule(t, pwaModuleFiles())
+	finder := New(root)
+	finder.SetHandlerBuildTags("pwa/main.wasm.go", []string{"wasm"})
+
+	owned, mainPkg, err := finder.ResolveOwner("pwa/main.wasm.go", filepath.Join(root, "frontend", "frontend.go"), EventWrite)
+	if err != nil {
+		t.Fatalf("ResolveOwner failed: %v", err)
+	}
+	if !owned || mainPkg != "testmod/pwa" {
+		t.Errorf("expected frontend.go to be claimed by testmod/pwa, got %v, %q", owned, mainPkg)
+	}
+}
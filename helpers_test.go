@@ -0,0 +1,24 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestModule creates a temporary module from a map of relative file paths
+// to contents and returns its root directory
+func writeTestModule(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir %s: %v", rel, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+	return root
+}
@@ -0,0 +1,162 @@
+package godepfind
+
+import (
+	"fmt"
+	"go/build"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SetIgnorePatterns excludes packages from the analysis, e.g. vendored or
+// generated code that is never watched. Patterns are slash-separated globs
+// matched against paths relative to rootDir, where "**" matches any number of
+// directories: a package is ignored when its directory matches (e.g.
+// "vendor/**", "gen/**") or when every one of its Go files matches (e.g.
+// "**/*.pb.go"); matching files of other packages are not mapped to them.
+// Ignored packages never enter the cache, the dependency graph or the mains.
+// An invalid pattern is reported as an error. Changing the patterns
+// invalidates the cache.
+func (g *GoDepFind) SetIgnorePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if err := validateGlob(pattern); err != nil {
+			return fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ignorePatterns = append([]string{}, patterns...)
+	g.cachedModule = false
+	return nil
+}
+
+// ExcludeFromOwnership adds patterns of packages whose files never belong to
+// a handler through its imports, e.g. generated or vendored code whose edits
+// shouldn't trigger rebuilds. Unlike SetIgnorePatterns the packages stay in
+// the cache and the graph; only ownership routing skips them. Patterns are
+// the globs of SetIgnorePatterns, matched against a package's import path,
+// its import path relative to the module path (e.g. "internal/generated/**")
+// and its directory relative to rootDir. Excludes are checked once the file's
+// package is resolved and before walking the handler's imports, so a handler's
+// own main package is never excluded. An invalid pattern is reported as an
+// error and none of the patterns is added.
+func (g *GoDepFind) ExcludeFromOwnership(patterns ...string) error {
+	for _, pattern := range patterns {
+		if err := validateGlob(pattern); err != nil {
+			return fmt.Errorf("invalid ownership exclude pattern %q: %w", pattern, err)
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ownershipExcludes = append(g.ownershipExcludes, patterns...)
+	return nil
+}
+
+// excludedFromOwnership reports whether a package matches an
+// ExcludeFromOwnership pattern
+func (g *GoDepFind) excludedFromOwnership(pkgPath string) bool {
+	if len(g.ownershipExcludes) == 0 || pkgPath == "" {
+		return false
+	}
+	names := []string{pkgPath}
+	if modulePath := g.currentResolution().modulePath; modulePath != "" {
+		if rest, ok := strings.CutPrefix(pkgPath, modulePath+"/"); ok {
+			names = append(names, rest)
+		}
+	}
+	if pkg := g.packageCache[pkgPath]; pkg != nil {
+		if dir, err := filepath.Abs(pkg.Dir); err == nil {
+			if rel, inside := g.relativeToRoot(dir); inside {
+				names = append(names, filepath.ToSlash(rel))
+			}
+		}
+	}
+	for _, pattern := range g.ownershipExcludes {
+		for _, name := range names {
+			if matchGlob(pattern, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateGlob checks the elements of a slash-separated glob
+func validateGlob(pattern string) error {
+	for _, elem := range strings.Split(pattern, "/") {
+		if _, err := path.Match(elem, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ignoredPath reports whether an absolute path under rootDir matches an ignore pattern
+func (g *GoDepFind) ignoredPath(absPath string) bool {
+	if len(g.ignorePatterns) == 0 {
+		return false
+	}
+	rel, inside := g.relativeToRoot(absPath)
+	if !inside {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range g.ignorePatterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredPackage reports whether a package is excluded by the ignore
+// patterns: its directory matches, or all of its Go files do
+func (g *GoDepFind) ignoredPackage(pkg *build.Package) bool {
+	if len(g.ignorePatterns) == 0 || pkg == nil {
+		return false
+	}
+	dir, err := filepath.Abs(pkg.Dir)
+	if err != nil {
+		return false
+	}
+	if g.ignoredPath(dir) {
+		return true
+	}
+	if len(pkg.GoFiles) == 0 {
+		return false
+	}
+	for _, file := range pkg.GoFiles {
+		if !g.ignoredPath(filepath.Join(dir, file)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchGlob matches a slash-separated path against a glob pattern in which
+// "**" stands for zero or more path elements and other elements follow
+// path.Match
+func matchGlob(pattern, name string) bool {
+	return matchGlobElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchGlobElems(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,169 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "vendor/github.com/x/y", true},
+		{"vendor/**", "app/vendor", false},
+		{"**/*.pb.go", "api.pb.go", true},
+		{"**/*.pb.go", "api/v1/api.pb.go", true},
+		{"**/*.pb.go", "api/v1/api.go", false},
+		{"gen/*", "gen/proto", true},
+		{"gen/*", "gen/proto/v1", false},
+		{"**/testdata/**", "a/testdata/b", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.expected {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestIgnorePatternsSkipPackages(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":               "module testmod\n\ngo 1.21\n",
+		"app/main.go":          "package main\n\nimport (\n\t\"testmod/api\"\n\t\"testmod/third/lib\"\n)\n\nfunc main() { api.Call(); lib.Do() }\n",
+		"api/api.go":           "package api\n\nimport \"testmod/api/pb\"\n\nfunc Call() { pb.Message() }\n",
+		"api/api_grpc.pb.go":   "package api\n",
+		"api/pb/message.pb.go": "package pb\n\nfunc Message() {}\n",
+		"third/lib/lib.go":     "package lib\n\nfunc Do() {}\n",
+		"third/tool/main.go":   "package main\n\nfunc main() {}\n",
+	})
+	finder := New(root)
+	if err := finder.SetIgnorePatterns([]string{"third/**", "**/*.pb.go"}); err != nil {
+		t.Fatalf("SetIgnorePatterns failed: %v", err)
+	}
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+
+	var cached []string
+	for pkgPath := range finder.packageCache {
+		cached = append(cached, pkgPath)
+	}
+	sort.Strings(cached)
+	if want := []string{"testmod/api", "testmod/app"}; !reflect.DeepEqual(cached, want) {
+		t.Errorf("expected cached packages %v, got %v", want, cached)
+	}
+	if !reflect.DeepEqual(finder.mainPackages, []string{"testmod/app"}) {
+		t.Errorf("expected only testmod/app as main, got %v", finder.mainPackages)
+	}
+	for from, deps := range finder.dependencyGraph {
+		for _, dep := range deps {
+			if dep == "testmod/third/lib" || dep == "testmod/api/pb" {
+				t.Errorf("unexpected edge to ignored package: %s -> %s", from, dep)
+			}
+		}
+	}
+
+	// Generated files of a kept package are not routed
+	isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "api", "api_grpc.pb.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if isMine {
+		t.Error("expected an ignored generated file not to be owned")
+	}
+	isMine, err = finder.ThisFileIsMine("app/main.go", filepath.Join(root, "api", "api.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !isMine {
+		t.Error("expected the kept package file to be owned")
+	}
+
+	if err := finder.SetIgnorePatterns([]string{"gen/[x"}); err == nil {
+		t.Error("expected error for an invalid pattern")
+	}
+}
+
+func TestExcludeFromOwnership(t *testing.T) {
+	files := chainModuleFiles()
+	files["app/util.go"] = "package main\n"
+	root := writeTestModule(t, files)
+	finder := New(root)
+	if err := finder.ExcludeFromOwnership("internal/leaf", "app"); err != nil {
+		t.Fatalf("ExcludeFromOwnership failed: %v", err)
+	}
+	leaf := filepath.Join(root, "internal", "leaf", "leaf.go")
+
+	for _, handler := range []string{"app/main.go", "testmod/app"} {
+		if mine, err := finder.ThisFileIsMine(handler, leaf, EventWrite); err != nil || mine {
+			t.Errorf("%s: expected excluded leaf.go not to be owned, got %v, %v", handler, mine, err)
+		}
+		if mine, err := finder.ThisFileIsMine(handler, filepath.Join(root, "mid", "mid.go"), EventWrite); err != nil || !mine {
+			t.Errorf("%s: expected mid.go to stay owned, got %v, %v", handler, mine, err)
+		}
+		// The handler's own main package is never excluded
+		if mine, err := finder.ThisFileIsMine(handler, filepath.Join(root, "app", "util.go"), EventWrite); err != nil || !mine {
+			t.Errorf("%s: expected util.go of the handler's main to stay owned, got %v, %v", handler, mine, err)
+		}
+	}
+
+	decision, err := finder.TraceOwnership("app/main.go", leaf)
+	if err != nil {
+		t.Fatalf("TraceOwnership failed: %v", err)
+	}
+	if decision.Case != CaseExcludedFromOwnership || decision.TargetPackage != "testmod/internal/leaf" {
+		t.Errorf("expected leaf.go to be decided as excluded, got %+v", decision)
+	}
+	// The package stays in the graph
+	if mains, err := finder.FindAffectedMains([]string{leaf}); err != nil || len(mains[leaf]) != 1 {
+		t.Errorf("expected leaf.go to still affect app in the graph, got %v, %v", mains, err)
+	}
+
+	if err := finder.ExcludeFromOwnership("gen/[", "ok"); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if contains(finder.ownershipExcludes, "ok") {
+		t.Error("expected no pattern to be added when one is invalid")
+	}
+}
+
+func TestExcludeFromOwnershipDiagnostics(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	if err := finder.ExcludeFromOwnership("internal/leaf"); err != nil {
+		t.Fatalf("ExcludeFromOwnership failed: %v", err)
+	}
+	leaf := filepath.Join(root, "internal", "leaf", "leaf.go")
+
+	tree, err := finder.ExplainOwnership("app/main.go", leaf)
+	if err != nil {
+		t.Fatalf("ExplainOwnership failed: %v", err)
+	}
+	if tree.Owned() || tree.Case != CaseExcludedFromOwnership {
+		t.Errorf("expected ExplainOwnership to report the exclusion:\n%s", tree)
+	}
+	explainAgreesWithThisFileIsMine(t, finder, []string{"app/main.go", "testmod/app"}, []string{leaf, filepath.Join(root, "mid", "mid.go")})
+
+	chain, err := finder.WhyDependsOn("app/main.go", leaf)
+	if err != nil {
+		t.Fatalf("WhyDependsOn failed: %v", err)
+	}
+	if len(chain) != 0 {
+		t.Errorf("expected no chain to an excluded package, got %v", chain)
+	}
+	if chain, err := finder.WhyDependsOn("app/main.go", filepath.Join(root, "mid", "mid.go")); err != nil || len(chain) != 2 {
+		t.Errorf("expected mid to keep its chain, got %v, %v", chain, err)
+	}
+
+	cached, authoritative, err := finder.VerifyOwnership("app/main.go", leaf)
+	if err != nil {
+		t.Fatalf("VerifyOwnership failed: %v", err)
+	}
+	if cached || authoritative {
+		t.Errorf("expected both decisions to exclude leaf.go, got cached=%v authoritative=%v", cached, authoritative)
+	}
+}
@@ -0,0 +1,192 @@
+package godepfind
+
+import (
+	"fmt"
+	"go/build"
+	"testing"
+)
+
+// diamondPackages builds depth layers of two packages, each importing both
+// packages of the next layer, over a single leaf: 2^depth import paths lead
+// from top to leaf
+func diamondPackages(depth int) map[string]*build.Package {
+	packages := map[string]*build.Package{"leaf": {ImportPath: "leaf"}}
+	below := []string{"leaf"}
+	for layer := depth - 1; layer >= 0; layer-- {
+		current := []string{fmt.Sprintf("l%d/a", layer), fmt.Sprintf("l%d/b", layer)}
+		for _, path := range current {
+			packages[path] = &build.Package{ImportPath: path, Imports: below}
+		}
+		below = current
+	}
+	packages["top"] = &build.Package{ImportPath: "top", Imports: below}
+	return packages
+}
+
+// recursiveImports is the former recursive imports, without a visited set,
+// kept to compare against in the benchmarks
+func recursiveImports(path string, packages map[string]*build.Package, any map[string]bool) bool {
+	if any[path] {
+		return true
+	}
+	pkg, ok := packages[path]
+	if !ok || pkg == nil {
+		return false
+	}
+	for _, imp := range pkg.Imports {
+		if recursiveImports(imp, packages, any) {
+			any[path] = true
+			return true
+		}
+	}
+	return false
+}
+
+func TestImportsWalk(t *testing.T) {
+	g := New("testproject")
+	packages := diamondPackages(40) // 2^40 paths: only a walk visiting each package once terminates
+	if g.imports("top", packages, map[string]bool{"missing": true}, nil) {
+		t.Error("expected top not to import a package outside the graph")
+	}
+	any := map[string]bool{"leaf": true}
+	if !g.imports("top", packages, any, nil) {
+		t.Error("expected top to import leaf")
+	}
+	if !any["top"] || !any["l0/a"] {
+		t.Errorf("expected the path to leaf to be recorded as importing it, got %v", any)
+	}
+
+	// Import cycles terminate
+	cycle := map[string]*build.Package{
+		"a": {Imports: []string{"b"}},
+		"b": {Imports: []string{"a"}},
+	}
+	if g.imports("a", cycle, map[string]bool{"c": true}, nil) {
+		t.Error("expected the cycle not to import c")
+	}
+
+	// A deep chain doesn't recurse
+	chain := make(map[string]*build.Package)
+	const depth = 200000
+	for i := 0; i < depth; i++ {
+		chain[fmt.Sprint(i)] = &build.Package{Imports: []string{fmt.Sprint(i + 1)}}
+	}
+	if !g.imports("0", chain, map[string]bool{fmt.Sprint(depth): true}, nil) {
+		t.Error("expected the chain to reach its end")
+	}
+
+	// Test imports count at every level when enabled
+	withTests := map[string]*build.Package{
+		"top": {Imports: []string{"mid"}},
+		"mid": {TestImports: []string{"target"}},
+	}
+	if g.imports("top", withTests, map[string]bool{"target": true}, nil) {
+		t.Error("expected test imports to be ignored by default")
+	}
+	g.SetTestImports(true)
+	if !g.imports("top", withTests, map[string]bool{"target": true}, nil) {
+		t.Error("expected mid's test import to count with SetTestImports")
+	}
+}
+
+// BenchmarkImportsDiamond walks a diamond graph missing the target: the
+// recursive walk follows all 2^depth paths, the iterative one visits each
+// package once
+func BenchmarkImportsDiamond(b *testing.B) {
+	const depth = 18
+	packages := diamondPackages(depth)
+	b.Run("recursive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			recursiveImports("top", packages, map[string]bool{"missing": true})
+		}
+	})
+	b.Run("iterative", func(b *testing.B) {
+		g := New("testproject")
+		for i := 0; i < b.N; i++ {
+			g.imports("top", packages, map[string]bool{"missing": true}, nil)
+		}
+	})
+}
+
+// widePackages builds sources packages importing the head of a shared chain
+// of length packages, plus one source importing target directly
+func widePackages(sources, length int) map[string]*build.Package {
+	packages := map[string]*build.Package{"target": {}, "direct": {Imports: []string{"target"}}}
+	for i := 0; i < length; i++ {
+		var imports []string
+		if i+1 < length {
+			imports = []string{fmt.Sprintf("chain/%d", i+1)}
+		}
+		packages[fmt.Sprintf("chain/%d", i)] = &build.Package{Imports: imports}
+	}
+	for i := 0; i < sources; i++ {
+		packages[fmt.Sprintf("src/%d", i)] = &build.Package{Imports: []string{"chain/0"}}
+	}
+	return packages
+}
+
+func TestImportsUnreachableMemo(t *testing.T) {
+	g := New("testproject")
+	packages := widePackages(3, 4)
+	targets := map[string]bool{"target": true}
+	unreachable := make(map[string]bool)
+
+	if g.imports("src/0", packages, targets, unreachable) {
+		t.Fatal("expected src/0 not to import target")
+	}
+	for _, path := range []string{"src/0", "chain/0", "chain/3"} {
+		if !unreachable[path] {
+			t.Errorf("expected %s to be recorded as unreachable, got %v", path, unreachable)
+		}
+	}
+	// A memoized package isn't walked again: poison the chain to prove it
+	packages["chain/3"] = &build.Package{Imports: []string{"target"}}
+	if g.imports("src/1", packages, targets, unreachable) {
+		t.Error("expected the memoized chain to be skipped")
+	}
+	if !g.imports("direct", packages, targets, unreachable) || unreachable["direct"] {
+		t.Error("expected direct to import target and not to be recorded as unreachable")
+	}
+
+	// A match records nothing as unreachable: b is left before a reaches the
+	// target through c, yet b imports it through a
+	cycle := map[string]*build.Package{
+		"a":      {Imports: []string{"b", "c"}},
+		"b":      {Imports: []string{"a"}},
+		"c":      {Imports: []string{"target"}},
+		"target": {},
+	}
+	unreachable = make(map[string]bool)
+	if !g.imports("a", cycle, map[string]bool{"target": true}, unreachable) || len(unreachable) != 0 {
+		t.Errorf("expected a to import target with nothing unreachable, got %v", unreachable)
+	}
+}
+
+// BenchmarkImportsWide checks many sources sharing a long chain missing the
+// target, as FindReverseDeps does: with a memo per source the chain is
+// walked for each of them, with the shared memo once
+func BenchmarkImportsWide(b *testing.B) {
+	packages := widePackages(500, 500)
+	paths := make([]string, 0, len(packages))
+	for path := range packages {
+		paths = append(paths, path)
+	}
+	g := New("testproject")
+	b.Run("per-source", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			targets := map[string]bool{"target": true}
+			for _, path := range paths {
+				g.imports(path, packages, targets, make(map[string]bool))
+			}
+		}
+	})
+	b.Run("shared", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			targets := map[string]bool{"target": true}
+			unreachable := make(map[string]bool)
+			for _, path := range paths {
+				g.imports(path, packages, targets, unreachable)
+			}
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package godepfind
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PackageLayers returns the layer of every module package: the length of the
+// longest import path from the package down to a leaf (a package importing no
+// other module package), so higher layers depend on lower ones. Only module
+// packages are considered; the standard library and external modules are not
+// part of the hierarchy. Packages in an import cycle share the layer of the
+// cycle as a whole; use PackageLayerCycles to report them.
+func (g *GoDepFind) PackageLayers() (map[string]int, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	components := g.moduleComponents()
+	componentOf := make(map[string]int)
+	for i, component := range components {
+		for _, pkgPath := range component {
+			componentOf[pkgPath] = i
+		}
+	}
+
+	// Tarjan emits components in reverse topological order: every component
+	// a component depends on has already been assigned its layer
+	componentLayer := make([]int, len(components))
+	layers := make(map[string]int)
+	for i, component := range components {
+		for _, pkgPath := range component {
+			for _, dep := range g.dependencyGraph[pkgPath] {
+				if j, internal := componentOf[dep]; internal && j != i && componentLayer[j]+1 > componentLayer[i] {
+					componentLayer[i] = componentLayer[j] + 1
+				}
+			}
+		}
+		for _, pkgPath := range component {
+			layers[pkgPath] = componentLayer[i]
+		}
+	}
+	return layers, nil
+}
+
+// PackageLayerCycles returns the import cycles among module packages, each as
+// the sorted list of packages involved. Cycles are sorted by first package.
+func (g *GoDepFind) PackageLayerCycles() ([][]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cycles := [][]string{}
+	for _, component := range g.moduleComponents() {
+		if len(component) > 1 || contains(g.dependencyGraph[component[0]], component[0]) {
+			cycles = append(cycles, component)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles, nil
+}
+
+// moduleComponents returns the strongly connected components of the module
+// package graph (Tarjan's algorithm), in reverse topological order. Packages
+// within a component are sorted.
+func (g *GoDepFind) moduleComponents() [][]string {
+	var pkgPaths []string
+	for pkgPath, pkg := range g.packageCache {
+		if pkg != nil {
+			pkgPaths = append(pkgPaths, pkgPath)
+		}
+	}
+	sort.Strings(pkgPaths)
+
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var connect func(pkgPath string)
+	connect = func(pkgPath string) {
+		index[pkgPath] = len(index)
+		lowlink[pkgPath] = index[pkgPath]
+		stack = append(stack, pkgPath)
+		onStack[pkgPath] = true
+
+		for _, dep := range g.dependencyGraph[pkgPath] {
+			if g.packageCache[dep] == nil {
+				continue // not a module package
+			}
+			if _, visited := index[dep]; !visited {
+				connect(dep)
+				lowlink[pkgPath] = min(lowlink[pkgPath], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[pkgPath] = min(lowlink[pkgPath], index[dep])
+			}
+		}
+
+		if lowlink[pkgPath] == index[pkgPath] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == pkgPath {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, pkgPath := range pkgPaths {
+		if _, visited := index[pkgPath]; !visited {
+			connect(pkgPath)
+		}
+	}
+	return components
+}
+
+// IsLeafPackage reports whether a module package imports no other module
+// package (only the standard library or third-party packages), i.e. it sits
+// in layer 0 of PackageLayers.
+func (g *GoDepFind) IsLeafPackage(pkgPath string) (bool, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	if g.packageCache[pkgPath] == nil {
+		return false, fmt.Errorf("package not found in module: %s", pkgPath)
+	}
+	for _, dep := range g.dependencyGraph[pkgPath] {
+		if g.packageCache[dep] != nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,107 @@
+package godepfind
+
+import (
+	"go/build"
+	"reflect"
+	"testing"
+)
+
+func TestPackageLayers(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":             "module testmod\n\ngo 1.21\n",
+		"cmd/app/main.go":    "package main\n\nimport (\n\t\"fmt\"\n\n\t\"testmod/service\"\n\t\"testmod/util\"\n)\n\nfunc main() { fmt.Println(service.Do(), util.Do()) }\n",
+		"service/service.go": "package service\n\nimport \"testmod/store\"\n\nfunc Do() string { return store.Do() }\n",
+		"store/store.go":     "package store\n\nimport \"testmod/util\"\n\nfunc Do() string { return util.Do() }\n",
+		"util/util.go":       "package util\n\nimport \"strings\"\n\nfunc Do() string { return strings.ToUpper(\"u\") }\n",
+	})
+	finder := New(root)
+
+	layers, err := finder.PackageLayers()
+	if err != nil {
+		t.Fatalf("PackageLayers failed: %v", err)
+	}
+	want := map[string]int{
+		"testmod/util":    0, // standard library imports don't count
+		"testmod/store":   1,
+		"testmod/service": 2,
+		"testmod/cmd/app": 3, // longest path wins over the direct util import
+	}
+	if !reflect.DeepEqual(layers, want) {
+		t.Errorf("PackageLayers = %v, want %v", layers, want)
+	}
+
+	cycles, err := finder.PackageLayerCycles()
+	if err != nil {
+		t.Fatalf("PackageLayerCycles failed: %v", err)
+	}
+	if len(cycles) != 0 {
+		t.Errorf("expected no cycles, got %v", cycles)
+	}
+}
+
+func TestPackageLayersWithCycle(t *testing.T) {
+	// go list refuses modules with import cycles, so cycles only show up in
+	// the cache while a change is being made: inject such a graph directly
+	finder := New(t.TempDir())
+	finder.packageCache = map[string]*build.Package{
+		"testmod/top":  {Name: "top"},
+		"testmod/a":    {Name: "a"},
+		"testmod/b":    {Name: "b"},
+		"testmod/leaf": {Name: "leaf"},
+	}
+	finder.dependencyGraph = map[string][]string{
+		"testmod/top":  {"testmod/a"},
+		"testmod/a":    {"testmod/b"},
+		"testmod/b":    {"testmod/a", "testmod/leaf", "fmt"},
+		"testmod/leaf": {},
+	}
+	finder.cachedModule = true
+
+	layers, err := finder.PackageLayers()
+	if err != nil {
+		t.Fatalf("PackageLayers failed: %v", err)
+	}
+	want := map[string]int{
+		"testmod/leaf": 0,
+		"testmod/a":    1, // a and b form a cycle and share its layer
+		"testmod/b":    1,
+		"testmod/top":  2,
+	}
+	if !reflect.DeepEqual(layers, want) {
+		t.Errorf("PackageLayers = %v, want %v", layers, want)
+	}
+
+	cycles, err := finder.PackageLayerCycles()
+	if err != nil {
+		t.Fatalf("PackageLayerCycles failed: %v", err)
+	}
+	if wantCycles := [][]string{{"testmod/a", "testmod/b"}}; !reflect.DeepEqual(cycles, wantCycles) {
+		t.Errorf("PackageLayerCycles = %v, want %v", cycles, wantCycles)
+	}
+}
+
+func TestIsLeafPackage(t *testing.T) {
+	finder := New("testproject")
+
+	tests := []struct {
+		pkgPath string
+		leaf    bool
+	}{
+		{"testproject/modules/module1", true},
+		{"testproject/modules/module3", true},
+		{"testproject/appAserver", false}, // imports module1 and module2
+	}
+	for _, tt := range tests {
+		leaf, err := finder.IsLeafPackage(tt.pkgPath)
+		if err != nil {
+			t.Fatalf("IsLeafPackage(%s) failed: %v", tt.pkgPath, err)
+		}
+		if leaf != tt.leaf {
+			t.Errorf("IsLeafPackage(%s) = %v, want %v", tt.pkgPath, leaf, tt.leaf)
+		}
+	}
+
+	if _, err := finder.IsLeafPackage("fmt"); err == nil {
+		t.Error("expected error for a package outside the module")
+	}
+}
@@ -0,0 +1,54 @@
+package godepfind
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+// PackageLineCounts returns the number of source lines in the GoFiles of each
+// cached package, useful to weight the rebuild cost of a main. Counts are
+// cached and invalidated together with their package.
+func (g *GoDepFind) PackageLineCounts() (map[string]int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int, len(g.packageCache))
+	for pkgPath, pkg := range g.packageCache {
+		if pkg == nil {
+			continue
+		}
+		count, cached := g.lineCounts[pkgPath]
+		if !cached {
+			var err error
+			count, err = countPackageLines(pkg.Dir, pkg.GoFiles)
+			if err != nil {
+				return nil, err
+			}
+			g.lineCounts[pkgPath] = count
+		}
+		result[pkgPath] = count
+	}
+	return result, nil
+}
+
+// countPackageLines sums the line counts of the given files inside dir
+func countPackageLines(dir string, files []string) (int, error) {
+	total := 0
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return 0, err
+		}
+		lines := bytes.Count(content, []byte("\n"))
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			lines++ // last line without trailing newline
+		}
+		total += lines
+	}
+	return total, nil
+}
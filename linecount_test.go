@@ -0,0 +1,45 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageLineCounts(t *testing.T) {
+	finder := New("testproject")
+
+	counts, err := finder.PackageLineCounts()
+	if err != nil {
+		t.Fatalf("PackageLineCounts failed: %v", err)
+	}
+	if got := counts["testproject/modules/module1"]; got != 5 {
+		t.Errorf("expected 5 lines for module1, got %d", got)
+	}
+	if got := counts["testproject/appAserver"]; got != 11 {
+		t.Errorf("expected 11 lines for appAserver, got %d", got)
+	}
+	if _, cached := finder.lineCounts["testproject/modules/module1"]; !cached {
+		t.Error("expected line count to be cached")
+	}
+
+	// Invalidating the package drops its cached count
+	if err := finder.invalidatePackageCacheOnly(filepath.Join("testproject", "modules", "module1", "module1.go")); err != nil {
+		t.Fatalf("invalidation failed: %v", err)
+	}
+	if _, cached := finder.lineCounts["testproject/modules/module1"]; cached {
+		t.Error("expected line count to be invalidated with its package")
+	}
+}
+
+func TestCountPackageLinesWithoutTrailingNewline(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"a.go": "package a\n\nfunc A() {}",
+	})
+	count, err := countPackageLines(root, []string{"a.go"})
+	if err != nil {
+		t.Fatalf("countPackageLines failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 lines, got %d", count)
+	}
+}
@@ -0,0 +1,209 @@
+package godepfind
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLastListCommand(t *testing.T) {
+	finder := New("testproject")
+
+	if program, args := finder.LastListCommand(); program != "" || args != nil {
+		t.Errorf("expected no command before any go list invocation, got %s %v", program, args)
+	}
+
+	finder.SetBuildTags([]string{"integration", "production"})
+	if _, err := finder.listPackages("./..."); err != nil {
+		t.Fatalf("listPackages failed: %v", err)
+	}
+
+	program, args := finder.LastListCommand()
+	if program != "go" {
+		t.Errorf("expected program go, got %s", program)
+	}
+	expected := []string{"list", "-tags", "integration,production", "./..."}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected args %v, got %v", expected, args)
+	}
+
+	// The returned slice must not alias internal state
+	args[0] = "mutated"
+	if _, again := finder.LastListCommand(); again[0] != "list" {
+		t.Error("LastListCommand returned a slice aliasing internal state")
+	}
+}
+
+func TestLastListEnv(t *testing.T) {
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("UNRELATED_SECRET", "x")
+	finder := New("testproject")
+	if dir, env := finder.LastListEnv(); dir != "" || len(env) != 0 {
+		t.Errorf("expected no environment before any go list invocation, got %s %v", dir, env)
+	}
+
+	finder.SetTargetPlatform("js", "wasm")
+	if _, err := finder.listPackages("./..."); err != nil {
+		t.Fatalf("listPackages failed: %v", err)
+	}
+	dir, env := finder.LastListEnv()
+	if want, _ := filepath.Abs("testproject"); dir != want {
+		t.Errorf("expected go list to run in %s, got %s", want, dir)
+	}
+	for _, want := range []string{"GOARCH=wasm", "GOFLAGS=-mod=mod", "GOOS=js", "GO111MODULE=on"} {
+		if !contains(env, want) {
+			t.Errorf("expected %s in the recorded environment, got %v", want, env)
+		}
+	}
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "UNRELATED_SECRET=") {
+			t.Errorf("expected only Go variables, got %v", env)
+		}
+	}
+}
+
+func TestSetGoBinary(t *testing.T) {
+	finder := New("testproject")
+
+	if err := finder.SetGoBinary(""); err == nil {
+		t.Error("expected error for an empty go binary path")
+	}
+	if err := finder.SetGoBinary(filepath.Join(t.TempDir(), "missing-go")); err == nil {
+		t.Error("expected error for a go binary that does not exist")
+	}
+
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go binary not on PATH")
+	}
+	if err := finder.SetGoBinary(goPath); err != nil {
+		t.Fatalf("SetGoBinary failed: %v", err)
+	}
+	if _, err := finder.GoFileComesFromMain("module1.go"); err != nil {
+		t.Fatalf("GoFileComesFromMain failed: %v", err)
+	}
+	if program, _ := finder.LastListCommand(); program != goPath {
+		t.Errorf("expected go list to run %s, got %s", goPath, program)
+	}
+}
+
+func TestGoBinaryExecutionError(t *testing.T) {
+	finder := New("testproject")
+	// Bypass SetGoBinary validation to simulate a binary removed after configuration
+	finder.goBinary = filepath.Join(t.TempDir(), "removed-go")
+
+	_, err := finder.listPackages("./...")
+	if err == nil {
+		t.Fatal("expected error when the go binary cannot be executed")
+	}
+	if !strings.Contains(err.Error(), "cannot execute go binary") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	if !errors.Is(err, ErrGoNotFound) {
+		t.Errorf("expected ErrGoNotFound, got %v", err)
+	}
+	var listErr *GoListError
+	if errors.As(err, &listErr) {
+		t.Error("a missing go binary must not be reported as a go list failure")
+	}
+}
+
+func TestGoListErrorCapturesStderr(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":      "module testmod\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport \"testmod/a\"\n\nfunc main() { a.A() }\n",
+		"a/a.go":      "package a\n\nimport \"testmod/b\"\n\nfunc A() { b.B() }\n",
+		"b/b.go":      "package b\n\nimport \"testmod/a\"\n\nfunc B() { a.A() }\n",
+	})
+	finder := New(root)
+
+	_, err := finder.GoFileComesFromMain("a.go")
+	if err == nil {
+		t.Fatal("expected go list to fail on an import cycle")
+	}
+	var listErr *GoListError
+	if !errors.As(err, &listErr) {
+		t.Fatalf("expected a *GoListError, got %T: %v", err, err)
+	}
+	if !strings.Contains(listErr.Stderr, "import cycle") {
+		t.Errorf("expected stderr to explain the failure, got %q", listErr.Stderr)
+	}
+	if len(listErr.Command) == 0 || listErr.Command[0] != "go" {
+		t.Errorf("expected the failing command to be recorded, got %v", listErr.Command)
+	}
+	if errors.Is(err, ErrGoNotFound) {
+		t.Error("a failing go list must not be reported as a missing go binary")
+	}
+}
+
+func TestListMemoAvoidsRepeatedScans(t *testing.T) {
+	root := writeTestModule(t, syntheticModuleFiles(10))
+	finder := New(root)
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+	afterRebuild := finder.listCount
+
+	// Full scans right after the rebuild reuse its listing
+	if mains, err := finder.findMainPackages(); err != nil || len(mains) != 1 {
+		t.Fatalf("findMainPackages = %v, %v", mains, err)
+	}
+	if pkg, err := finder.findPackageContainingFile("pkg.go"); err != nil || pkg == "" {
+		t.Fatalf("findPackageContainingFile = %q, %v", pkg, err)
+	}
+	pkg3 := filepath.Join(root, "pkg3", "pkg.go")
+	for i := 0; i < 3; i++ {
+		// The first write drops pkg3 from the package cache, so later lookups fall back to a scan
+		if err := finder.updateCacheForFile(pkg3, "write"); err != nil {
+			t.Fatalf("write event failed: %v", err)
+		}
+	}
+	if finder.listCount != afterRebuild {
+		t.Errorf("expected no go list after the rebuild, got %d more", finder.listCount-afterRebuild)
+	}
+
+	// Creating a file may change the package set: the next scan lists again
+	if err := os.WriteFile(filepath.Join(root, "pkg3", "extra.go"), []byte("package pkg3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.updateCacheForFile(filepath.Join(root, "pkg3", "extra.go"), "create"); err != nil {
+		t.Fatalf("create event failed: %v", err)
+	}
+	if finder.listCount == afterRebuild {
+		t.Error("expected a create event to invalidate the memoized listing")
+	}
+}
+
+// BenchmarkWriteEventsListMemo measures go list invocations for repeated
+// write events after a rebuild, with and without the memoized listing
+func BenchmarkWriteEventsListMemo(b *testing.B) {
+	for _, memo := range []bool{true, false} {
+		name := "memoized"
+		if !memo {
+			name = "unmemoized"
+		}
+		b.Run(name, func(b *testing.B) {
+			root := writeTestModule(b, syntheticModuleFiles(20))
+			finder := New(root)
+			if err := finder.ensureCacheInitialized(); err != nil {
+				b.Fatalf("cache init failed: %v", err)
+			}
+			file := filepath.Join(root, "pkg7", "pkg.go")
+			start := finder.listCount
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !memo {
+					finder.invalidateListMemo()
+				}
+				if err := finder.updateCacheForFile(file, "write"); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(finder.listCount-start)/float64(b.N), "golist/op")
+		})
+	}
+}
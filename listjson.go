@@ -0,0 +1,139 @@
+package godepfind
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ListPackage mirrors the field layout emitted by `go list -json` for the
+// subset of data available in the cache.
+type ListPackage struct {
+	Dir            string   `json:",omitempty"`
+	ImportPath     string   `json:",omitempty"`
+	Name           string   `json:",omitempty"`
+	Doc            string   `json:",omitempty"`
+	Root           string   `json:",omitempty"`
+	Goroot         bool     `json:",omitempty"`
+	Standard       bool     `json:",omitempty"`
+	GoFiles        []string `json:",omitempty"`
+	CgoFiles       []string `json:",omitempty"`
+	IgnoredGoFiles []string `json:",omitempty"`
+	TestGoFiles    []string `json:",omitempty"`
+	XTestGoFiles   []string `json:",omitempty"`
+	EmbedPatterns  []string `json:",omitempty"`
+	EmbedFiles     []string `json:",omitempty"`
+	Imports        []string `json:",omitempty"`
+	Deps           []string `json:",omitempty"`
+	TestImports    []string `json:",omitempty"`
+	XTestImports   []string `json:",omitempty"`
+}
+
+// ListJSON writes the cached packages matching pattern to w using the same
+// stream of JSON objects produced by `go list -json`, so tools consuming that
+// format can use godepfind as a faster cached `go list`.
+//
+// pattern accepts "./..." (all packages), relative directory patterns such as
+// "./modules/..." and import path patterns such as "testproject/modules/...".
+// Deps holds the transitive imports computed from the cached dependency graph.
+func (g *GoDepFind) ListJSON(w io.Writer, pattern string) error {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var paths []string
+	for pkgPath, pkg := range g.packageCache {
+		if pkg != nil && g.matchesListPattern(pattern, pkgPath, pkg.Dir) {
+			paths = append(paths, pkgPath)
+		}
+	}
+	sort.Strings(paths)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	for _, pkgPath := range paths {
+		if err := enc.Encode(g.listPackage(pkgPath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listPackage converts a cached build.Package into its go list representation
+func (g *GoDepFind) listPackage(pkgPath string) *ListPackage {
+	pkg := g.packageCache[pkgPath]
+	dir := pkg.Dir
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return &ListPackage{
+		Dir:            dir,
+		ImportPath:     pkgPath,
+		Name:           pkg.Name,
+		Doc:            pkg.Doc,
+		Root:           pkg.Root,
+		Goroot:         pkg.Goroot,
+		Standard:       pkg.Goroot,
+		GoFiles:        pkg.GoFiles,
+		CgoFiles:       pkg.CgoFiles,
+		IgnoredGoFiles: pkg.IgnoredGoFiles,
+		TestGoFiles:    pkg.TestGoFiles,
+		XTestGoFiles:   pkg.XTestGoFiles,
+		EmbedPatterns:  pkg.EmbedPatterns,
+		EmbedFiles:     g.embedFiles[pkgPath],
+		Imports:        pkg.Imports,
+		Deps:           g.transitiveDeps(pkgPath),
+		TestImports:    pkg.TestImports,
+		XTestImports:   pkg.XTestImports,
+	}
+}
+
+// transitiveDeps returns the sorted transitive imports of pkgPath from the cached dependency graph
+func (g *GoDepFind) transitiveDeps(pkgPath string) []string {
+	visited := map[string]bool{pkgPath: true}
+	stack := []string{pkgPath}
+	var deps []string
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, dep := range g.dependencyGraph[current] {
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			deps = append(deps, dep)
+			stack = append(stack, dep)
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// matchesListPattern reports whether a package matches a go list style pattern
+func (g *GoDepFind) matchesListPattern(pattern, pkgPath, pkgDir string) bool {
+	if pattern == "" || pattern == "./..." || pattern == "all" {
+		return true
+	}
+
+	target := pkgPath
+	if pattern == "." || strings.HasPrefix(pattern, "./") {
+		relDir, err := filepath.Rel(g.rootDir, pkgDir)
+		if err != nil {
+			return false
+		}
+		target = filepath.ToSlash(relDir)
+		pattern = strings.TrimPrefix(pattern, "./")
+		if pattern == "" {
+			pattern = "."
+		}
+	}
+
+	if base, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return base == "." || target == base || strings.HasPrefix(target, base+"/")
+	}
+	return target == pattern
+}
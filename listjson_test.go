@@ -0,0 +1,87 @@
+package godepfind
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+// goListPackage is the subset of the cmd/go list -json Package struct consumed by tooling
+type goListPackage struct {
+	Dir        string
+	ImportPath string
+	Name       string
+	GoFiles    []string
+	Imports    []string
+	Deps       []string
+}
+
+func TestListJSON(t *testing.T) {
+	finder := New("testproject")
+
+	var buf bytes.Buffer
+	if err := finder.ListJSON(&buf, "./..."); err != nil {
+		t.Fatalf("ListJSON failed: %v", err)
+	}
+
+	packages := make(map[string]goListPackage)
+	dec := json.NewDecoder(&buf)
+	for {
+		var pkg goListPackage
+		if err := dec.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("emitted JSON does not decode as go list output: %v", err)
+		}
+		packages[pkg.ImportPath] = pkg
+	}
+
+	if len(packages) != 7 {
+		t.Errorf("expected 7 packages, got %d", len(packages))
+	}
+
+	server, ok := packages["testproject/appAserver"]
+	if !ok {
+		t.Fatal("expected testproject/appAserver in output")
+	}
+	if server.Name != "main" {
+		t.Errorf("expected Name main, got %q", server.Name)
+	}
+	if server.Dir == "" || len(server.GoFiles) != 1 || server.GoFiles[0] != "main.go" {
+		t.Errorf("unexpected Dir/GoFiles: %q %v", server.Dir, server.GoFiles)
+	}
+	if !contains(server.Imports, "testproject/modules/module1") || !contains(server.Imports, "testproject/modules/module2") {
+		t.Errorf("unexpected Imports: %v", server.Imports)
+	}
+	if !contains(server.Deps, "testproject/modules/module2") {
+		t.Errorf("expected Deps to include module2, got %v", server.Deps)
+	}
+}
+
+func TestListJSONPattern(t *testing.T) {
+	finder := New("testproject")
+
+	var buf bytes.Buffer
+	if err := finder.ListJSON(&buf, "./modules/..."); err != nil {
+		t.Fatalf("ListJSON failed: %v", err)
+	}
+
+	dec := json.NewDecoder(&buf)
+	count := 0
+	for {
+		var pkg goListPackage
+		if err := dec.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		if pkg.Name == "main" {
+			t.Errorf("pattern ./modules/... should not include main package %s", pkg.ImportPath)
+		}
+		count++
+	}
+	if count != 4 {
+		t.Errorf("expected 4 module packages, got %d", count)
+	}
+}
@@ -34,3 +34,52 @@ func TestMainFileNameEqualsHandlerMainFilePath(t *testing.T) {
 		t.Errorf("Expected ThisFileIsMine to return true when handler specifically targets this package, got false")
 	}
 }
+
+// The handler main file must be recognized whatever the separators of the
+// event path and the handler path, so edits adding imports to it are rescanned
+func TestHandlerMainFileDetectedWithNativeSeparators(t *testing.T) {
+	for _, handler := range []string{filepath.FromSlash("app/main.go"), "app/main.go", "./app/main.go"} {
+		root := writeTestModule(t, map[string]string{
+			"go.mod":      "module testmod\n\ngo 1.21\n",
+			"app/main.go": "package main\n\nfunc main() {}\n",
+			"lib/lib.go":  "package lib\n\nfunc Do() {}\n",
+		})
+		finder := New(root)
+		mainFile := filepath.Join(root, filepath.FromSlash("app/main.go"))
+		libFile := filepath.Join(root, filepath.FromSlash("lib/lib.go"))
+
+		if mine, err := finder.ThisFileIsMine(handler, libFile, EventWrite); err != nil || mine {
+			t.Fatalf("handler %q: lib before the import = %v, %v; want false", handler, mine, err)
+		}
+
+		// Editing the main file to import lib must trigger the rescan
+		if err := os.WriteFile(mainFile, []byte("package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do() }\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mine, confidence, err := finder.ThisFileIsMineWithConfidence(handler, mainFile, EventWrite)
+		if err != nil || !mine || confidence != ConfidenceHigh {
+			t.Fatalf("handler %q: main file = %v, %v, %v; want true with high confidence", handler, mine, confidence, err)
+		}
+		if mine, err := finder.ThisFileIsMine(handler, libFile, EventWrite); err != nil || !mine {
+			t.Errorf("handler %q: lib after the import = %v, %v; want true", handler, mine, err)
+		}
+	}
+}
+
+func TestSameRelativePath(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{filepath.FromSlash("app/main.go"), "app/main.go", true},
+		{"./app/main.go", "app/main.go", true},
+		{"app//main.go", filepath.FromSlash("app/main.go"), true},
+		{"app/main.go", "other/main.go", false},
+		{"main.go", "app/main.go", false},
+	}
+	for _, c := range cases {
+		if got := sameRelativePath(c.a, c.b); got != c.want {
+			t.Errorf("sameRelativePath(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package godepfind
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// MainTarget describes a main package, i.e. a binary that can be rebuilt
+type MainTarget struct {
+	ImportPath string `json:"import_path"`
+	Dir        string `json:"dir"` // absolute package directory
+}
+
+// mainTarget builds the MainTarget for a cached main package path
+func (g *GoDepFind) mainTarget(mainPath string) MainTarget {
+	target := MainTarget{ImportPath: mainPath}
+	if pkg := g.packageCache[mainPath]; pkg != nil {
+		target.Dir = pkg.Dir
+		if abs, err := filepath.Abs(pkg.Dir); err == nil {
+			target.Dir = abs
+		}
+	}
+	return target
+}
+
+// mainTargets converts main package paths to sorted MainTargets
+func (g *GoDepFind) mainTargets(mainPaths []string) []MainTarget {
+	sorted := append([]string{}, mainPaths...)
+	sort.Strings(sorted)
+	targets := make([]MainTarget, 0, len(sorted))
+	for _, mainPath := range sorted {
+		targets = append(targets, g.mainTarget(mainPath))
+	}
+	return targets
+}
+
+// ListMainPackages returns the import paths of the main packages found in the
+// tree, sorted. The slice is a copy: later events adding or removing mains
+// don't change it.
+func (g *GoDepFind) ListMainPackages() ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	mains := append([]string{}, g.mainPackages...)
+	sort.Strings(mains)
+	return mains, nil
+}
+
+// IsMainPackageDir reports whether a directory (relative to the root, e.g.
+// "cmd/server", or absolute) holds a main package of the cache, and returns
+// its import path. Directories are compared cleaned, so "./cmd/server/" works
+// too. A directory holding no package, or a non-main one, yields false and an
+// empty path.
+func (g *GoDepFind) IsMainPackageDir(relDir string) (bool, string, error) {
+	if relDir == "" {
+		return false, "", fmt.Errorf("dir cannot be empty")
+	}
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return false, "", err
+	}
+	defer unlock()
+
+	pkgPath := g.packageForDir(g.rootPath(filepath.Clean(filepath.FromSlash(relDir))))
+	if pkgPath == "" || !g.isMainPackage(pkgPath) {
+		return false, "", nil
+	}
+	return true, pkgPath, nil
+}
+
+// FilesForMain returns the absolute paths of the Go files compiled into the
+// binary of a handler main (a main file path or the import path of a main
+// package): the GoFiles of the main package and of every package it imports
+// transitively, plus their _test files when SetTestImports is enabled.
+// Standard library packages are skipped. The result is sorted and has no
+// duplicates, e.g. to hash or copy every source of a binary.
+func (g *GoDepFind) FilesForMain(mainInputFileRelativePath string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	mainPkg, byImportPath, err := g.handlerMainImportPath(mainInputFileRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	if !byImportPath {
+		if mainPkg, err = g.resolveHandlerMainPackage(mainInputFileRelativePath); err != nil {
+			return nil, err
+		}
+		if !g.isMainPackage(mainPkg) {
+			return nil, fmt.Errorf("handler main file %s is not part of a main package", mainInputFileRelativePath)
+		}
+	}
+
+	seen := make(map[string]bool)
+	result := []string{}
+	addFiles := func(dir string, files []string) {
+		for _, file := range files {
+			absPath, err := filepath.Abs(filepath.Join(dir, file))
+			if err != nil || seen[absPath] || g.ignoredPath(absPath) {
+				continue
+			}
+			seen[absPath] = true
+			result = append(result, absPath)
+		}
+	}
+
+	visited := map[string]bool{mainPkg: true}
+	stack := []string{mainPkg}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		pkg := g.packageCache[current]
+		if pkg == nil {
+			pkg = g.externalPackages[current]
+		}
+		if pkg != nil && !pkg.Goroot {
+			addFiles(pkg.Dir, pkg.GoFiles)
+			addFiles(pkg.Dir, g.enabledTestGoFiles(pkg))
+		}
+		for _, dep := range g.dependencyGraph[current] {
+			if !visited[dep] {
+				visited[dep] = true
+				stack = append(stack, dep)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// MainsSharingDependency returns every main whose transitive closure includes pkgPath
+func (g *GoDepFind) MainsSharingDependency(pkgPath string) ([]MainTarget, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var mains []string
+	for _, mainPath := range g.mainPackages {
+		if mainPath != pkgPath && g.cachedMainImportsPackage(mainPath, pkgPath) {
+			mains = append(mains, mainPath)
+		}
+	}
+	return g.mainTargets(mains), nil
+}
+
+// FindCommonDependencies returns the sorted packages imported, directly or
+// transitively, by every one of mainPkgs: the packages whose compilation the
+// binaries can share. Standard library packages are included unless excluded
+// with SetExcludeStdlib. An empty list of mains yields an empty slice; a name
+// that isn't a main package is an error.
+func (g *GoDepFind) FindCommonDependencies(mainPkgs []string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, mainPkg := range mainPkgs {
+		if !g.isMainPackage(mainPkg) {
+			return nil, fmt.Errorf("not a main package: %s", mainPkg)
+		}
+	}
+	result := []string{}
+	if len(mainPkgs) == 0 {
+		return result, nil
+	}
+
+	counts := make(map[string]int)
+	seen := make(map[string]bool, len(mainPkgs))
+	for _, mainPkg := range mainPkgs {
+		if seen[mainPkg] {
+			continue // a repeated main doesn't narrow the intersection
+		}
+		seen[mainPkg] = true
+		for _, dep := range g.transitiveDeps(mainPkg) {
+			counts[dep]++
+		}
+	}
+	for dep, count := range counts {
+		if count == len(seen) {
+			result = append(result, dep)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// DependencyClusters groups the mains whose first-party dependency sets
+// overlap, directly or through other mains in the group. Mains that share
+// nothing with any other main form a cluster of their own. Clusters are sorted
+// by their first main's import path.
+func (g *GoDepFind) DependencyClusters() ([][]MainTarget, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	mains := append([]string{}, g.mainPackages...)
+	sort.Strings(mains)
+
+	// Union-find over mains, joined through the first-party packages they share
+	parent := make(map[string]string, len(mains))
+	var find func(string) string
+	find = func(m string) string {
+		if parent[m] != m {
+			parent[m] = find(parent[m])
+		}
+		return parent[m]
+	}
+	owner := make(map[string]string) // dependency -> first main seen importing it
+	for _, mainPath := range mains {
+		parent[mainPath] = mainPath
+	}
+	for _, mainPath := range mains {
+		for _, dep := range g.transitiveDeps(mainPath) {
+			if _, firstParty := g.packageCache[dep]; !firstParty {
+				continue
+			}
+			if other, seen := owner[dep]; seen {
+				parent[find(mainPath)] = find(other)
+			} else {
+				owner[dep] = mainPath
+			}
+		}
+	}
+
+	groups := make(map[string][]string)
+	var roots []string
+	for _, mainPath := range mains {
+		root := find(mainPath)
+		if _, exists := groups[root]; !exists {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], mainPath)
+	}
+
+	clusters := make([][]MainTarget, 0, len(roots))
+	for _, root := range roots {
+		clusters = append(clusters, g.mainTargets(groups[root]))
+	}
+	return clusters, nil
+}
+
+// UnaffectedMains returns the mains that definitely don't need rebuilding when
+// fileAbsPath changes: every main except those whose closure includes the
+// file's package. When the package can only be guessed from the file name,
+// every candidate package counts as affected. Files outside any package, or
+// excluded by build constraints, leave every main unaffected.
+func (g *GoDepFind) UnaffectedMains(fileAbsPath string) ([]MainTarget, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	affected, err := g.affectedMains(fileAbsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var unaffected []string
+	for _, mainPath := range g.mainPackages {
+		if !affected[mainPath] {
+			unaffected = append(unaffected, mainPath)
+		}
+	}
+	return g.mainTargets(unaffected), nil
+}
+
+// affectedMains returns the set of mains whose closure includes the package of fileAbsPath
+func (g *GoDepFind) affectedMains(fileAbsPath string) (map[string]bool, error) {
+	if fileAbsPath == "" {
+		return nil, fmt.Errorf("fileAbsPath cannot be empty")
+	}
+	fileAbsPath = g.rootPath(fileAbsPath)
+	absPath, err := filepath.Abs(fileAbsPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve fileAbsPath to absolute path: %w", err)
+	}
+
+	pkg, exact, err := g.resolvePackageForFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	affected := make(map[string]bool)
+	if pkg == "" || g.isExcludedByConstraints(absPath) {
+		return affected, nil
+	}
+
+	candidates := []string{pkg}
+	if !exact {
+		candidates = g.fileToPackages[g.pathKey(filepath.Base(absPath))]
+	}
+	for _, mainPath := range g.mainPackages {
+		for _, candidate := range candidates {
+			if g.cachedMainImportsPackage(mainPath, candidate) {
+				affected[mainPath] = true
+				break
+			}
+		}
+	}
+	return affected, nil
+}
+
+// MainDirectImporters returns the main packages that import pkgPath directly
+// (not transitively), i.e. the binaries embedding it without intermediaries.
+func (g *GoDepFind) MainDirectImporters(pkgPath string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result := []string{}
+	for _, importer := range g.reverseDeps[pkgPath] {
+		if g.isMainPackage(importer) && !contains(result, importer) {
+			result = append(result, importer)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// MainImportsPackageWithinDepth reports whether targetPkg is reachable from
+// mainPath in at most maxDepth import hops of the cached dependency graph:
+// 0 matches only mainPath itself, 1 its direct imports, 2 their imports, and
+// so on. A negative maxDepth is an error.
+func (g *GoDepFind) MainImportsPackageWithinDepth(mainPath, targetPkg string, maxDepth int) (bool, error) {
+	if maxDepth < 0 {
+		return false, fmt.Errorf("maxDepth cannot be negative: %d", maxDepth)
+	}
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	// Breadth-first, so each package is reached at its shortest distance
+	visited := map[string]bool{mainPath: true}
+	level := []string{mainPath}
+	for depth := 0; len(level) > 0; depth++ {
+		if contains(level, targetPkg) {
+			return true, nil
+		}
+		if depth == maxDepth {
+			break
+		}
+		var next []string
+		for _, pkgPath := range level {
+			for _, dep := range g.dependencyGraph[pkgPath] {
+				if !visited[dep] {
+					visited[dep] = true
+					next = append(next, dep)
+				}
+			}
+		}
+		level = next
+	}
+	return false, nil
+}
+
+// RebuildPlan returns the mains affected by changes to fileAbsPaths, each
+// once, sorted by import path. Go rejects imports of main packages, so no main
+// has to be built before another: the plan has no ordering constraints and
+// its builds can run in any order or concurrently.
+func (g *GoDepFind) RebuildPlan(fileAbsPaths []string) ([]MainTarget, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	affected := make(map[string]bool)
+	for _, fileAbsPath := range fileAbsPaths {
+		mains, err := g.affectedMains(fileAbsPath)
+		if err != nil {
+			return nil, err
+		}
+		for mainPath := range mains {
+			affected[mainPath] = true
+		}
+	}
+
+	mains := make([]string, 0, len(affected))
+	for mainPath := range affected {
+		mains = append(mains, mainPath)
+	}
+	sort.Strings(mains)
+
+	targets := make([]MainTarget, 0, len(mains))
+	for _, mainPath := range mains {
+		targets = append(targets, g.mainTarget(mainPath))
+	}
+	return targets, nil
+}
+
+// FindAffectedMains returns, for each changed file, the sorted main packages
+// that depend on it transitively (a main's own files affect the main itself).
+// Entries are file names (e.g. "module1.go", matched in every package holding
+// a file of that name) or paths, absolute or relative to rootDir as printed by
+// git diff, resolved to their exact package. The closure of every main is
+// computed once for the whole change set. A module file path (go.mod, go.sum,
+// go.work) maps to the mains building packages it governs. Other files
+// outside any package map to an empty slice.
+func (g *GoDepFind) FindAffectedMains(fileNames []string) (map[string][]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Single pass over the graph: package -> mains whose closure includes it
+	mainsOf := make(map[string][]string)
+	for _, mainPath := range g.mainPackages {
+		mainsOf[mainPath] = append(mainsOf[mainPath], mainPath)
+		for _, dep := range g.transitiveDeps(mainPath) {
+			mainsOf[dep] = append(mainsOf[dep], mainPath)
+		}
+	}
+
+	result := make(map[string][]string, len(fileNames))
+	for _, fileName := range fileNames {
+		seen := make(map[string]bool)
+		mains := []string{}
+		if filepath.Base(fileName) != fileName && isModuleFile(fileName) {
+			if absPath, err := filepath.Abs(g.rootPath(fileName)); err == nil {
+				for _, mainPath := range g.mainPackages {
+					if g.moduleFileAffectsMain(absPath, mainPath) {
+						mains = append(mains, mainPath)
+					}
+				}
+			}
+		}
+		for _, pkgPath := range g.changedFilePackages(fileName) {
+			for _, mainPath := range mainsOf[pkgPath] {
+				if !seen[mainPath] {
+					seen[mainPath] = true
+					mains = append(mains, mainPath)
+				}
+			}
+		}
+		sort.Strings(mains)
+		result[fileName] = mains
+	}
+	return result, nil
+}
+
+// changedFilePackages returns the packages a changed file belongs to: every
+// package with a file of that name for a bare file name, the exact package
+// for a path (falling back to the name when the path isn't cached)
+func (g *GoDepFind) changedFilePackages(fileName string) []string {
+	if filepath.Base(fileName) == fileName {
+		return g.fileToPackages[g.pathKey(fileName)]
+	}
+	fileAbsPath := g.rootPath(fileName)
+	absPath, err := filepath.Abs(fileAbsPath)
+	if err != nil {
+		return nil
+	}
+	if pkgPath, exists := g.lookupFilePath(absPath); exists {
+		return []string{pkgPath}
+	}
+	if g.isExcludedByConstraints(absPath) {
+		return nil
+	}
+	return g.fileToPackages[g.pathKey(filepath.Base(absPath))]
+}
@@ -0,0 +1,470 @@
+package godepfind
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// overlappingMainsFiles returns a module where mains a and b share a package,
+// c and d share another, and e depends on nothing first-party
+func overlappingMainsFiles() map[string]string {
+	return map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"cmd/a/main.go": `package main
+
+import (
+	"testmod/shared"
+	"testmod/x"
+)
+
+func main() { shared.Do(); x.Do() }
+`,
+		"cmd/b/main.go": `package main
+
+import "testmod/shared"
+
+func main() { shared.Do() }
+`,
+		"cmd/c/main.go": `package main
+
+import "testmod/y"
+
+func main() { y.Do() }
+`,
+		"cmd/d/main.go": `package main
+
+import "testmod/z"
+
+func main() { z.Do() }
+`,
+		"cmd/e/main.go": `package main
+
+func main() {}
+`,
+		"shared/shared.go": "package shared\n\nfunc Do() {}\n",
+		"x/x.go":           "package x\n\nfunc Do() {}\n",
+		"y/y.go":           "package y\n\nfunc Do() {}\n",
+		"z/z.go": `package z
+
+import "testmod/y"
+
+func Do() { y.Do() }
+`,
+	}
+}
+
+func importPaths(targets []MainTarget) []string {
+	paths := make([]string, 0, len(targets))
+	for _, target := range targets {
+		paths = append(paths, target.ImportPath)
+	}
+	return paths
+}
+
+func TestMainsSharingDependency(t *testing.T) {
+	finder := New(writeTestModule(t, overlappingMainsFiles()))
+
+	mains, err := finder.MainsSharingDependency("testmod/shared")
+	if err != nil {
+		t.Fatalf("MainsSharingDependency failed: %v", err)
+	}
+	got := importPaths(mains)
+	if len(got) != 2 || got[0] != "testmod/cmd/a" || got[1] != "testmod/cmd/b" {
+		t.Errorf("expected [testmod/cmd/a testmod/cmd/b], got %v", got)
+	}
+	if mains[0].Dir == "" {
+		t.Error("expected MainTarget.Dir to be populated")
+	}
+
+	// y is reached directly by c and transitively (via z) by d
+	mains, err = finder.MainsSharingDependency("testmod/y")
+	if err != nil {
+		t.Fatalf("MainsSharingDependency failed: %v", err)
+	}
+	if got := importPaths(mains); len(got) != 2 || got[0] != "testmod/cmd/c" || got[1] != "testmod/cmd/d" {
+		t.Errorf("expected [testmod/cmd/c testmod/cmd/d], got %v", got)
+	}
+}
+
+func TestDependencyClusters(t *testing.T) {
+	finder := New(writeTestModule(t, overlappingMainsFiles()))
+
+	clusters, err := finder.DependencyClusters()
+	if err != nil {
+		t.Fatalf("DependencyClusters failed: %v", err)
+	}
+
+	expected := [][]string{
+		{"testmod/cmd/a", "testmod/cmd/b"},
+		{"testmod/cmd/c", "testmod/cmd/d"},
+		{"testmod/cmd/e"},
+	}
+	if len(clusters) != len(expected) {
+		t.Fatalf("expected %d clusters, got %d: %v", len(expected), len(clusters), clusters)
+	}
+	for i, cluster := range clusters {
+		got := importPaths(cluster)
+		if len(got) != len(expected[i]) {
+			t.Errorf("cluster %d: expected %v, got %v", i, expected[i], got)
+			continue
+		}
+		for j := range got {
+			if got[j] != expected[i][j] {
+				t.Errorf("cluster %d: expected %v, got %v", i, expected[i], got)
+				break
+			}
+		}
+	}
+}
+
+func TestUnaffectedMains(t *testing.T) {
+	root := writeTestModule(t, overlappingMainsFiles())
+	finder := New(root)
+
+	tests := []struct {
+		file     string
+		expected []string
+	}{
+		{"y/y.go", []string{"testmod/cmd/a", "testmod/cmd/b", "testmod/cmd/e"}},
+		{"shared/shared.go", []string{"testmod/cmd/c", "testmod/cmd/d", "testmod/cmd/e"}},
+		{"cmd/e/main.go", []string{"testmod/cmd/a", "testmod/cmd/b", "testmod/cmd/c", "testmod/cmd/d"}},
+	}
+	for _, tt := range tests {
+		unaffected, err := finder.UnaffectedMains(filepath.Join(root, tt.file))
+		if err != nil {
+			t.Fatalf("UnaffectedMains(%s) failed: %v", tt.file, err)
+		}
+		if got := importPaths(unaffected); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("UnaffectedMains(%s) = %v, want %v", tt.file, got, tt.expected)
+		}
+	}
+}
+
+func TestMainDirectImporters(t *testing.T) {
+	root := writeTestModule(t, overlappingMainsFiles())
+	finder := New(root)
+
+	// c imports y directly, d only through z
+	direct, err := finder.MainDirectImporters("testmod/y")
+	if err != nil {
+		t.Fatalf("MainDirectImporters failed: %v", err)
+	}
+	if want := []string{"testmod/cmd/c"}; !reflect.DeepEqual(direct, want) {
+		t.Errorf("MainDirectImporters = %v, want %v", direct, want)
+	}
+
+	transitive, err := finder.MainsSharingDependency("testmod/y")
+	if err != nil {
+		t.Fatalf("MainsSharingDependency failed: %v", err)
+	}
+	if want := []string{"testmod/cmd/c", "testmod/cmd/d"}; !reflect.DeepEqual(importPaths(transitive), want) {
+		t.Errorf("MainsSharingDependency = %v, want %v", importPaths(transitive), want)
+	}
+}
+
+func TestRebuildPlan(t *testing.T) {
+	root := writeTestModule(t, overlappingMainsFiles())
+	finder := New(root)
+
+	plan, err := finder.RebuildPlan([]string{
+		filepath.Join(root, "y", "y.go"),
+		filepath.Join(root, "x", "x.go"),
+		filepath.Join(root, "z", "z.go"),
+	})
+	if err != nil {
+		t.Fatalf("RebuildPlan failed: %v", err)
+	}
+	if want := []string{"testmod/cmd/a", "testmod/cmd/c", "testmod/cmd/d"}; !reflect.DeepEqual(importPaths(plan), want) {
+		t.Errorf("RebuildPlan = %v, want %v", importPaths(plan), want)
+	}
+}
+
+func TestFindAffectedMains(t *testing.T) {
+	finder := New("testproject")
+
+	module1Abs, err := filepath.Abs(filepath.Join("testproject", "modules", "module1", "module1.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	affected, err := finder.FindAffectedMains([]string{
+		"module1.go",
+		"modules/module3/module3.go",
+		module1Abs,
+		"appBcmd/main.go",
+		"missing.go",
+	})
+	if err != nil {
+		t.Fatalf("FindAffectedMains failed: %v", err)
+	}
+
+	expected := map[string][]string{
+		"module1.go":                 {"testproject/appAserver", "testproject/appBcmd"},
+		"modules/module3/module3.go": {"testproject/appCwasm"},
+		module1Abs:                   {"testproject/appAserver", "testproject/appBcmd"},
+		"appBcmd/main.go":            {"testproject/appBcmd"},
+		"missing.go":                 {},
+	}
+	if !reflect.DeepEqual(affected, expected) {
+		t.Errorf("FindAffectedMains = %v, want %v", affected, expected)
+	}
+}
+
+func TestListMainPackages(t *testing.T) {
+	root := writeTestModule(t, overlappingMainsFiles())
+	finder := New(root)
+
+	mains, err := finder.ListMainPackages()
+	if err != nil {
+		t.Fatalf("ListMainPackages failed: %v", err)
+	}
+	expected := []string{"testmod/cmd/a", "testmod/cmd/b", "testmod/cmd/c", "testmod/cmd/d", "testmod/cmd/e"}
+	if !reflect.DeepEqual(mains, expected) {
+		t.Fatalf("expected %v, got %v", expected, mains)
+	}
+
+	// The result is a copy: mutating it or changing the tree leaves it alone
+	mains[0] = "mutated"
+	again, err := finder.ListMainPackages()
+	if err != nil {
+		t.Fatalf("ListMainPackages failed: %v", err)
+	}
+	if !reflect.DeepEqual(again, expected) {
+		t.Errorf("mutating the result changed the cache: %v", again)
+	}
+	if err := finder.updateCacheForFile(filepath.Join(root, "cmd", "e", "main.go"), EventRemove); err != nil {
+		t.Fatalf("remove event failed: %v", err)
+	}
+	if !reflect.DeepEqual(again, expected) {
+		t.Errorf("a later event changed a returned slice: %v", again)
+	}
+}
+
+func TestIsMainPackageDir(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+
+	cases := []struct {
+		dir     string
+		isMain  bool
+		pkgPath string
+	}{
+		{"app", true, "testmod/app"},
+		{"./app/", true, "testmod/app"},
+		{filepath.Join(root, "other"), true, "testmod/other"},
+		{"mid", false, ""},      // a library package
+		{"internal", false, ""}, // no package
+		{"does/not/exist", false, ""},
+	}
+	for _, c := range cases {
+		isMain, pkgPath, err := finder.IsMainPackageDir(c.dir)
+		if err != nil {
+			t.Fatalf("IsMainPackageDir(%q) failed: %v", c.dir, err)
+		}
+		if isMain != c.isMain || pkgPath != c.pkgPath {
+			t.Errorf("IsMainPackageDir(%q) = %v, %q; expected %v, %q", c.dir, isMain, pkgPath, c.isMain, c.pkgPath)
+		}
+	}
+	if _, _, err := finder.IsMainPackageDir(""); err == nil {
+		t.Error("expected an error for an empty directory")
+	}
+}
+
+func TestCreateAndRemoveUpdateMainPackages(t *testing.T) {
+	root := writeTestModule(t, overlappingMainsFiles())
+	finder := New(root)
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+
+	// A new command is registered on its create event, without a rebuild
+	newMain := filepath.Join(root, "cmd", "f", "main.go")
+	if err := os.MkdirAll(filepath.Dir(newMain), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newMain, []byte("package main\n\nimport \"testmod/x\"\n\nfunc main() { x.Do() }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.updateCacheForFile(newMain, EventCreate); err != nil {
+		t.Fatalf("create event failed: %v", err)
+	}
+	if !finder.isMainPackage("testmod/cmd/f") {
+		t.Fatalf("expected testmod/cmd/f registered as a main, got %v", finder.mainPackages)
+	}
+	isMine, err := finder.ThisFileIsMine("cmd/f/main.go", filepath.Join(root, "x", "x.go"), EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !isMine {
+		t.Error("expected the new command to own x.go")
+	}
+	if !finder.cachedModule {
+		t.Error("expected the create event not to invalidate the whole cache")
+	}
+
+	// Removing its only file unregisters it
+	if err := os.Remove(newMain); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.updateCacheForFile(newMain, EventRemove); err != nil {
+		t.Fatalf("remove event failed: %v", err)
+	}
+	if finder.isMainPackage("testmod/cmd/f") {
+		t.Errorf("expected testmod/cmd/f unregistered, got %v", finder.mainPackages)
+	}
+}
+
+func TestMainImportsPackageWithinDepth(t *testing.T) {
+	root := writeTestModule(t, overlappingMainsFiles())
+	finder := New(root)
+
+	tests := []struct {
+		main, target string
+		depth        int
+		want         bool
+	}{
+		{"testmod/cmd/d", "testmod/cmd/d", 0, true},
+		{"testmod/cmd/d", "testmod/z", 0, false},
+		{"testmod/cmd/d", "testmod/z", 1, true},
+		{"testmod/cmd/d", "testmod/y", 1, false},
+		{"testmod/cmd/d", "testmod/y", 2, true},
+		{"testmod/cmd/d", "testmod/y", 10, true},
+		{"testmod/cmd/d", "testmod/x", 10, false},
+	}
+	for _, tt := range tests {
+		got, err := finder.MainImportsPackageWithinDepth(tt.main, tt.target, tt.depth)
+		if err != nil {
+			t.Fatalf("MainImportsPackageWithinDepth failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("MainImportsPackageWithinDepth(%s, %s, %d) = %v, want %v", tt.main, tt.target, tt.depth, got, tt.want)
+		}
+	}
+
+	if _, err := finder.MainImportsPackageWithinDepth("testmod/cmd/d", "testmod/z", -1); err == nil {
+		t.Error("expected an error for a negative depth")
+	}
+}
+
+func TestFilesForMain(t *testing.T) {
+	files := chainModuleFiles()
+	files["mid/helper.go"] = "package mid\n\nimport \"fmt\"\n\nfunc helper() { fmt.Println() }\n"
+	files["mid/mid_test.go"] = "package mid\n\nimport \"testing\"\n\nfunc TestDo(t *testing.T) { Do() }\n"
+	root := writeTestModule(t, files)
+	finder := New(root)
+
+	abs := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+	want := []string{abs("app/main.go"), abs("internal/leaf/leaf.go"), abs("mid/helper.go"), abs("mid/mid.go")}
+
+	got, err := finder.FilesForMain("app/main.go")
+	if err != nil {
+		t.Fatalf("FilesForMain failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilesForMain(app/main.go) = %v, want %v", got, want)
+	}
+
+	// A handler identified by the import path of its main package
+	got, err = finder.FilesForMain("testmod/app")
+	if err != nil {
+		t.Fatalf("FilesForMain by import path failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilesForMain(testmod/app) = %v, want %v", got, want)
+	}
+
+	got, err = finder.FilesForMain("other/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{abs("other/main.go")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilesForMain(other/main.go) = %v, want %v", got, want)
+	}
+
+	if _, err := finder.FilesForMain("mid/mid.go"); err == nil {
+		t.Error("expected error for a file outside a main package")
+	}
+	if _, err := finder.FilesForMain("missing/main.go"); err == nil {
+		t.Error("expected error for a missing handler main file")
+	}
+
+	finder.SetTestImports(true)
+	got, err = finder.FilesForMain("app/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(got, abs("mid/mid_test.go")) {
+		t.Errorf("FilesForMain with test imports lacks mid_test.go: %v", got)
+	}
+}
+
+func TestFindCommonDependencies(t *testing.T) {
+	finder := New(writeTestModule(t, overlappingMainsFiles()))
+
+	tests := []struct {
+		mains []string
+		want  []string
+	}{
+		{[]string{"testmod/cmd/a", "testmod/cmd/b"}, []string{"testmod/shared"}},
+		{[]string{"testmod/cmd/c", "testmod/cmd/d"}, []string{"testmod/y"}},
+		{[]string{"testmod/cmd/d", "testmod/cmd/d"}, []string{"testmod/y", "testmod/z"}},
+		{[]string{"testmod/cmd/a", "testmod/cmd/c"}, []string{}},
+		{[]string{"testmod/cmd/a", "testmod/cmd/e"}, []string{}},
+		{nil, []string{}},
+	}
+	for _, tt := range tests {
+		common, err := finder.FindCommonDependencies(tt.mains)
+		if err != nil {
+			t.Fatalf("FindCommonDependencies(%v) failed: %v", tt.mains, err)
+		}
+		if !reflect.DeepEqual(common, tt.want) {
+			t.Errorf("FindCommonDependencies(%v) = %v, want %v", tt.mains, common, tt.want)
+		}
+	}
+
+	if _, err := finder.FindCommonDependencies([]string{"testmod/cmd/a", "testmod/shared"}); err == nil {
+		t.Error("expected an error for a package that isn't a main")
+	}
+}
+
+func TestMainAtRootDir(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":     "module example.com/tool\n\ngo 1.21\n",
+		"main.go":    "package main\n\nimport \"example.com/tool/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go": "package lib\n\nfunc Do() {}\n",
+		"other/o.go": "package other\n",
+	})
+	check := func(t *testing.T, finder *GoDepFind, rootDir string) {
+		t.Helper()
+		mains, err := finder.ListMainPackages()
+		if err != nil {
+			t.Fatalf("ListMainPackages failed: %v", err)
+		}
+		if want := []string{"example.com/tool"}; !reflect.DeepEqual(mains, want) {
+			t.Errorf("expected the module root main %v, got %v", want, mains)
+		}
+		for file, want := range map[string]bool{"main.go": true, "lib/lib.go": true, "other/o.go": false} {
+			mine, err := finder.ThisFileIsMine("main.go", filepath.Join(rootDir, filepath.FromSlash(file)), EventWrite)
+			if err != nil {
+				t.Fatalf("ThisFileIsMine(%s) failed: %v", file, err)
+			}
+			if mine != want {
+				t.Errorf("ThisFileIsMine(%s) = %v, want %v", file, mine, want)
+			}
+		}
+	}
+
+	for _, jsonListing := range []bool{true, false} {
+		t.Run(fmt.Sprintf("json=%v", jsonListing), func(t *testing.T) {
+			finder := New(root)
+			finder.SetPerPackageImport(!jsonListing)
+			check(t, finder, root)
+		})
+	}
+	t.Run("relative", func(t *testing.T) {
+		t.Chdir(root)
+		check(t, New("."), root)
+	})
+}
@@ -0,0 +1,63 @@
+package godepfind
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteMainManifest(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+
+	var first, second bytes.Buffer
+	if err := finder.WriteMainManifest("testmod/app", &first); err != nil {
+		t.Fatalf("WriteMainManifest failed: %v", err)
+	}
+	if err := finder.WriteMainManifest("testmod/app", &second); err != nil {
+		t.Fatalf("WriteMainManifest failed: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("manifest is not stable:\n%s\nvs\n%s", first.String(), second.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(first.String()), "\n")
+	wantPrefixes := []string{
+		"testmod/app",
+		"\tapp/main.go sha256:",
+		"testmod/internal/leaf",
+		"\tinternal/leaf/leaf.go sha256:",
+		"testmod/mid",
+		"\tmid/mid.go sha256:",
+	}
+	if len(lines) != len(wantPrefixes) {
+		t.Fatalf("unexpected manifest:\n%s", first.String())
+	}
+	for i, prefix := range wantPrefixes {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("line %d = %q, want prefix %q", i, lines[i], prefix)
+		}
+	}
+
+	// Editing a dependency changes only that file's hash
+	leafPath := filepath.Join(root, "internal", "leaf", "leaf.go")
+	if err := os.WriteFile(leafPath, []byte("package leaf\n\nfunc Do() { println() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var changed bytes.Buffer
+	if err := finder.WriteMainManifest("testmod/app", &changed); err != nil {
+		t.Fatalf("WriteMainManifest failed: %v", err)
+	}
+	changedLines := strings.Split(strings.TrimSpace(changed.String()), "\n")
+	for i := range lines {
+		if differs := lines[i] != changedLines[i]; differs != (i == 3) {
+			t.Errorf("line %d changed = %v, want %v: %q -> %q", i, differs, i == 3, lines[i], changedLines[i])
+		}
+	}
+
+	if err := finder.WriteMainManifest("testmod/mid", &changed); err == nil {
+		t.Error("expected error for a non-main package")
+	}
+}
@@ -0,0 +1,58 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// isModuleFile reports whether a file is one of the files of the go command
+// declaring modules and their requirements: go.mod, go.sum, go.work and
+// go.work.sum. Any change to them can reshape the package graph.
+func isModuleFile(filePath string) bool {
+	switch filepath.Base(filePath) {
+	case "go.mod", "go.sum", "go.work", "go.work.sum":
+		return true
+	}
+	return false
+}
+
+// moduleFileAffectsMain reports whether the main package mainPkg is built from
+// a package governed by the module file at fileAbsPath: a package of the
+// module declared next to a go.mod or go.sum (nested modules excluded), or any
+// package under the directory of a go.work. It holds even after the file is
+// removed.
+func (g *GoDepFind) moduleFileAffectsMain(fileAbsPath, mainPkg string) bool {
+	if mainPkg == "" {
+		return false
+	}
+	moduleDir := filepath.Dir(fileAbsPath)
+	workspace := strings.HasPrefix(filepath.Base(fileAbsPath), "go.work")
+	for _, pkgPath := range append([]string{mainPkg}, g.transitiveDeps(mainPkg)...) {
+		pkg := g.packageCache[pkgPath]
+		if pkg == nil {
+			pkg = g.externalPackages[pkgPath]
+		}
+		if pkg == nil || pkg.Goroot {
+			continue
+		}
+		pkgDir, err := filepath.Abs(pkg.Dir)
+		if err != nil {
+			continue
+		}
+		if _, inside := relativeToDir(moduleDir, pkgDir); !inside {
+			continue
+		}
+		if workspace {
+			return true
+		}
+		// A go.mod between the package and moduleDir declares a nested module
+		goModDir := findGoModDir(pkgDir)
+		if goModDir == "" || goModDir == moduleDir {
+			return true
+		}
+		if _, nested := relativeToDir(moduleDir, goModDir); !nested {
+			return true
+		}
+	}
+	return false
+}
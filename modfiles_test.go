@@ -0,0 +1,83 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGoModEditRebuildsCache(t *testing.T) {
+	files := externalModuleFiles()
+	// The replace first points to a copy of extlib without the inner import
+	files["app/go.mod"] = strings.Replace(files["app/go.mod"], "../extlib", "../extlib_old", 1)
+	files["extlib_old/go.mod"] = "module example.com/extlib\n\ngo 1.21\n"
+	files["extlib_old/extlib.go"] = "package extlib\n\nfunc Name() string { return \"old\" }\n"
+	root := writeTestModule(t, files)
+	goMod := filepath.Join(root, "app", "go.mod")
+	inner := filepath.Join(root, "extlib", "inner", "inner.go")
+
+	finder := New(filepath.Join(root, "app"))
+	finder.SetIncludeExternalModules(true)
+	if mine, err := finder.ThisFileIsMine("cmd/server/main.go", inner, EventWrite); err != nil || mine {
+		t.Fatalf("expected inner.go not to belong to the server before the edit, got %v, %v", mine, err)
+	}
+
+	if err := os.WriteFile(goMod, []byte(externalModuleFiles()["app/go.mod"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mine, err := finder.ThisFileIsMine("cmd/server/main.go", goMod, EventWrite)
+	if err != nil {
+		t.Fatalf("go.mod event failed: %v", err)
+	}
+	if !mine {
+		t.Error("expected the go.mod edit to affect the server")
+	}
+	if mine, err := finder.ThisFileIsMine("cmd/server/main.go", inner, EventWrite); err != nil || !mine {
+		t.Errorf("expected inner.go to belong to the server after the go.mod edit, got %v, %v", mine, err)
+	}
+
+	decision, err := finder.TraceOwnership("cmd/server/main.go", filepath.Join(root, "app", "go.sum"))
+	if err != nil {
+		t.Fatalf("TraceOwnership failed: %v", err)
+	}
+	if decision.Case != CaseModuleFile || !decision.Owned {
+		t.Errorf("expected go.sum to be decided as an owned module file, got %+v", decision)
+	}
+
+	// A go.mod of a replaced module affects the mains building its packages
+	affected, err := finder.FindAffectedMains([]string{filepath.Join(root, "extlib", "go.mod"), "go.mod"})
+	if err != nil {
+		t.Fatalf("FindAffectedMains failed: %v", err)
+	}
+	if want := []string{"testmod/cmd/server"}; !reflect.DeepEqual(affected[filepath.Join(root, "extlib", "go.mod")], want) {
+		t.Errorf("expected extlib/go.mod to affect %v, got %v", want, affected)
+	}
+}
+
+func TestNestedModuleFileDoesNotAffectOuterMain(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":             "module testmod\n\ngo 1.21\n",
+		"app/main.go":        "package main\n\nfunc main() {}\n",
+		"plugin/go.mod":      "module example.com/plugin\n\ngo 1.21\n",
+		"plugin/plugin.go":   "package plugin\n",
+		"plugin/cmd/main.go": "package main\n\nfunc main() {}\n",
+	})
+	finder := New(root)
+
+	mine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "plugin", "go.mod"), EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if mine {
+		t.Error("expected the nested module's go.mod not to affect app")
+	}
+	mine, err = finder.ThisFileIsMine("app/main.go", filepath.Join(root, "go.mod"), EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !mine {
+		t.Error("expected the root go.mod to affect app")
+	}
+}
@@ -0,0 +1,262 @@
+package godepfind
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ModuleMode selects how import paths are resolved to package directories
+type ModuleMode int
+
+const (
+	// ModuleModeAuto uses module mode when a go.mod is found in rootDir or one
+	// of its parents, or a go.work in rootDir, and GOPATH mode otherwise
+	ModuleModeAuto ModuleMode = iota
+	// ModuleModeModule resolves import paths under the module path declared in
+	// go.mod to directories under the module root
+	ModuleModeModule
+	// ModuleModeGOPATH resolves import paths to directories under $GOPATH/src
+	ModuleModeGOPATH
+)
+
+// String returns the name of the module mode
+func (m ModuleMode) String() string {
+	switch m {
+	case ModuleModeAuto:
+		return "auto"
+	case ModuleModeModule:
+		return "module"
+	case ModuleModeGOPATH:
+		return "gopath"
+	}
+	return "unknown"
+}
+
+// SetModuleMode selects the resolution strategy used to list and import
+// packages. Changing the mode invalidates the cache.
+func (g *GoDepFind) SetModuleMode(mode ModuleMode) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.moduleMode = mode
+	g.resolution = nil
+	g.cachedModule = false
+}
+
+// moduleResolution is the resolution strategy detected for the current mode
+type moduleResolution struct {
+	mode       ModuleMode // ModuleModeModule or ModuleModeGOPATH, never Auto
+	moduleRoot string     // directory containing go.mod (module mode)
+	modulePath string     // module path declared in go.mod (module mode)
+	gopath     string     // GOPATH workspace containing rootDir (GOPATH mode)
+
+	workspace []workspaceModule // modules of the go.work in rootDir (module mode)
+	peers     bool              // workspace holds the independent roots of NewMulti
+	replaces  []localReplace    // go.mod replace directives to local directories
+}
+
+// currentResolution returns the resolution computed for the last rebuild, or
+// detects it when no rebuild happened yet
+func (g *GoDepFind) currentResolution() *moduleResolution {
+	if g.resolution != nil {
+		return g.resolution
+	}
+	return g.detectResolution()
+}
+
+// detectResolution determines the resolution strategy for the configured mode
+func (g *GoDepFind) detectResolution() *moduleResolution {
+	rootAbs, err := filepath.Abs(g.rootDir)
+	if err != nil {
+		rootAbs = g.rootDir
+	}
+
+	if g.moduleMode != ModuleModeGOPATH {
+		if len(g.extraRoots) > 0 {
+			if peers := g.detectPeerModules(); len(peers) > 0 {
+				return &moduleResolution{mode: ModuleModeModule, workspace: peers, peers: true}
+			}
+		}
+		if workspace := g.detectWorkspace(rootAbs); len(workspace) > 0 {
+			res := &moduleResolution{mode: ModuleModeModule, workspace: workspace}
+			res.replaces = res.detectLocalReplaces()
+			return res
+		}
+	}
+
+	goModDir := findGoModDir(rootAbs)
+	mode := g.moduleMode
+	if mode == ModuleModeAuto {
+		mode = ModuleModeGOPATH
+		if goModDir != "" {
+			mode = ModuleModeModule
+		}
+	}
+
+	res := &moduleResolution{mode: mode}
+	switch mode {
+	case ModuleModeModule:
+		if goModDir != "" {
+			res.modulePath = readModulePath(filepath.Join(goModDir, "go.mod"))
+			res.moduleRoot = goModDir
+			if goModDir == rootAbs {
+				// Keep the root as given so package directories stay comparable to rootDir
+				res.moduleRoot = g.rootDir
+			}
+			res.replaces = res.detectLocalReplaces()
+		}
+	case ModuleModeGOPATH:
+		res.gopath = gopathFor(rootAbs)
+	}
+	return res
+}
+
+// findGoModDir returns the closest directory from dir upwards containing a
+// go.mod file, or "" when there is none
+func findGoModDir(dir string) string {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readModulePath returns the module path declared in a go.mod file
+func readModulePath(goModPath string) string {
+	file, err := os.Open(goModPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "module"); ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			rest, _, _ = strings.Cut(rest, "//")
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// gopathFor returns the GOPATH workspace containing dir (the parent of its
+// closest "src" ancestor), falling back to the default GOPATH
+func gopathFor(dir string) string {
+	for current := dir; ; {
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		if filepath.Base(current) == "src" {
+			return parent
+		}
+		current = parent
+	}
+	return build.Default.GOPATH
+}
+
+// importPathDir returns the directory of an import path under the resolution
+// strategy, and false when the path is not part of the project (e.g. the
+// standard library or another module)
+func (res *moduleResolution) importPathDir(importPath string) (string, bool) {
+	switch res.mode {
+	case ModuleModeModule:
+		if len(res.workspace) > 0 {
+			return res.workspaceImportPathDir(importPath)
+		}
+		if res.modulePath == "" {
+			return "", false
+		}
+		if importPath == res.modulePath {
+			return res.moduleRoot, true
+		}
+		if rest, ok := strings.CutPrefix(importPath, res.modulePath+"/"); ok {
+			return filepath.Join(res.moduleRoot, filepath.FromSlash(rest)), true
+		}
+	case ModuleModeGOPATH:
+		if res.gopath == "" {
+			return "", false
+		}
+		dir := filepath.Join(res.gopath, "src", filepath.FromSlash(importPath))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// environ returns the go tool environment variables selecting the mode
+func (res *moduleResolution) environ() []string {
+	if res.mode == ModuleModeGOPATH {
+		env := []string{"GO111MODULE=off"}
+		if res.gopath != "" {
+			env = append(env, "GOPATH="+res.gopath)
+		}
+		return env
+	}
+	return []string{"GO111MODULE=on"}
+}
+
+// rootRelativeDir expresses an absolute directory under a root in the same
+// form as that root (relative or absolute), so package directories stay
+// comparable to it
+func (g *GoDepFind) rootRelativeDir(dir string) string {
+	if !filepath.IsAbs(dir) {
+		return dir
+	}
+	if root, rel, inside := g.containingRoot(dir); inside && !filepath.IsAbs(root) {
+		return filepath.Join(root, rel)
+	}
+	return dir
+}
+
+// ModulePath returns the module path declared in the go.mod of the module
+// rootDir belongs to (the "testproject" in "testproject/appAserver"), so
+// callers can convert between import paths and directories. With a go.work
+// it is the workspace module containing rootDir. The value is detected with
+// the module mode and kept until the next rebuild. An error is returned in
+// GOPATH mode or when no module declares rootDir.
+func (g *GoDepFind) ModulePath() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resolution == nil {
+		g.resolution = g.detectResolution()
+	}
+	res := g.resolution
+	if res.mode != ModuleModeModule {
+		return "", fmt.Errorf("no module path for %s: resolving in %s mode", g.rootDir, res.mode)
+	}
+	if len(res.workspace) == 0 {
+		if res.modulePath == "" {
+			return "", fmt.Errorf("no go.mod declaring a module found for %s", g.rootDir)
+		}
+		return res.modulePath, nil
+	}
+
+	rootAbs, err := filepath.Abs(g.rootDir)
+	if err != nil {
+		return "", err
+	}
+	modulePath, bestLen := "", -1
+	for _, module := range res.workspace {
+		if _, inside := relativeToDir(module.dir, rootAbs); !inside || module.modulePath == "" {
+			continue
+		}
+		if dirAbs, err := filepath.Abs(module.dir); err == nil && len(dirAbs) > bestLen {
+			modulePath, bestLen = module.modulePath, len(dirAbs)
+		}
+	}
+	if modulePath == "" {
+		return "", fmt.Errorf("no workspace module contains %s", g.rootDir)
+	}
+	return modulePath, nil
+}
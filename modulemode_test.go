@@ -0,0 +1,123 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// gopathProjectFiles returns a GOPATH-style project (no go.mod) under src/
+func gopathProjectFiles() map[string]string {
+	return map[string]string{
+		"src/example.org/proj/cmd/app/main.go": "package main\n\nimport \"example.org/proj/lib\"\n\nfunc main() { lib.Do() }\n",
+		"src/example.org/proj/lib/lib.go":      "package lib\n\nfunc Do() {}\n",
+	}
+}
+
+// moduleProjectFiles returns a module whose path has several elements
+func moduleProjectFiles() map[string]string {
+	return map[string]string{
+		"go.mod":           "module example.com/team/proj\n\ngo 1.21\n",
+		"cmd/app/main.go":  "package main\n\nimport \"example.com/team/proj/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":       "package lib\n\nfunc Do() {}\n",
+		"lib/lib_extra.go": "package lib\n",
+	}
+}
+
+// assertResolution checks that every package resolved to its own directory
+// and that lib.go belongs to the app handler
+func assertResolution(t *testing.T, finder *GoDepFind, projectDir, libPath string) {
+	t.Helper()
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+
+	lib := finder.packageCache[libPath]
+	if lib == nil {
+		t.Fatalf("expected %s in cache, got %v", libPath, finder.packageCache)
+	}
+	if got, want := lib.Dir, filepath.Join(projectDir, "lib"); got != want {
+		t.Errorf("%s resolved to %s, want %s", libPath, got, want)
+	}
+
+	isMine, err := finder.ThisFileIsMine("cmd/app/main.go", filepath.Join(projectDir, "lib", "lib.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !isMine {
+		t.Error("expected lib.go to belong to cmd/app")
+	}
+}
+
+func TestModuleModeModuleProject(t *testing.T) {
+	root := writeTestModule(t, moduleProjectFiles())
+
+	for _, mode := range []ModuleMode{ModuleModeAuto, ModuleModeModule} {
+		t.Run(mode.String(), func(t *testing.T) {
+			finder := New(root)
+			finder.SetModuleMode(mode)
+			assertResolution(t, finder, root, "example.com/team/proj/lib")
+			if got := finder.currentResolution().mode; got != ModuleModeModule {
+				t.Errorf("resolved mode = %v, want module", got)
+			}
+		})
+	}
+}
+
+func TestModuleModeGOPATHProject(t *testing.T) {
+	gopath := writeTestModule(t, gopathProjectFiles())
+	projectDir := filepath.Join(gopath, "src", "example.org", "proj")
+
+	for _, mode := range []ModuleMode{ModuleModeAuto, ModuleModeGOPATH} {
+		t.Run(mode.String(), func(t *testing.T) {
+			finder := New(projectDir)
+			finder.SetModuleMode(mode)
+			assertResolution(t, finder, projectDir, "example.org/proj/lib")
+			if got := finder.currentResolution().gopath; got != gopath {
+				t.Errorf("GOPATH = %s, want %s", got, gopath)
+			}
+		})
+	}
+}
+
+func TestReadModulePath(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod": "// comment\nmodule \"example.com/quoted\" // trailing\n\ngo 1.21\n",
+	})
+	if got := readModulePath(filepath.Join(root, "go.mod")); got != "example.com/quoted" {
+		t.Errorf("readModulePath = %q, want example.com/quoted", got)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	root := writeTestModule(t, moduleProjectFiles())
+	for _, dir := range []string{root, filepath.Join(root, "cmd", "app")} {
+		finder := New(dir)
+		if got, err := finder.ModulePath(); err != nil || got != "example.com/team/proj" {
+			t.Errorf("ModulePath for %s = %q, %v; want example.com/team/proj", dir, got, err)
+		}
+	}
+
+	// The value is kept until the next rebuild
+	finder := New(root)
+	if _, err := finder.ModulePath(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/renamed\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := finder.ModulePath(); got != "example.com/team/proj" {
+		t.Errorf("expected the cached module path before a rebuild, got %q", got)
+	}
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := finder.ModulePath(); got != "example.com/renamed" {
+		t.Errorf("expected the new module path after a rebuild, got %q", got)
+	}
+
+	gopath := writeTestModule(t, gopathProjectFiles())
+	if got, err := New(filepath.Join(gopath, "src", "example.org", "proj")).ModulePath(); err == nil {
+		t.Errorf("expected an error in GOPATH mode, got %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package godepfind
+
+import (
+	"context"
+	"go/build"
+	"path/filepath"
+)
+
+// moduleRootAbove returns the absolute module root when rootDir is a
+// subdirectory of the module rather than the directory holding go.mod, and ""
+// otherwise (root of the module, workspaces, GOPATH mode)
+func (g *GoDepFind) moduleRootAbove() string {
+	res := g.currentResolution()
+	if res.mode != ModuleModeModule || len(res.workspace) > 0 || res.moduleRoot == "" {
+		return ""
+	}
+	moduleRoot, err := filepath.Abs(res.moduleRoot)
+	if err != nil {
+		return ""
+	}
+	if rootAbs, err := filepath.Abs(g.rootDir); err != nil || rootAbs == moduleRoot {
+		return ""
+	}
+	return moduleRoot
+}
+
+// inModuleAboveRoot reports whether an absolute path outside rootDir belongs
+// to the module rootDir is part of (see moduleRootAbove), nested modules
+// excluded
+func (g *GoDepFind) inModuleAboveRoot(absPath string) bool {
+	moduleRoot := g.moduleRootAbove()
+	if moduleRoot == "" {
+		return false
+	}
+	if _, inside := relativeToDir(moduleRoot, absPath); !inside {
+		return false
+	}
+	return findGoModDir(filepath.Dir(absPath)) == moduleRoot
+}
+
+// loadModulePackagesAboveRoot adds to packages the packages of the module
+// outside rootDir that the listed packages import, transitively, when rootDir
+// is a subdirectory of the module. rootDir stays the scan scope (only its
+// packages are listed, so only its mains are reported), but the module
+// packages its mains build are part of the graph like the listed ones.
+func (g *GoDepFind) loadModulePackagesAboveRoot(ctx context.Context, packages map[string]*build.Package, ignored map[string]bool) {
+	if g.moduleRootAbove() == "" {
+		return
+	}
+	res := g.currentResolution()
+	var queue []string
+	seen := make(map[string]bool)
+	enqueue := func(pkg *build.Package) {
+		imports := append(append([]string{}, pkg.Imports...), g.enabledTestImports(pkg)...)
+		for _, imp := range imports {
+			if _, local := packages[imp]; local || ignored[imp] || seen[imp] {
+				continue
+			}
+			seen[imp] = true
+			if _, inModule := res.importPathDir(imp); inModule {
+				queue = append(queue, imp)
+			}
+		}
+	}
+	for _, pkg := range packages {
+		if pkg != nil {
+			enqueue(pkg)
+		}
+	}
+	for len(queue) > 0 && ctx.Err() == nil {
+		next := queue[0]
+		queue = queue[1:]
+		pkg, err := g.importPackage(next)
+		if err != nil || pkg.Goroot {
+			continue
+		}
+		if g.ignoredPackage(pkg) {
+			ignored[next] = true
+			continue
+		}
+		packages[next] = pkg
+		enqueue(pkg)
+	}
+}
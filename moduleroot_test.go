@@ -0,0 +1,51 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRootDirBelowModuleRoot(t *testing.T) {
+	finder := New(filepath.Join("testproject", "appAserver"))
+
+	mains, err := finder.ListMainPackages()
+	if err != nil {
+		t.Fatalf("ListMainPackages failed: %v", err)
+	}
+	if want := []string{"testproject/appAserver"}; !reflect.DeepEqual(mains, want) {
+		t.Errorf("expected the scan to stay in rootDir with mains %v, got %v", want, mains)
+	}
+
+	// Module packages imported from rootDir are resolved through the module root
+	module1, err := filepath.Abs(filepath.Join("testproject", "modules", "module1", "module1.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg, err := finder.PackageForFile(module1); err != nil || pkg != "testproject/modules/module1" {
+		t.Errorf("expected module1.go in testproject/modules/module1, got %q, %v", pkg, err)
+	}
+	mine, err := finder.ThisFileIsMine("main.go", module1, EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !mine {
+		t.Error("expected module1.go outside rootDir to belong to appAserver")
+	}
+	if from, err := finder.GoFileComesFromMain("module1.go"); err != nil || !reflect.DeepEqual(from, []string{"testproject/appAserver"}) {
+		t.Errorf("expected module1.go to come from appAserver, got %v, %v", from, err)
+	}
+
+	// Module packages not imported from rootDir are out of scope
+	module3, err := filepath.Abs(filepath.Join("testproject", "modules", "module3", "module3.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mine, err = finder.ThisFileIsMine("main.go", module3, EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if mine {
+		t.Error("expected module3.go not to belong to appAserver")
+	}
+}
@@ -0,0 +1,140 @@
+package godepfind
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SetIncludeExternalModules enables or disables loading the packages of
+// external modules imported by the module. When enabled, third-party packages
+// and their own imports become part of the dependency graph, so closures reach
+// past the module boundary. Changing the setting invalidates the cache.
+func (g *GoDepFind) SetIncludeExternalModules(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.includeExternal = enabled
+	g.cachedModule = false
+}
+
+// SetExcludeStdlib enables or disables leaving standard library imports
+// ("fmt", "net/http", ...) out of the dependency graph and the reverse
+// dependencies, which otherwise hold an entry for every standard package the
+// module imports. It is disabled by default. Changing the setting invalidates
+// the cache.
+func (g *GoDepFind) SetExcludeStdlib(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.excludeStdlib = enabled
+	g.cachedModule = false
+}
+
+// skipsStdlibImport reports whether imp is left out of the graph as a
+// standard library package. Packages of the module or loaded from external
+// modules are never skipped, even when their path has no dot (e.g. a module
+// named "app").
+func (g *GoDepFind) skipsStdlibImport(imp string, packages ...map[string]*build.Package) bool {
+	if !g.excludeStdlib || !isStandardImportPath(imp) {
+		return false
+	}
+	for _, known := range packages {
+		if pkg := known[imp]; pkg != nil && !pkg.Goroot {
+			return false
+		}
+	}
+	return true
+}
+
+// loadExternalPackages imports, transitively, every non-standard package
+// imported by packages that is not part of them nor ignored. Packages that
+// cannot be resolved are skipped.
+func (g *GoDepFind) loadExternalPackages(ctx context.Context, packages map[string]*build.Package, ignored map[string]bool) map[string]*build.Package {
+	buildCtx := g.buildContext()
+	// go/build resolves module imports from the working directory, not srcDir
+	if rootAbs, err := filepath.Abs(g.rootDir); err == nil {
+		buildCtx.Dir = rootAbs
+	}
+	external := make(map[string]*build.Package)
+	seen := make(map[string]bool)
+
+	type pending struct{ path, srcDir string }
+	var queue []pending
+	enqueue := func(pkg *build.Package) {
+		srcDir := pkg.Dir
+		if abs, err := filepath.Abs(srcDir); err == nil {
+			srcDir = abs
+		}
+		for _, imp := range pkg.Imports {
+			if _, local := packages[imp]; local || ignored[imp] || seen[imp] || isStandardImportPath(imp) {
+				continue
+			}
+			seen[imp] = true
+			queue = append(queue, pending{imp, srcDir})
+		}
+	}
+	for _, pkg := range packages {
+		if pkg != nil {
+			enqueue(pkg)
+		}
+	}
+	for len(queue) > 0 && ctx.Err() == nil {
+		next := queue[0]
+		queue = queue[1:]
+		pkg, err := buildCtx.Import(next.path, next.srcDir, 0)
+		if err != nil {
+			continue
+		}
+		external[next.path] = pkg
+		enqueue(pkg)
+	}
+	return external
+}
+
+// isStandardImportPath reports whether an import path belongs to the standard
+// library, i.e. its first element has no dot ("fmt", "net/http")
+func isStandardImportPath(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// DependencyOrigin splits the transitive closure of a main package into
+// first-party packages (whose directory is under the module root) and
+// third-party packages. Standard library packages are not reported. Only the
+// direct third-party imports of the module are visible unless
+// SetIncludeExternalModules is enabled. Both slices are sorted.
+func (g *GoDepFind) DependencyOrigin(mainPkg string) (firstParty, thirdParty []string, err error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+	if !g.isMainPackage(mainPkg) {
+		return nil, nil, fmt.Errorf("not a main package: %s", mainPkg)
+	}
+
+	firstParty = []string{}
+	thirdParty = []string{}
+	for _, dep := range g.transitiveDeps(mainPkg) {
+		pkg := g.packageCache[dep]
+		if pkg == nil {
+			pkg = g.externalPackages[dep]
+		}
+		switch {
+		case pkg != nil && !pkg.Goroot:
+			dir, _ := filepath.Abs(pkg.Dir)
+			if _, inside := g.relativeToRoot(dir); inside {
+				firstParty = append(firstParty, dep)
+			} else {
+				thirdParty = append(thirdParty, dep)
+			}
+		case pkg == nil && !isStandardImportPath(dep):
+			thirdParty = append(thirdParty, dep)
+		}
+	}
+	sort.Strings(firstParty)
+	sort.Strings(thirdParty)
+	return firstParty, thirdParty, nil
+}
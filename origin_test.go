@@ -0,0 +1,89 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// externalModuleFiles returns a module (under app/) that imports an external
+// module (under extlib/) through a local replace directive
+func externalModuleFiles() map[string]string {
+	return map[string]string{
+		"app/go.mod":                  "module testmod\n\ngo 1.21\n\nrequire example.com/extlib v0.0.0\n\nreplace example.com/extlib => ../extlib\n",
+		"app/cmd/server/main.go":      "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/extlib\"\n\t\"testmod/internal/store\"\n)\n\nfunc main() { fmt.Println(extlib.Name(), store.Get()) }\n",
+		"app/internal/store/store.go": "package store\n\nfunc Get() string { return \"store\" }\n",
+		"extlib/go.mod":               "module example.com/extlib\n\ngo 1.21\n",
+		"extlib/extlib.go":            "package extlib\n\nimport \"example.com/extlib/inner\"\n\nfunc Name() string { return inner.Name }\n",
+		"extlib/inner/inner.go":       "package inner\n\nconst Name = \"inner\"\n",
+	}
+}
+
+func TestDependencyOrigin(t *testing.T) {
+	root := writeTestModule(t, externalModuleFiles())
+
+	finder := New(filepath.Join(root, "app"))
+	finder.SetIncludeExternalModules(true)
+
+	firstParty, thirdParty, err := finder.DependencyOrigin("testmod/cmd/server")
+	if err != nil {
+		t.Fatalf("DependencyOrigin failed: %v", err)
+	}
+	if want := []string{"testmod/internal/store"}; !reflect.DeepEqual(firstParty, want) {
+		t.Errorf("firstParty = %v, want %v", firstParty, want)
+	}
+	if want := []string{"example.com/extlib", "example.com/extlib/inner"}; !reflect.DeepEqual(thirdParty, want) {
+		t.Errorf("thirdParty = %v, want %v", thirdParty, want)
+	}
+}
+
+func TestDependencyOriginWithoutExternalModules(t *testing.T) {
+	root := writeTestModule(t, externalModuleFiles())
+
+	finder := New(filepath.Join(root, "app"))
+
+	// Only the direct third-party import is visible without loading external modules
+	_, thirdParty, err := finder.DependencyOrigin("testmod/cmd/server")
+	if err != nil {
+		t.Fatalf("DependencyOrigin failed: %v", err)
+	}
+	if want := []string{"example.com/extlib"}; !reflect.DeepEqual(thirdParty, want) {
+		t.Errorf("thirdParty = %v, want %v", thirdParty, want)
+	}
+
+	if _, _, err := finder.DependencyOrigin("testmod/internal/store"); err == nil {
+		t.Error("expected error for a non-main package")
+	}
+}
+
+func TestExcludeStdlib(t *testing.T) {
+	root := writeTestModule(t, externalModuleFiles())
+
+	finder := New(filepath.Join(root, "app"))
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := finder.reverseDeps["fmt"]; !ok {
+		t.Fatal("expected fmt in reverseDeps by default")
+	}
+
+	finder.SetExcludeStdlib(true)
+	finder.SetIncludeExternalModules(true)
+	if err := finder.rebuildCache(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := finder.reverseDeps["fmt"]; ok {
+		t.Errorf("fmt in reverseDeps with the standard library excluded: %v", finder.reverseDeps["fmt"])
+	}
+	// Module packages without a dot in their path are kept
+	want := []string{"example.com/extlib", "testmod/internal/store"}
+	got := append([]string{}, finder.dependencyGraph["testmod/cmd/server"]...)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencyGraph[server] = %v, want %v", got, want)
+	}
+	if mine, err := finder.ThisFileIsMine("cmd/server/main.go", filepath.Join(root, "app", "internal", "store", "store.go"), EventWrite); err != nil || !mine {
+		t.Errorf("store.go ownership = %v, %v; want true", mine, err)
+	}
+}
@@ -0,0 +1,54 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestThisFileIsMineFileOutsideRoot(t *testing.T) {
+	finder := New("testproject")
+
+	outside := filepath.Join(t.TempDir(), "other.go")
+	if err := os.WriteFile(outside, []byte("package other\n"), 0644); err != nil {
+		t.Fatalf("write outside file: %v", err)
+	}
+
+	isMine, err := finder.ThisFileIsMine("appAserver/main.go", outside, "write")
+	if err != nil {
+		t.Fatalf("expected no error for file outside rootDir, got %v", err)
+	}
+	if isMine {
+		t.Error("expected file outside rootDir not to be owned")
+	}
+}
+
+func TestRelativeInsideRelFailure(t *testing.T) {
+	// filepath.Rel fails for an absolute base and a relative target, as it
+	// does on Windows for different drives
+	base, err := filepath.Abs("testproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := filepath.Rel(base, "modules/module1/module1.go"); err == nil {
+		t.Fatal("expected filepath.Rel to fail for these paths")
+	}
+	if rel, inside := relativeInside(base, "modules/module1/module1.go"); inside || rel != "" {
+		t.Errorf("expected a path that cannot be made relative to be outside, got %q, %v", rel, inside)
+	}
+	if rel, inside := relativeInside(base, filepath.Join(base, "modules", "module1", "module1.go")); !inside || rel != filepath.Join("modules", "module1", "module1.go") {
+		t.Errorf("expected a path under base to be inside, got %q, %v", rel, inside)
+	}
+}
+
+func TestThisFileIsMineDifferentVolumeWindows(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("different volumes only exist on Windows")
+	}
+
+	finder := New(`C:\project`)
+	if _, inside := finder.relativeToRoot(`D:\other\file.go`); inside {
+		t.Error("expected file on another drive to be reported outside rootDir")
+	}
+}
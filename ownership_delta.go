@@ -0,0 +1,83 @@
+package godepfind
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// OwnershipDeltaAfterMainEdit rescans the dependencies of an edited main file
+// and returns the files whose ownership changed for that main: gained lists
+// files now owned (e.g. a new import), lost lists files no longer owned
+// (e.g. a removed import). Paths are absolute and sorted.
+//
+// Call it in place of the "write" event for the main file, since the
+// comparison starts from the cache state before the edit is processed.
+func (g *GoDepFind) OwnershipDeltaAfterMainEdit(mainFileAbsPath string) (gained, lost []string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if mainFileAbsPath == "" {
+		return nil, nil, fmt.Errorf("mainFileAbsPath cannot be empty")
+	}
+	mainFileAbsPath = g.rootPath(mainFileAbsPath)
+	mainFileAbsPath, err = filepath.Abs(mainFileAbsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := g.ensureCacheInitialized(); err != nil {
+		return nil, nil, err
+	}
+
+	before, err := g.filesOwnedByMainFile(mainFileAbsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := g.rescanMainPackageDependencies(mainFileAbsPath); err != nil {
+		return nil, nil, err
+	}
+	after, err := g.filesOwnedByMainFile(mainFileAbsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gained, lost = []string{}, []string{}
+	for file := range after {
+		if !before[file] {
+			gained = append(gained, file)
+		}
+	}
+	for file := range before {
+		if !after[file] {
+			lost = append(lost, file)
+		}
+	}
+	sort.Strings(gained)
+	sort.Strings(lost)
+	return gained, lost, nil
+}
+
+// filesOwnedByMainFile returns the absolute paths of the cached files compiled
+// into the main package containing mainFileAbsPath, based on cached data only
+func (g *GoDepFind) filesOwnedByMainFile(mainFileAbsPath string) (map[string]bool, error) {
+	mainPkg, exists := g.lookupFilePath(mainFileAbsPath)
+	if !exists || !g.isMainPackage(mainPkg) {
+		return nil, fmt.Errorf("file is not part of a cached main package: %s", mainFileAbsPath)
+	}
+
+	packages := map[string]bool{mainPkg: true}
+	for _, dep := range g.transitiveDeps(mainPkg) {
+		packages[dep] = true
+	}
+
+	owned := make(map[string]bool)
+	for file, pkg := range g.filePathToPackage {
+		if !packages[pkg] {
+			continue
+		}
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		owned[file] = true
+	}
+	return owned, nil
+}
@@ -0,0 +1,60 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOwnershipDeltaAfterMainEdit(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+import "testmod/db"
+
+func main() { db.Open() }
+`,
+		"db/db.go":       "package db\n\nfunc Open() {}\n",
+		"db/query.go":    "package db\n\nfunc Query() {}\n",
+		"cache/cache.go": "package cache\n\nfunc Get() {}\n",
+	})
+	finder := New(root)
+	mainPath := filepath.Join(root, "app", "main.go")
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+
+	// Swap the db import for cache
+	edited := `package main
+
+import "testmod/cache"
+
+func main() { cache.Get() }
+`
+	if err := os.WriteFile(mainPath, []byte(edited), 0644); err != nil {
+		t.Fatalf("rewrite main: %v", err)
+	}
+
+	gained, lost, err := finder.OwnershipDeltaAfterMainEdit(mainPath)
+	if err != nil {
+		t.Fatalf("OwnershipDeltaAfterMainEdit failed: %v", err)
+	}
+
+	expectedLost := []string{filepath.Join(root, "db", "db.go"), filepath.Join(root, "db", "query.go")}
+	if len(lost) != 2 || lost[0] != expectedLost[0] || lost[1] != expectedLost[1] {
+		t.Errorf("expected lost %v, got %v", expectedLost, lost)
+	}
+	if len(gained) != 1 || gained[0] != filepath.Join(root, "cache", "cache.go") {
+		t.Errorf("expected gained [cache/cache.go], got %v", gained)
+	}
+
+	// The cache now reflects the edit
+	isMine, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "db", "db.go"), "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if isMine {
+		t.Error("expected db.go to no longer be owned after the edit")
+	}
+}
@@ -0,0 +1,77 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// splitPackage moves a/y.go into a new package b, as when splitting a package
+func splitPackage(t *testing.T, root string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "b", "y.go"), []byte("package b\n\nfunc Y() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "a", "y.go")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileReattributedAfterPackageSplit(t *testing.T) {
+	orders := map[string][]struct {
+		file  string
+		event FileEvent
+	}{
+		// The old package is invalidated by a write before the remove is seen,
+		// so the remove can no longer resolve a/y.go to package a
+		"remove before create": {
+			{"a/x.go", "write"},
+			{"a/y.go", "remove"},
+			{"b/y.go", "create"},
+		},
+		"create before remove": {
+			{"a/x.go", "write"},
+			{"b/y.go", "create"},
+			{"a/y.go", "remove"},
+		},
+		"remove event lost": {
+			{"a/x.go", "write"},
+			{"b/y.go", "create"},
+		},
+	}
+
+	for name, events := range orders {
+		t.Run(name, func(t *testing.T) {
+			root := writeTestModule(t, map[string]string{
+				"go.mod": "module testmod\n\ngo 1.21\n",
+				"a/x.go": "package a\n\nfunc X() {}\n",
+				"a/y.go": "package a\n\nfunc Y() {}\n",
+			})
+			finder := New(root)
+			if err := finder.ensureCacheInitialized(); err != nil {
+				t.Fatalf("cache init failed: %v", err)
+			}
+
+			splitPackage(t, root)
+			for _, ev := range events {
+				if err := finder.updateCacheForFile(filepath.Join(root, ev.file), ev.event); err != nil {
+					t.Fatalf("%s %s failed: %v", ev.event, ev.file, err)
+				}
+			}
+
+			if got, want := finder.fileToPackages["y.go"], []string{"testmod/b"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("fileToPackages[y.go] = %v, want %v", got, want)
+			}
+			if got := finder.filePathToPackage[filepath.Join(root, "b", "y.go")]; got != "testmod/b" {
+				t.Errorf("b/y.go mapped to %q, want testmod/b", got)
+			}
+			if got, want := finder.fileToPackages["x.go"], []string{"testmod/a"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("fileToPackages[x.go] = %v, want %v", got, want)
+			}
+		})
+	}
+}
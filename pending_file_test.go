@@ -0,0 +1,64 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPendingFileRoutesByDirectory(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	// Build the cache before the new file appears, as a watcher would
+	if _, err := finder.ThisFileIsMine("app/main.go", filepath.Join(root, "mid", "mid.go"), EventWrite); err != nil {
+		t.Fatalf("initial query failed: %v", err)
+	}
+
+	// A new file in a package imported by app, created empty
+	newFile := filepath.Join(root, "internal", "leaf", "new.go")
+	if err := os.WriteFile(newFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	owned, err := finder.ThisFileIsMine("app/main.go", newFile, EventCreate)
+	if err != nil {
+		t.Fatalf("create of empty file failed: %v", err)
+	}
+	if !owned {
+		t.Error("expected empty created file to belong to app through its directory")
+	}
+	owned, err = finder.ThisFileIsMine("other/main.go", newFile, EventCreate)
+	if err != nil {
+		t.Fatalf("create of empty file failed: %v", err)
+	}
+	if owned {
+		t.Error("expected empty created file not to belong to other")
+	}
+
+	// A partially written file is still skipped on write
+	owned, err = finder.ThisFileIsMine("app/main.go", newFile, EventWrite)
+	if err != nil {
+		t.Fatalf("write of empty file failed: %v", err)
+	}
+	if owned {
+		t.Error("expected write of empty file to be skipped")
+	}
+
+	// A rename reported for a path that no longer exists resolves the same way
+	gone := filepath.Join(root, "mid", "renamed.go")
+	owned, err = finder.ThisFileIsMine("app/main.go", gone, EventRename)
+	if err != nil {
+		t.Fatalf("rename of missing file failed: %v", err)
+	}
+	if !owned {
+		t.Error("expected renamed file to belong to app through its directory")
+	}
+
+	decision, err := finder.TraceOwnership("app/main.go", newFile)
+	if err != nil {
+		t.Fatalf("TraceOwnership failed: %v", err)
+	}
+	if decision.Case != CaseInvalidGoFile {
+		t.Errorf("expected a query without event to skip the empty file, got %s", decision.Case)
+	}
+}
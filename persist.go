@@ -0,0 +1,235 @@
+package godepfind
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// cacheFileVersion is bumped whenever the layout of cacheFile changes
+const cacheFileVersion = 2
+
+// cacheFile is the on-disk form of the dependency cache written by SaveCache
+type cacheFile struct {
+	Version            int                 `json:"version"`
+	Stamp              string              `json:"stamp"`    // validation stamp of the tree, see cacheStamp
+	Packages           []string            `json:"packages"` // module packages, re-imported on load
+	DependencyGraph    map[string][]string `json:"dependency_graph"`
+	ReverseDeps        map[string][]string `json:"reverse_deps"`
+	FileToPackages     map[string][]string `json:"file_to_packages"`
+	FileToTestPackages map[string][]string `json:"file_to_test_packages"`
+	FilePathToPackage  map[string]string   `json:"file_path_to_package"`
+	MainPackages       []string            `json:"main_packages"`
+	DuplicateFiles     map[string][]string `json:"duplicate_files,omitempty"`
+}
+
+// SaveCache writes the dependency cache to path (JSON), building it first if
+// needed, so a later LoadCache can skip listing the module with go list. The
+// file is stamped with a hash of the go.mod files, the directory modification
+// times and the configuration, and replaced atomically.
+func (g *GoDepFind) SaveCache(path string) error {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stamp, err := g.cacheStamp(path)
+	if err != nil {
+		return fmt.Errorf("cannot stamp cache: %w", err)
+	}
+	data := cacheFile{
+		Version:            cacheFileVersion,
+		Stamp:              stamp,
+		Packages:           make([]string, 0, len(g.packageCache)),
+		DependencyGraph:    g.dependencyGraph,
+		ReverseDeps:        g.reverseDeps,
+		FileToPackages:     g.fileToPackages,
+		FileToTestPackages: g.fileToTestPackages,
+		FilePathToPackage:  g.filePathToPackage,
+		MainPackages:       g.mainPackages,
+		DuplicateFiles:     g.duplicateFiles,
+	}
+	for pkgPath, pkg := range g.packageCache {
+		if pkg != nil {
+			data.Packages = append(data.Packages, pkgPath)
+		}
+	}
+	sort.Strings(data.Packages)
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, encoded, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// LoadCache restores a cache written by SaveCache. The cached packages are
+// re-imported from their directories, but go list is not run. A missing file,
+// a file that cannot be decoded, or one whose stamp doesn't match the current
+// tree (a go.mod changed, a directory gained or lost files, or the
+// configuration differs) is ignored and the cache is rebuilt normally.
+// Changes that keep the directory times, such as an edited import, must be
+// reported as events as usual.
+func (g *GoDepFind) LoadCache(path string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	encoded, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return g.rebuildCache()
+	}
+	if err != nil {
+		return err
+	}
+	var data cacheFile
+	if err := json.Unmarshal(encoded, &data); err != nil || data.Version != cacheFileVersion {
+		return g.rebuildCache()
+	}
+	if stamp, err := g.cacheStamp(path); err != nil || stamp != data.Stamp {
+		return g.rebuildCache()
+	}
+
+	previousResolution := g.resolution
+	g.resolution = g.detectResolution()
+	packages, err := g.getPackagesWithWorkers(context.Background(), data.Packages, g.parallelism())
+	if err != nil {
+		g.resolution = previousResolution
+		return g.rebuildCache()
+	}
+
+	g.packageCache = packages
+	g.invalidateListMemo()
+	g.memoizeListedPackages("./...", packages)
+	g.lineCounts = make(map[string]int)
+	g.importHashes = make(map[string]uint64)
+	g.dependencyGraph = nonNilSliceMap(data.DependencyGraph)
+	g.reverseDeps = nonNilSliceMap(data.ReverseDeps)
+	g.fileToPackages = nonNilSliceMap(data.FileToPackages)
+	g.fileToTestPackages = nonNilSliceMap(data.FileToTestPackages)
+	g.filePathToPackage = data.FilePathToPackage
+	if g.filePathToPackage == nil {
+		g.filePathToPackage = make(map[string]string)
+	}
+	g.mainPackages = append([]string{}, data.MainPackages...)
+	g.duplicateFiles = nonNilSliceMap(data.DuplicateFiles)
+	g.externalPackages = make(map[string]*build.Package)
+	g.updateReachability()
+	if g.autoRefresh {
+		g.recordTreeModTime()
+	}
+	g.cachedModule = true
+	return nil
+}
+
+// nonNilSliceMap returns m, or an empty map when m is nil
+func nonNilSliceMap(m map[string][]string) map[string][]string {
+	if m == nil {
+		return make(map[string][]string)
+	}
+	return m
+}
+
+// cacheStamp hashes what a saved cache depends on: the configuration, the
+// content of the go.mod and go.work files and the modification time of every
+// directory under the roots (which changes when files are added, removed or
+// renamed). Directories ignored by the go tool are skipped. The directory
+// holding the cache file itself is stamped by its entry names other than the
+// cache file, since saving the cache changes its modification time.
+func (g *GoDepFind) cacheStamp(cachePath string) (string, error) {
+	cacheAbs, err := filepath.Abs(cachePath)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, cacheName := filepath.Dir(cacheAbs), filepath.Base(cacheAbs)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "tests=%v xtests=%v tags=%s goos=%s goarch=%s mode=%s external=%v nostdlib=%v embeds=%v casefold=%v ignore=%s\n",
+		g.testImports, g.xtestImports, strings.Join(g.buildTags, ","), g.goos, g.goarch, g.moduleMode,
+		g.includeExternal, g.excludeStdlib, g.trackEmbeds, g.caseFoldPaths, strings.Join(g.ignorePatterns, ","))
+
+	hashFile := func(path string) error {
+		file, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fmt.Fprintf(hash, "file %s\n", path)
+		_, err = io.Copy(hash, file)
+		return err
+	}
+
+	for _, root := range g.roots() {
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "root %s\n", rootAbs)
+		// The module may be declared above the root
+		if goModDir := findGoModDir(rootAbs); goModDir != "" && goModDir != rootAbs {
+			if err := hashFile(filepath.Join(goModDir, "go.mod")); err != nil {
+				return "", err
+			}
+		}
+		err = filepath.WalkDir(rootAbs, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				if name := d.Name(); name == "go.mod" || name == "go.work" {
+					return hashFile(path)
+				}
+				return nil
+			}
+			name := d.Name()
+			if path != rootAbs && (name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			if path == cacheDir {
+				entries, err := os.ReadDir(path)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(hash, "dir %s", path)
+				for _, entry := range entries {
+					if name := entry.Name(); name != cacheName && name != cacheName+".tmp" {
+						fmt.Fprintf(hash, " %s", name)
+					}
+				}
+				fmt.Fprintln(hash)
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "dir %s %d\n", path, info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,90 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveAndLoadCache(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	cachePath := filepath.Join(root, "deps-cache.json")
+
+	saved := New(root)
+	if err := saved.SaveCache(cachePath); err != nil {
+		t.Fatalf("SaveCache failed: %v", err)
+	}
+
+	// A fresh instance restores the cache without running go list
+	loaded := New(root)
+	if err := loaded.LoadCache(cachePath); err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+	if loaded.listCount != 0 {
+		t.Errorf("expected no go list on a valid cache, got %d", loaded.listCount)
+	}
+	if !loaded.cachedModule {
+		t.Fatal("expected the cache to be initialized after LoadCache")
+	}
+	if !reflect.DeepEqual(loaded.dependencyGraph, saved.dependencyGraph) {
+		t.Errorf("dependency graph differs after load:\n got %v\nwant %v", loaded.dependencyGraph, saved.dependencyGraph)
+	}
+	if !reflect.DeepEqual(loaded.filePathToPackage, saved.filePathToPackage) {
+		t.Errorf("file mapping differs after load:\n got %v\nwant %v", loaded.filePathToPackage, saved.filePathToPackage)
+	}
+	isMine, err := loaded.ThisFileIsMine("app/main.go", filepath.Join(root, "internal", "leaf", "leaf.go"), EventWrite)
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !isMine {
+		t.Error("expected app/main.go to own leaf.go from the loaded cache")
+	}
+	if loaded.packageCache["testmod/internal/leaf"] == nil {
+		t.Error("expected the cached packages to be re-imported")
+	}
+
+	// A new package directory invalidates the stamp: the cache is rebuilt
+	if err := os.MkdirAll(filepath.Join(root, "extra"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "extra", "extra.go"), []byte("package extra\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stale := New(root)
+	if err := stale.LoadCache(cachePath); err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+	if stale.listCount == 0 {
+		t.Error("expected a stale cache to be rebuilt with go list")
+	}
+	if _, found := stale.packageCache["testmod/extra"]; !found {
+		t.Error("expected the rebuilt cache to include the new package")
+	}
+
+	// A missing file falls back to a normal rebuild
+	missing := New(root)
+	if err := missing.LoadCache(filepath.Join(t.TempDir(), "none.json")); err != nil {
+		t.Fatalf("LoadCache of a missing file failed: %v", err)
+	}
+	if !missing.cachedModule {
+		t.Error("expected a rebuilt cache when the file is missing")
+	}
+}
+
+func TestLoadCacheIgnoresOtherConfiguration(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	cachePath := filepath.Join(t.TempDir(), "deps-cache.json")
+	if err := New(root).SaveCache(cachePath); err != nil {
+		t.Fatalf("SaveCache failed: %v", err)
+	}
+
+	finder := New(root)
+	finder.SetBuildTags([]string{"integration"})
+	if err := finder.LoadCache(cachePath); err != nil {
+		t.Fatalf("LoadCache failed: %v", err)
+	}
+	if finder.listCount == 0 {
+		t.Error("expected a cache saved with other build tags to be rebuilt")
+	}
+}
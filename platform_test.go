@@ -0,0 +1,73 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func platformModuleFiles() map[string]string {
+	return map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+import "testmod/platform"
+
+func main() { platform.Open() }
+`,
+		"platform/open.go": `package platform
+
+func Open() { open() }
+`,
+		"platform/open_linux.go": `package platform
+
+func open() {}
+`,
+		"platform/open_windows.go": `package platform
+
+func open() {}
+`,
+		// Another package sharing the basename must not affect routing
+		"winsvc/open_windows.go": `package winsvc
+
+func Service() {}
+`,
+	}
+}
+
+func TestPlatformSpecificFileRouting(t *testing.T) {
+	root := writeTestModule(t, platformModuleFiles())
+	linuxFile := filepath.Join(root, "platform", "open_linux.go")
+	windowsFile := filepath.Join(root, "platform", "open_windows.go")
+
+	tests := []struct {
+		goos         string
+		linuxOwned   bool
+		windowsOwned bool
+	}{
+		{"linux", true, false},
+		{"windows", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			finder := New(root)
+			finder.SetTargetPlatform(tt.goos, "amd64")
+
+			isMine, err := finder.ThisFileIsMine("app/main.go", linuxFile, "write")
+			if err != nil {
+				t.Fatalf("ThisFileIsMine(linux file) failed: %v", err)
+			}
+			if isMine != tt.linuxOwned {
+				t.Errorf("open_linux.go owned = %v, want %v", isMine, tt.linuxOwned)
+			}
+
+			isMine, err = finder.ThisFileIsMine("app/main.go", windowsFile, "write")
+			if err != nil {
+				t.Fatalf("ThisFileIsMine(windows file) failed: %v", err)
+			}
+			if isMine != tt.windowsOwned {
+				t.Errorf("open_windows.go owned = %v, want %v", isMine, tt.windowsOwned)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package godepfind
+
+import "sort"
+
+// ReachabilityChanges reports the packages that transitioned between reachable
+// and unreachable from the main packages during the most recent cache rebuild
+// or rescan of an edited main file (e.g. after a main file removed or added an
+// import). The first build only
+// establishes the baseline and reports no changes.
+func (g *GoDepFind) ReachabilityChanges() (nowReachable, nowUnreachable []string, err error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+	return append([]string{}, g.nowReachable...), append([]string{}, g.nowUnreachable...), nil
+}
+
+// updateReachability recomputes the set of cached packages reachable from any
+// main package and records the difference with the previous rebuild
+func (g *GoDepFind) updateReachability() {
+	current := make(map[string]bool)
+	for _, mainPath := range g.mainPackages {
+		for _, dep := range g.transitiveDeps(mainPath) {
+			if _, cached := g.packageCache[dep]; cached {
+				current[dep] = true
+			}
+		}
+	}
+
+	g.nowReachable = nil
+	g.nowUnreachable = nil
+	if g.reachable != nil {
+		for pkg := range current {
+			if !g.reachable[pkg] {
+				g.nowReachable = append(g.nowReachable, pkg)
+			}
+		}
+		for pkg := range g.reachable {
+			if !current[pkg] {
+				g.nowUnreachable = append(g.nowUnreachable, pkg)
+			}
+		}
+		sort.Strings(g.nowReachable)
+		sort.Strings(g.nowUnreachable)
+	}
+	g.reachable = current
+}
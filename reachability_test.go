@@ -0,0 +1,56 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReachabilityChangesAfterImportRemoved(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+import "testmod/lib"
+
+func main() {
+	lib.Run()
+}
+`,
+		"lib/lib.go": `package lib
+
+func Run() {}
+`,
+	})
+
+	finder := New(root)
+
+	// Baseline build reports no transitions
+	nowReachable, nowUnreachable, err := finder.ReachabilityChanges()
+	if err != nil {
+		t.Fatalf("ReachabilityChanges failed: %v", err)
+	}
+	if len(nowReachable) != 0 || len(nowUnreachable) != 0 {
+		t.Fatalf("expected no changes on first build, got %v / %v", nowReachable, nowUnreachable)
+	}
+
+	// Remove the import from the main and notify the finder
+	mainPath := filepath.Join(root, "app", "main.go")
+	if err := os.WriteFile(mainPath, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("rewrite main: %v", err)
+	}
+	if _, err := finder.ThisFileIsMine("app/main.go", mainPath, "write"); err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+
+	nowReachable, nowUnreachable, err = finder.ReachabilityChanges()
+	if err != nil {
+		t.Fatalf("ReachabilityChanges failed: %v", err)
+	}
+	if len(nowUnreachable) != 1 || nowUnreachable[0] != "testmod/lib" {
+		t.Errorf("expected testmod/lib to become unreachable, got %v", nowUnreachable)
+	}
+	if len(nowReachable) != 0 {
+		t.Errorf("expected no newly reachable packages, got %v", nowReachable)
+	}
+}
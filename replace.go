@@ -0,0 +1,163 @@
+package godepfind
+
+import (
+	"bufio"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localReplace is a go.mod replace directive whose target is a local
+// directory (e.g. "replace example.com/lib => ../local-lib")
+type localReplace struct {
+	modulePath string // replaced module path
+	dir        string // absolute directory holding the replacement
+}
+
+// readLocalReplaces returns the replace directives of a go.mod file that point
+// to a directory, in both the single-line and the block form. Relative targets
+// are resolved against the go.mod directory. Replacements by another module
+// version are skipped: their sources are not part of the developer's tree.
+func readLocalReplaces(goModPath string) []localReplace {
+	file, err := os.Open(goModPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var replaces []localReplace
+	add := func(directive string) {
+		old, target, found := strings.Cut(directive, "=>")
+		if !found {
+			return
+		}
+		oldFields, targetFields := strings.Fields(old), strings.Fields(target)
+		if len(oldFields) == 0 || len(targetFields) != 1 {
+			return // a target with a version is a module, not a directory
+		}
+		dir := strings.Trim(targetFields[0], `"`)
+		if !strings.HasPrefix(dir, "./") && !strings.HasPrefix(dir, "../") && !filepath.IsAbs(dir) {
+			return
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(goModPath), filepath.FromSlash(dir))
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			replaces = append(replaces, localReplace{modulePath: strings.Trim(oldFields[0], `"`), dir: abs})
+		}
+	}
+
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock:
+			add(line)
+		case line == "replace (" || line == "replace(":
+			inBlock = true
+		default:
+			if rest, ok := strings.CutPrefix(line, "replace "); ok {
+				add(rest)
+			}
+		}
+	}
+	return replaces
+}
+
+// detectLocalReplaces returns the local replace directives of the go.mod
+// files of the project (the module of rootDir or every workspace module)
+func (res *moduleResolution) detectLocalReplaces() []localReplace {
+	var goMods []string
+	if res.moduleRoot != "" {
+		goMods = append(goMods, filepath.Join(res.moduleRoot, "go.mod"))
+	}
+	for _, module := range res.workspace {
+		goMods = append(goMods, filepath.Join(module.dir, "go.mod"))
+	}
+	var replaces []localReplace
+	for _, goMod := range goMods {
+		replaces = append(replaces, readLocalReplaces(goMod)...)
+	}
+	return replaces
+}
+
+// replacedImportPathDir resolves an import path of a locally replaced module
+// to its directory
+func (res *moduleResolution) replacedImportPathDir(importPath string) (string, bool) {
+	for _, replace := range res.replaces {
+		if importPath == replace.modulePath {
+			return replace.dir, true
+		}
+		if rest, ok := strings.CutPrefix(importPath, replace.modulePath+"/"); ok {
+			return filepath.Join(replace.dir, filepath.FromSlash(rest)), true
+		}
+	}
+	return "", false
+}
+
+// inReplacedDir reports whether an absolute path is inside the directory of a
+// locally replaced module
+func (res *moduleResolution) inReplacedDir(absPath string) bool {
+	for _, replace := range res.replaces {
+		if _, inside := relativeToDir(replace.dir, absPath); inside {
+			return true
+		}
+	}
+	return false
+}
+
+// mapReplacedPackages maps the files of the locally replaced packages imported
+// by the module (and, with SetIncludeExternalModules, by the loaded external
+// packages) to their import path, so edits in a replacement directory outside
+// the root resolve to the package the handlers import. The graph is not
+// changed: replaced packages are reached through the module's import edges.
+func (g *GoDepFind) mapReplacedPackages(packages, external map[string]*build.Package) {
+	res := g.currentResolution()
+	if len(res.replaces) == 0 {
+		return
+	}
+	buildCtx := g.buildContext()
+	mapped := make(map[string]bool)
+	mapPackage := func(importPath string) {
+		if mapped[importPath] || packages[importPath] != nil {
+			return
+		}
+		dir, ok := res.replacedImportPathDir(importPath)
+		if !ok {
+			return
+		}
+		mapped[importPath] = true
+		pkg := external[importPath]
+		if pkg == nil {
+			imported, err := buildCtx.ImportDir(dir, 0)
+			if err != nil {
+				return
+			}
+			pkg = imported
+		}
+		for _, file := range pkg.GoFiles {
+			absPath, err := filepath.Abs(filepath.Join(pkg.Dir, file))
+			if err != nil {
+				continue
+			}
+			if _, exists := g.filePathToPackage[g.pathKey(absPath)]; !exists {
+				g.filePathToPackage[g.pathKey(absPath)] = importPath
+			}
+		}
+	}
+	for _, set := range []map[string]*build.Package{packages, external} {
+		for importPath, pkg := range set {
+			mapPackage(importPath)
+			if pkg != nil {
+				for _, imp := range pkg.Imports {
+					mapPackage(imp)
+				}
+			}
+		}
+	}
+}
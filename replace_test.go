@@ -0,0 +1,75 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadLocalReplaces(t *testing.T) {
+	dir := t.TempDir()
+	goMod := filepath.Join(dir, "go.mod")
+	content := `module testmod
+
+go 1.21
+
+replace example.com/single => ../single
+
+replace (
+	example.com/block v1.2.0 => ./vendor/block // pinned fork
+	example.com/remote => example.com/fork v1.0.0
+	"example.com/quoted" => "/abs/quoted"
+)
+`
+	if err := os.WriteFile(goMod, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := []localReplace{
+		{modulePath: "example.com/single", dir: filepath.Join(filepath.Dir(dir), "single")},
+		{modulePath: "example.com/block", dir: filepath.Join(dir, "vendor", "block")},
+		{modulePath: "example.com/quoted", dir: "/abs/quoted"},
+	}
+	if got := readLocalReplaces(goMod); !reflect.DeepEqual(got, want) {
+		t.Errorf("readLocalReplaces = %+v, want %+v", got, want)
+	}
+}
+
+func TestReplacedSiblingDirectoryIsOwned(t *testing.T) {
+	files := externalModuleFiles()
+	files["app/cmd/tool/main.go"] = "package main\n\nfunc main() {}\n"
+	root := writeTestModule(t, files)
+	extlib := filepath.Join(root, "extlib", "extlib.go")
+	inner := filepath.Join(root, "extlib", "inner", "inner.go")
+
+	finder := New(filepath.Join(root, "app"))
+	cases := []struct {
+		handler, file string
+		want          bool
+	}{
+		{"cmd/server/main.go", extlib, true},
+		{"cmd/tool/main.go", extlib, false},
+		// Without external modules the graph stops at the direct import
+		{"cmd/server/main.go", inner, false},
+	}
+	for _, c := range cases {
+		mine, err := finder.ThisFileIsMine(c.handler, c.file, EventWrite)
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s) failed: %v", c.handler, c.file, err)
+		}
+		if mine != c.want {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", c.handler, c.file, mine, c.want)
+		}
+	}
+	if pkg, err := finder.PackageForFile(extlib); err != nil || pkg != "example.com/extlib" {
+		t.Errorf("PackageForFile(extlib.go) = %q, %v; want example.com/extlib", pkg, err)
+	}
+
+	finder.SetIncludeExternalModules(true)
+	if mine, err := finder.ThisFileIsMine("cmd/server/main.go", inner, EventWrite); err != nil || !mine {
+		t.Errorf("with external modules: inner.go ownership = %v, %v; want true", mine, err)
+	}
+	if mine, err := finder.ThisFileIsMine("cmd/tool/main.go", inner, EventWrite); err != nil || mine {
+		t.Errorf("with external modules: tool owns inner.go = %v, %v; want false", mine, err)
+	}
+}
@@ -0,0 +1,99 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRemovePackagePrunesReverseEdges(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+import "testmod/mid"
+
+func main() { mid.Do() }
+`,
+		"mid/mid.go": `package mid
+
+import "testmod/leaf"
+
+func Do() { leaf.Do() }
+`,
+		"leaf/leaf.go": "package leaf\n\nfunc Do() {}\n",
+	})
+
+	finder := New(root)
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+	if !contains(finder.reverseDeps["testmod/leaf"], "testmod/mid") {
+		t.Fatalf("expected mid to import leaf initially, got %v", finder.reverseDeps["testmod/leaf"])
+	}
+
+	midPath := filepath.Join(root, "mid", "mid.go")
+	if err := finder.updateCacheForFile(midPath, "remove"); err != nil {
+		t.Fatalf("remove event failed: %v", err)
+	}
+	if err := os.Remove(midPath); err != nil {
+		t.Fatalf("remove mid.go: %v", err)
+	}
+
+	if _, exists := finder.reverseDeps["testmod/mid"]; exists {
+		t.Error("expected removed package's own reverseDeps entry to be dropped")
+	}
+	for dep, importers := range finder.reverseDeps {
+		if contains(importers, "testmod/mid") {
+			t.Errorf("stale reverse edge %s <- testmod/mid remains", dep)
+		}
+	}
+	if importers := finder.reverseDeps["testmod/leaf"]; len(importers) != 0 {
+		t.Errorf("expected leaf to have no importers left, got %v", importers)
+	}
+}
+
+func TestCreateInImportedPackageKeepsImporterEdges(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":      "module testmod\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport (\n\t\"testmod/m1\"\n\t\"testmod/m2\"\n)\n\nfunc main() { m1.Do(); m2.Do() }\n",
+		"m1/m1.go":    "package m1\n\nfunc Do() {}\n",
+		"m2/m2.go":    "package m2\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+	if _, err := finder.ListMainPackages(); err != nil {
+		t.Fatalf("cache build failed: %v", err)
+	}
+
+	created := filepath.Join(root, "m1", "extra.go")
+	if err := os.WriteFile(created, []byte("package m1\n\nfunc Extra() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.updateCacheForFile(created, EventCreate); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	m1 := filepath.Join(root, "m1", "m1.go")
+	if err := os.WriteFile(m1, []byte("package m1\n\nfunc Do() { _ = 1 }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.updateCacheForFile(m1, EventWrite); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	if imports := finder.packageCache["testmod/app"].Imports; !reflect.DeepEqual(imports, []string{"testmod/m1", "testmod/m2"}) {
+		t.Errorf("expected app's cached imports to stay intact, got %v", imports)
+	}
+	for _, file := range []string{"m1.go", "extra.go", "m2.go"} {
+		mains, err := finder.GoFileComesFromMain(file)
+		if err != nil {
+			t.Fatalf("GoFileComesFromMain(%s) failed: %v", file, err)
+		}
+		if !reflect.DeepEqual(mains, []string{"testmod/app"}) {
+			t.Errorf("GoFileComesFromMain(%s) = %v, want [testmod/app]", file, mains)
+		}
+	}
+	if mine, err := finder.ThisFileIsMine("app/main.go", created, EventWrite); err != nil || !mine {
+		t.Errorf("expected the created file to belong to app, got %v, %v", mine, err)
+	}
+}
@@ -0,0 +1,40 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// roots returns rootDir followed by the peer roots given to NewMulti
+func (g *GoDepFind) roots() []string {
+	return append([]string{g.rootDir}, g.extraRoots...)
+}
+
+// Roots returns the directories analyzed: rootDir followed by the peer roots
+// given to NewMulti. File watchers use it to know what to watch.
+func (g *GoDepFind) Roots() []string {
+	return g.roots()
+}
+
+// rootPath resolves a path relative to the root to a file path. With several
+// roots the first root under which the path exists wins, and rootDir is used
+// when it exists under none. Absolute paths are returned unchanged.
+func (g *GoDepFind) rootPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	joined := filepath.Join(g.rootDir, path)
+	if len(g.extraRoots) == 0 {
+		return joined
+	}
+	if _, err := os.Stat(joined); err == nil {
+		return joined
+	}
+	for _, root := range g.extraRoots {
+		candidate := filepath.Join(root, path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return joined
+}
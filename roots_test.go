@@ -0,0 +1,66 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewMultiPeerRoots(t *testing.T) {
+	front := writeTestModule(t, map[string]string{
+		"go.mod": "module front\n\ngo 1.21\n",
+		"cmd/web/main.go": `package main
+
+import "front/ui"
+
+func main() { ui.Render() }
+`,
+		"ui/ui.go": "package ui\n\nfunc Render() {}\n",
+	})
+	back := writeTestModule(t, map[string]string{
+		"go.mod": "module back\n\ngo 1.21\n",
+		"cmd/api/main.go": `package main
+
+import "back/store"
+
+func main() { store.Open() }
+`,
+		"store/store.go": "package store\n\nfunc Open() {}\n",
+	})
+
+	finder := NewMulti(front, back)
+	mains, err := finder.ListMainPackages()
+	if err != nil {
+		t.Fatalf("ListMainPackages failed: %v", err)
+	}
+	if want := []string{"back/cmd/api", "front/cmd/web"}; !reflect.DeepEqual(mains, want) {
+		t.Errorf("expected mains of both roots %v, got %v", want, mains)
+	}
+
+	storeFile := filepath.Join(back, "store", "store.go")
+	uiFile := filepath.Join(front, "ui", "ui.go")
+	if pkg, found := finder.lookupFilePath(storeFile); !found || pkg != "back/store" {
+		t.Errorf("expected %s mapped to back/store, got %q", storeFile, pkg)
+	}
+
+	tests := []struct {
+		handler string
+		file    string
+		want    bool
+	}{
+		{"cmd/api/main.go", storeFile, true}, // handler under the second root
+		{"cmd/api/main.go", uiFile, false},
+		{"cmd/web/main.go", uiFile, true},
+		{"cmd/web/main.go", storeFile, false},
+		{filepath.Join(back, "cmd", "api", "main.go"), storeFile, true},
+	}
+	for _, tt := range tests {
+		isMine, err := finder.ThisFileIsMine(tt.handler, tt.file, EventWrite)
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s, %s) failed: %v", tt.handler, tt.file, err)
+		}
+		if isMine != tt.want {
+			t.Errorf("ThisFileIsMine(%s, %s) = %v, want %v", tt.handler, tt.file, isMine, tt.want)
+		}
+	}
+}
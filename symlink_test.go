@@ -0,0 +1,53 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymlinkedSourceDirectory(t *testing.T) {
+	real := writeTestModule(t, chainModuleFiles())
+	link := filepath.Join(t.TempDir(), "work")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	realLeaf, err := filepath.EvalSymlinks(filepath.Join(real, "internal", "leaf", "leaf.go"))
+	if err != nil {
+		t.Fatalf("EvalSymlinks failed: %v", err)
+	}
+	linkLeaf := filepath.Join(link, "internal", "leaf", "leaf.go")
+
+	tests := []struct {
+		name string
+		root string
+		file string
+	}{
+		{"symlinked root, real event path", link, realLeaf},
+		{"real root, symlinked event path", real, linkLeaf},
+		{"symlinked root, symlinked event path", link, linkLeaf},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := New(tt.root)
+			pkg, err := finder.findPackageContainingFileByPath(tt.file)
+			if err != nil {
+				t.Fatalf("findPackageContainingFileByPath failed: %v", err)
+			}
+			if pkg != "testmod/internal/leaf" {
+				t.Errorf("expected testmod/internal/leaf, got %q", pkg)
+			}
+			if pkg, found := finder.lookupFilePath(tt.file); !found || pkg != "testmod/internal/leaf" {
+				t.Errorf("expected a cached mapping to testmod/internal/leaf, got %q (found=%v)", pkg, found)
+			}
+
+			isMine, err := finder.ThisFileIsMine("app/main.go", tt.file, "write")
+			if err != nil {
+				t.Fatalf("ThisFileIsMine failed: %v", err)
+			}
+			if !isMine {
+				t.Error("expected app/main.go to own the leaf file")
+			}
+		})
+	}
+}
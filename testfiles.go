@@ -0,0 +1,178 @@
+package godepfind
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MainForTestFile returns the main packages served by the package under test
+// of a _test.go file. Both in-package (package foo) and external
+// (package foo_test) test files map to the package in the same directory.
+func (g *GoDepFind) MainForTestFile(testFileAbsPath string) ([]string, error) {
+	if !strings.HasSuffix(testFileAbsPath, "_test.go") {
+		return nil, fmt.Errorf("not a test file: %s", testFileAbsPath)
+	}
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	testFileAbsPath = g.rootPath(testFileAbsPath)
+	subject := g.packageForDir(filepath.Dir(testFileAbsPath))
+	if subject == "" {
+		return []string{}, nil
+	}
+
+	result := []string{}
+	for _, mainPath := range g.mainPackages {
+		if g.cachedMainImportsPackage(mainPath, subject) {
+			result = append(result, mainPath)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// IsTestFile reports whether fileAbsPath is a _test.go file of a package in
+// the module (in-package or external test), so a watcher can skip rebuilding
+// the production binaries when only tests changed. Test files are tracked
+// apart from production files whether or not SetTestImports is enabled. A
+// _test.go file created after the cache was built counts as a test file when
+// its directory holds a known package. It returns an error when the file
+// belongs to no package.
+func (g *GoDepFind) IsTestFile(fileAbsPath string) (bool, error) {
+	if fileAbsPath == "" {
+		return false, fmt.Errorf("file path is required")
+	}
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	absPath, err := filepath.Abs(g.rootPath(fileAbsPath))
+	if err != nil {
+		return false, err
+	}
+	if len(g.fileToTestPackages[g.pathKey(absPath)]) > 0 {
+		return true, nil
+	}
+	if _, ok := g.filePathToPackage[g.pathKey(absPath)]; ok {
+		return false, nil
+	}
+	if strings.HasSuffix(g.pathKey(absPath), "_test.go") && g.packageForDir(filepath.Dir(absPath)) != "" {
+		return true, nil
+	}
+	return false, fmt.Errorf("file not found in any package: %s", fileAbsPath)
+}
+
+// TestFilesForPackage returns the absolute paths of the _test.go files
+// (in-package and external) of pkgPath, sorted. It returns an error for a
+// package outside the module.
+func (g *GoDepFind) TestFilesForPackage(pkgPath string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if g.packageCache[pkgPath] == nil {
+		return nil, fmt.Errorf("package not found in module: %s", pkgPath)
+	}
+
+	result := []string{}
+	for path, pkgs := range g.fileToTestPackages {
+		if contains(pkgs, pkgPath) {
+			result = append(result, path)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// packageForDir returns the cached package whose directory is dir, or "" if none
+func (g *GoDepFind) packageForDir(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for pkgPath, pkg := range g.packageCache {
+		if pkg == nil {
+			continue
+		}
+		if pkgDir, err := filepath.Abs(pkg.Dir); err == nil && g.pathKey(pkgDir) == g.pathKey(absDir) {
+			return pkgPath
+		}
+	}
+	return ""
+}
+
+// ExclusivelyTestImporters returns the packages that import pkgPath only
+// through their own _test files: they have no production edge to pkgPath,
+// direct or transitive, so a production change to pkgPath can't break their
+// non-test build. Requires test imports to be enabled; only the kinds of test
+// files enabled (see SetExternalTestImports) count.
+func (g *GoDepFind) ExclusivelyTestImporters(pkgPath string) ([]string, error) {
+	unlock, err := g.lockQuery()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if !g.tracksTestImports() {
+		return nil, fmt.Errorf("test imports are disabled: enable SetTestImports to track test edges")
+	}
+
+	result := []string{}
+	for importer, pkg := range g.packageCache {
+		if pkg == nil || importer == pkgPath {
+			continue
+		}
+		if !contains(g.enabledTestImports(pkg), pkgPath) {
+			continue
+		}
+		if g.cachedImports(importer, pkgPath, make(map[string]bool)) {
+			continue // also reachable from production code
+		}
+		result = append(result, importer)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// tracksTestImports reports whether the imports of any kind of test file
+// (in-package or external) are included
+func (g *GoDepFind) tracksTestImports() bool {
+	return g.testImports || g.xtestImports
+}
+
+// enabledTestImports returns the test imports of pkg that are included: those
+// of in-package test files with SetTestImports, those of external test files
+// unless SetExternalTestImports turned them off
+func (g *GoDepFind) enabledTestImports(pkg *build.Package) []string {
+	switch {
+	case g.testImports && g.xtestImports:
+		return append(append([]string{}, pkg.TestImports...), pkg.XTestImports...)
+	case g.testImports:
+		return pkg.TestImports
+	case g.xtestImports:
+		return pkg.XTestImports
+	}
+	return nil
+}
+
+// enabledTestGoFiles returns the test files of pkg whose imports are included
+// (see enabledTestImports)
+func (g *GoDepFind) enabledTestGoFiles(pkg *build.Package) []string {
+	switch {
+	case g.testImports && g.xtestImports:
+		return append(append([]string{}, pkg.TestGoFiles...), pkg.XTestGoFiles...)
+	case g.testImports:
+		return pkg.TestGoFiles
+	case g.xtestImports:
+		return pkg.XTestGoFiles
+	}
+	return nil
+}
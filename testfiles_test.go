@@ -0,0 +1,217 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMainForTestFile(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+import "testmod/lib"
+
+func main() { lib.Do() }
+`,
+		"other/main.go": `package main
+
+func main() {}
+`,
+		"lib/lib.go": `package lib
+
+func Do() {}
+`,
+		"lib/lib_test.go": `package lib
+
+import "testing"
+
+func TestDo(t *testing.T) { Do() }
+`,
+		"lib/external_test.go": `package lib_test
+
+import (
+	"testing"
+
+	"testmod/lib"
+)
+
+func TestExternal(t *testing.T) { lib.Do() }
+`,
+	})
+
+	finder := New(root)
+
+	for _, name := range []string{"lib_test.go", "external_test.go"} {
+		mains, err := finder.MainForTestFile(filepath.Join(root, "lib", name))
+		if err != nil {
+			t.Fatalf("MainForTestFile(%s) failed: %v", name, err)
+		}
+		if len(mains) != 1 || mains[0] != "testmod/app" {
+			t.Errorf("MainForTestFile(%s): expected [testmod/app], got %v", name, mains)
+		}
+	}
+
+	if _, err := finder.MainForTestFile(filepath.Join(root, "lib", "lib.go")); err == nil {
+		t.Error("expected error for a non-test file")
+	}
+}
+
+func TestExclusivelyTestImporters(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":              "module testmod\n\ngo 1.21\n",
+		"target/target.go":    "package target\n\nfunc Do() {}\n",
+		"direct/direct.go":    "package direct\n\nimport \"testmod/target\"\n\nfunc Do() { target.Do() }\n",
+		"via/via.go":          "package via\n\nimport \"testmod/direct\"\n\nfunc Do() { direct.Do() }\n",
+		"via/via_test.go":     "package via\n\nimport (\n\t\"testing\"\n\n\t\"testmod/target\"\n)\n\nfunc TestVia(t *testing.T) { target.Do() }\n",
+		"testonly/a.go":       "package testonly\n",
+		"testonly/a_test.go":  "package testonly\n\nimport (\n\t\"testing\"\n\n\t\"testmod/target\"\n)\n\nfunc TestA(t *testing.T) { target.Do() }\n",
+		"xtestonly/b.go":      "package xtestonly\n",
+		"xtestonly/b_test.go": "package xtestonly_test\n\nimport (\n\t\"testing\"\n\n\t\"testmod/target\"\n)\n\nfunc TestB(t *testing.T) { target.Do() }\n",
+	})
+
+	finder := New(root)
+	if _, err := finder.ExclusivelyTestImporters("testmod/target"); err == nil {
+		t.Error("expected error when test imports are disabled")
+	}
+
+	finder.SetTestImports(true)
+	importers, err := finder.ExclusivelyTestImporters("testmod/target")
+	if err != nil {
+		t.Fatalf("ExclusivelyTestImporters failed: %v", err)
+	}
+	// direct imports target from production code, via reaches it through direct
+	want := []string{"testmod/testonly", "testmod/xtestonly"}
+	if !reflect.DeepEqual(importers, want) {
+		t.Errorf("ExclusivelyTestImporters = %v, want %v", importers, want)
+	}
+}
+
+func TestIsTestFile(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":               "module testmod\n\ngo 1.21\n",
+		"app/main.go":          "package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do() }\n",
+		"lib/lib.go":           "package lib\n\nfunc Do() {}\n",
+		"lib/lib_test.go":      "package lib\n\nimport \"testing\"\n\nfunc TestDo(t *testing.T) { Do() }\n",
+		"lib/external_test.go": "package lib_test\n\nimport (\n\t\"testing\"\n\n\t\"testmod/lib\"\n)\n\nfunc TestExternal(t *testing.T) { lib.Do() }\n",
+	})
+	libTest := filepath.Join(root, "lib", "lib_test.go")
+	externalTest := filepath.Join(root, "lib", "external_test.go")
+
+	for _, testImports := range []bool{false, true} {
+		finder := New(root)
+		finder.SetTestImports(testImports)
+
+		for path, want := range map[string]bool{
+			libTest:                            true,
+			externalTest:                       true,
+			filepath.Join(root, "lib/lib.go"):  false,
+			filepath.Join(root, "app/main.go"): false,
+		} {
+			got, err := finder.IsTestFile(path)
+			if err != nil {
+				t.Fatalf("testImports=%v: IsTestFile(%s) failed: %v", testImports, path, err)
+			}
+			if got != want {
+				t.Errorf("testImports=%v: IsTestFile(%s) = %v, want %v", testImports, path, got, want)
+			}
+		}
+		if _, err := finder.IsTestFile(filepath.Join(root, "nowhere", "x_test.go")); err == nil {
+			t.Errorf("testImports=%v: expected error for a file outside any package", testImports)
+		}
+
+		files, err := finder.TestFilesForPackage("testmod/lib")
+		if err != nil {
+			t.Fatalf("TestFilesForPackage failed: %v", err)
+		}
+		if want := []string{externalTest, libTest}; !reflect.DeepEqual(files, want) {
+			t.Errorf("testImports=%v: TestFilesForPackage = %v, want %v", testImports, files, want)
+		}
+
+		// Production mappings only include test files when test imports are on
+		_, mapped := finder.filePathToPackage[libTest]
+		if mapped != testImports {
+			t.Errorf("testImports=%v: test file in production mapping = %v", testImports, mapped)
+		}
+	}
+
+	if _, err := New(root).TestFilesForPackage("testmod/missing"); err == nil {
+		t.Error("expected error for an unknown package")
+	}
+}
+
+func TestIsTestFileTracksCreatedTestFiles(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":     "module testmod\n\ngo 1.21\n",
+		"lib/lib.go": "package lib\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+	finder.SetTestImports(true)
+	if _, err := finder.TestFilesForPackage("testmod/lib"); err != nil {
+		t.Fatal(err)
+	}
+
+	created := filepath.Join(root, "lib", "new_test.go")
+	if err := os.WriteFile(created, []byte("package lib\n\nimport \"testing\"\n\nfunc TestNew(t *testing.T) {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.updateCacheForFile(created, EventCreate); err != nil {
+		t.Fatal(err)
+	}
+	files, err := finder.TestFilesForPackage("testmod/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{created}; !reflect.DeepEqual(files, want) {
+		t.Errorf("after create: TestFilesForPackage = %v, want %v", files, want)
+	}
+	if ok, err := finder.IsTestFile(created); err != nil || !ok {
+		t.Errorf("IsTestFile(created) = %v, %v; want true", ok, err)
+	}
+}
+
+func TestExternalTestImportsSelectable(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":               "module testmod\n\ngo 1.21\n",
+		"lib/lib.go":           "package lib\n\nfunc Do() {}\n",
+		"lib/lib_test.go":      "package lib\n\nimport \"testmod/helper\"\n\nvar _ = helper.Help\n",
+		"lib/lib_ext_test.go":  "package lib_test\n\nimport \"testmod/blackbox\"\n\nvar _ = blackbox.Check\n",
+		"helper/helper.go":     "package helper\n\nfunc Help() {}\n",
+		"blackbox/blackbox.go": "package blackbox\n\nfunc Check() {}\n",
+	})
+
+	cases := []struct {
+		name         string
+		configure    func(g *GoDepFind)
+		helper, xdep bool // lib reported as importing helper, blackbox
+	}{
+		{"none", func(g *GoDepFind) {}, false, false},
+		{"both", func(g *GoDepFind) { g.SetTestImports(true) }, true, true},
+		{"in-package", func(g *GoDepFind) { g.SetTestImports(true); g.SetExternalTestImports(false) }, true, false},
+		{"external", func(g *GoDepFind) { g.SetExternalTestImports(true) }, false, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := New(root)
+			tc.configure(g)
+			for target, want := range map[string]bool{"testmod/helper": tc.helper, "testmod/blackbox": tc.xdep} {
+				importers, err := g.DirectImporters(target)
+				if err != nil {
+					t.Fatalf("DirectImporters failed: %v", err)
+				}
+				if got := contains(importers, "testmod/lib"); got != want {
+					t.Errorf("DirectImporters(%s) includes lib = %v, want %v", target, got, want)
+				}
+				deps, err := g.FindReverseDeps("./...", []string{target})
+				if err != nil {
+					t.Fatalf("FindReverseDeps failed: %v", err)
+				}
+				if got := contains(deps, "testmod/lib"); got != want {
+					t.Errorf("FindReverseDeps(%s) includes lib = %v, want %v", target, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// toolsModuleFiles returns a module whose main blank-imports a tool package
+// from a tools.go guarded by the "tools" build tag
+func toolsModuleFiles() map[string]string {
+	return map[string]string{
+		"go.mod": "module testmod\n\ngo 1.21\n",
+		"app/main.go": `package main
+
+func main() {}
+`,
+		"app/tools.go": `//go:build tools
+
+package main
+
+import _ "testmod/internal/gen"
+`,
+		"internal/gen/gen.go": `package gen
+
+func Generate() {}
+`,
+	}
+}
+
+func TestToolsTagMakesToolPackagesReachable(t *testing.T) {
+	root := writeTestModule(t, toolsModuleFiles())
+	genPath := filepath.Join(root, "internal", "gen", "gen.go")
+
+	// Without the tag the tools.go imports are invisible
+	finder := New(root)
+	isMine, err := finder.ThisFileIsMine("app/main.go", genPath, "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if isMine {
+		t.Error("expected tool package not to be owned without the tools tag")
+	}
+
+	// With the tag the tool package is attributed to the main
+	finder = New(root)
+	finder.SetBuildTags([]string{"tools"})
+
+	mains, err := finder.GoFileComesFromMain("gen.go")
+	if err != nil {
+		t.Fatalf("GoFileComesFromMain failed: %v", err)
+	}
+	if len(mains) != 1 || mains[0] != "testmod/app" {
+		t.Errorf("expected gen.go to come from testmod/app, got %v", mains)
+	}
+
+	isMine, err = finder.ThisFileIsMine("app/main.go", genPath, "write")
+	if err != nil {
+		t.Fatalf("ThisFileIsMine failed: %v", err)
+	}
+	if !isMine {
+		t.Error("expected tool package to be owned by the main with the tools tag")
+	}
+}
+
+func TestBuildTagsRouteGuardedHelpers(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":              "module testmod\n\ngo 1.21\n",
+		"app/main.go":         "package main\n\nimport \"testmod/wiring\"\n\nfunc main() { wiring.Start() }\n",
+		"wiring/wiring.go":    "package wiring\n\nfunc Start() { start() }\n",
+		"wiring/dev.go":       "//go:build !production\n\npackage wiring\n\nfunc start() {}\n",
+		"wiring/prod.go":      "//go:build production\n\npackage wiring\n\nimport \"testmod/prodhelper\"\n\nfunc start() { prodhelper.Tune() }\n",
+		"wiring/it.go":        "//go:build integration\n\npackage wiring\n\nimport \"testmod/fixtures\"\n\nvar _ = fixtures.Seed\n",
+		"prodhelper/tune.go":  "//go:build production\n\npackage prodhelper\n\nfunc Tune() {}\n",
+		"fixtures/fixture.go": "package fixtures\n\nfunc Seed() {}\n",
+	})
+	helper := filepath.Join(root, "prodhelper", "tune.go")
+	fixture := filepath.Join(root, "fixtures", "fixture.go")
+
+	tests := []struct {
+		tags           []string
+		helperOwned    bool
+		fixtureOwned   bool
+		devOwned       bool
+		expectListTags bool
+	}{
+		{nil, false, false, true, false},
+		{[]string{"production"}, true, false, false, true},
+		{[]string{"integration", "production"}, true, true, false, true},
+	}
+	for _, tt := range tests {
+		finder := New(root)
+		finder.SetBuildTags(tt.tags)
+
+		for file, expected := range map[string]bool{
+			helper:                                  tt.helperOwned,
+			fixture:                                 tt.fixtureOwned,
+			filepath.Join(root, "wiring", "dev.go"): tt.devOwned,
+		} {
+			isMine, err := finder.ThisFileIsMine("app/main.go", file, "write")
+			if err != nil {
+				t.Fatalf("tags %v: ThisFileIsMine(%s) failed: %v", tt.tags, file, err)
+			}
+			if isMine != expected {
+				t.Errorf("tags %v: ThisFileIsMine(%s) = %v, want %v", tt.tags, file, isMine, expected)
+			}
+		}
+
+		// go list and the package imports agree on the active files
+		_, args := finder.LastListCommand()
+		if hasTags := len(args) > 1 && args[1] == "-tags"; hasTags != tt.expectListTags {
+			t.Errorf("tags %v: unexpected go list arguments %v", tt.tags, args)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package godepfind
+
+import (
+	"go/build"
+	"hash/fnv"
+)
+
+// UpdateStats counts how the cache absorbed changes since the finder was created
+type UpdateStats struct {
+	Rebuilds         int // full rebuilds of the cache
+	WriteEvents      int // write events applied to a cached package
+	ImportsUnchanged int // write events that kept the package's imports: the graph was left untouched
+}
+
+// OnRebuild registers a callback invoked with the running UpdateStats after
+// each full rebuild of the cache and each write event applied to a package,
+// e.g. to measure how often edits take the fast path of keeping their
+// imports. It runs with the finder locked and must not call back into it.
+func (g *GoDepFind) OnRebuild(fn func(stats UpdateStats)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onRebuild = fn
+}
+
+// notifyUpdate reports the running stats to the OnRebuild callback
+func (g *GoDepFind) notifyUpdate() {
+	if g.onRebuild != nil {
+		g.onRebuild(g.updateStats)
+	}
+}
+
+// importsHash hashes what the graph and the file mappings take from a
+// package: its name, imports and files. The files are included because a
+// build constraint edit moves files in or out of the package.
+func importsHash(pkg *build.Package) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(pkg.Name))
+	for _, list := range [][]string{
+		pkg.Imports, pkg.TestImports, pkg.XTestImports,
+		pkg.GoFiles, pkg.TestGoFiles, pkg.XTestGoFiles, pkg.EmbedPatterns,
+	} {
+		hash.Write([]byte{0})
+		for _, s := range list {
+			hash.Write([]byte(s))
+			hash.Write([]byte{1})
+		}
+	}
+	return hash.Sum64()
+}
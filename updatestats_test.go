@@ -0,0 +1,72 @@
+package godepfind
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteKeepingImportsSkipsInvalidation(t *testing.T) {
+	root := writeTestModule(t, chainModuleFiles())
+	finder := New(root)
+	var last UpdateStats
+	finder.OnRebuild(func(stats UpdateStats) { last = stats })
+	if _, err := finder.ListMainPackages(); err != nil {
+		t.Fatalf("cache build failed: %v", err)
+	}
+	if last.Rebuilds != 1 {
+		t.Fatalf("expected one rebuild reported, got %+v", last)
+	}
+
+	// The first write re-imports mid with go/build: its go list baseline
+	// can't be compared, so it takes the slow path
+	midFile := filepath.Join(root, "mid", "mid.go")
+	body := "package mid\n\nimport \"testmod/internal/leaf\"\n\nfunc Do() { leaf.Do(); leaf.Do() }\n"
+	if err := os.WriteFile(midFile, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.updateCacheForFile(midFile, EventWrite); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if want := (UpdateStats{Rebuilds: 1, WriteEvents: 1}); last != want {
+		t.Errorf("expected %+v after the first write, got %+v", want, last)
+	}
+
+	body = "package mid\n\nimport \"testmod/internal/leaf\"\n\nfunc Do() { leaf.Do() }\n"
+	if err := os.WriteFile(midFile, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.updateCacheForFile(midFile, EventWrite); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if want := (UpdateStats{Rebuilds: 1, WriteEvents: 2, ImportsUnchanged: 1}); last != want {
+		t.Errorf("expected %+v after a body-only edit, got %+v", want, last)
+	}
+	if deps := finder.dependencyGraph["testmod/mid"]; !reflect.DeepEqual(deps, []string{"testmod/internal/leaf"}) {
+		t.Errorf("expected mid's edges to be kept, got %v", deps)
+	}
+	if !finder.cachedModule {
+		t.Error("expected the cache to stay built")
+	}
+
+	// Dropping the import takes the slow path and updates the graph
+	if err := os.WriteFile(midFile, []byte("package mid\n\nfunc Do() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finder.updateCacheForFile(midFile, EventWrite); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if want := (UpdateStats{Rebuilds: 1, WriteEvents: 3, ImportsUnchanged: 1}); last != want {
+		t.Errorf("expected %+v after an import edit, got %+v", want, last)
+	}
+	mains, err := finder.FindAffectedMains([]string{filepath.Join(root, "internal", "leaf", "leaf.go")})
+	if err != nil {
+		t.Fatalf("FindAffectedMains failed: %v", err)
+	}
+	for _, affected := range mains {
+		if len(affected) != 0 {
+			t.Errorf("expected leaf.go to affect no main after mid dropped it, got %v", affected)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package godepfind
 import (
 	"bufio"
 	"fmt"
+	"go/build"
 	"go/parser"
 	"go/token"
 	"os"
@@ -63,7 +64,8 @@ func (g *GoDepFind) ValidateInputForProcessing(mainInputFileRelativePath, fileNa
 		resolvedPath := filePath
 		if !filepath.IsAbs(filePath) {
 			// Check if filePath already starts with rootDir
-			if strings.HasPrefix(filePath, g.rootDir+"/") || filePath == g.rootDir {
+			slashPath, slashRoot := filepath.ToSlash(filePath), filepath.ToSlash(filepath.Clean(g.rootDir))
+			if strings.HasPrefix(slashPath, slashRoot+"/") || slashPath == slashRoot {
 				// Path already includes rootDir, use as is
 				resolvedPath = filePath
 			} else {
@@ -199,3 +201,48 @@ func (v *GoFileValidator) IsFileBeingWritten(filePath string) (bool, error) {
 
 	return false, nil
 }
+
+// HandlerMainValid reports whether the main package of a handler parses, so
+// dispatchers can pause routing to a broken handler instead of relying on a
+// stale cache. The handler main file and the other files built with it in its
+// directory are parsed; when one of them fails, false is returned together
+// with an error describing the problem.
+func (g *GoDepFind) HandlerMainValid(mainInputFileRelativePath string) (bool, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if mainInputFileRelativePath == "" {
+		return false, fmt.Errorf("handler mainInputFileRelativePath cannot be empty")
+	}
+	mainAbsPath := g.rootPath(mainInputFileRelativePath)
+	if _, err := os.Stat(mainAbsPath); err != nil {
+		return false, fmt.Errorf("handler main file does not exist: %s", mainInputFileRelativePath)
+	}
+
+	files := []string{mainAbsPath}
+	dir := filepath.Dir(mainAbsPath)
+	pkg, err := g.buildContext().ImportDir(dir, 0)
+	if err != nil {
+		if _, noGo := err.(*build.NoGoError); !noGo {
+			return false, fmt.Errorf("handler main package %s is invalid: %w", mainInputFileRelativePath, err)
+		}
+	}
+	// Sibling files only count when the handler file is part of the same build
+	if pkg != nil && contains(pkg.GoFiles, filepath.Base(mainAbsPath)) {
+		if pkg.Name != "main" {
+			return false, fmt.Errorf("handler main package %s is package %s, not main", mainInputFileRelativePath, pkg.Name)
+		}
+		for _, file := range pkg.GoFiles {
+			if path := filepath.Join(dir, file); path != mainAbsPath {
+				files = append(files, path)
+			}
+		}
+	}
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if _, err := parser.ParseFile(fset, file, nil, parser.AllErrors); err != nil {
+			return false, fmt.Errorf("handler main package %s does not parse: %w", mainInputFileRelativePath, err)
+		}
+	}
+	return true, nil
+}
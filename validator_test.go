@@ -188,3 +188,40 @@ func TestGoFileValidator_IsFileBeingWritten(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerMainValid(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.mod":            "module testmod\n\ngo 1.21\n",
+		"good/main.go":      "package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do() }\n",
+		"broken/main.go":    "package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do(\n",
+		"sibling/main.go":   "package main\n\nfunc main() { helper() }\n",
+		"sibling/helper.go": "package main\n\nfunc helper() {\n",
+		"lib/lib.go":        "package lib\n\nfunc Do() {}\n",
+	})
+	finder := New(root)
+
+	tests := []struct {
+		handler string
+		valid   bool
+	}{
+		{"good/main.go", true},
+		{"broken/main.go", false},
+		{"sibling/main.go", false}, // another file of the main package is broken
+	}
+	for _, tt := range tests {
+		valid, err := finder.HandlerMainValid(tt.handler)
+		if valid != tt.valid {
+			t.Errorf("HandlerMainValid(%s) = %v, want %v (err: %v)", tt.handler, valid, tt.valid, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("HandlerMainValid(%s): expected an error describing the problem", tt.handler)
+		}
+		if tt.valid && err != nil {
+			t.Errorf("HandlerMainValid(%s): unexpected error: %v", tt.handler, err)
+		}
+	}
+
+	if _, err := finder.HandlerMainValid("missing/main.go"); err == nil {
+		t.Error("expected error for a missing handler main file")
+	}
+}
@@ -0,0 +1,82 @@
+package godepfind
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// VerifyOwnership compares the cached ownership decision for a file with an
+// authoritative one computed freshly by `go list -deps` from the handler's
+// main: the file is owned when it is one of the compiled files of a package
+// in the main's dependencies. Both results are returned so callers (and
+// tests) can assert they agree and catch cache bugs. The cached decision is
+// taken without an event, so the cache is not updated. go list knows nothing
+// of ExcludeFromOwnership, so packages excluded there are not owned by the
+// authoritative decision either, except the handler's own main package.
+func (g *GoDepFind) VerifyOwnership(mainInputFileRelativePath, fileAbsPath string) (cached bool, authoritative bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshIfStale()
+	cached, _, err = g.thisFileIsMine(mainInputFileRelativePath, fileAbsPath, "")
+	if err != nil {
+		return false, false, err
+	}
+	authoritative, err = g.ownedPerGoList(mainInputFileRelativePath, fileAbsPath)
+	if err != nil {
+		return false, false, err
+	}
+	return cached, authoritative, nil
+}
+
+// ownedPerGoList reports whether fileAbsPath is compiled into the handler's
+// main according to `go list -deps`
+func (g *GoDepFind) ownedPerGoList(mainInputFileRelativePath, fileAbsPath string) (bool, error) {
+	mainAbsPath := g.rootPath(mainInputFileRelativePath)
+	mainAbsPath, err := filepath.Abs(mainAbsPath)
+	if err != nil {
+		return false, err
+	}
+	fileAbsPath = g.rootPath(fileAbsPath)
+	fileAbsPath, err = filepath.Abs(fileAbsPath)
+	if err != nil {
+		return false, err
+	}
+
+	// List the main's package, or the file alone when it is a build variant
+	// excluded from its directory's package
+	target := mainAbsPath
+	if err := g.ensureCacheInitialized(); err != nil {
+		return false, err
+	}
+	mainPkg, inPackage := g.lookupFilePath(mainAbsPath)
+	if inPackage {
+		target = filepath.Dir(mainAbsPath)
+	}
+
+	args := []string{"list", "-deps", "-f", "{{.Dir}}\t{{join .GoFiles \",\"}}\t{{join .CgoFiles \",\"}}\t{{.ImportPath}}"}
+	if len(g.buildTags) > 0 {
+		args = append(args, "-tags", strings.Join(g.buildTags, ","))
+	}
+	cmd := exec.Command(g.goProgram(), append(args, target)...)
+	cmd.Dir = g.rootDir
+	cmd.Env = g.listEnv()
+	out, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("go list -deps %s failed: %w", mainInputFileRelativePath, goToolError(g.goProgram(), append(args, target), err))
+	}
+
+	fileDir, fileName := filepath.Dir(fileAbsPath), filepath.Base(fileAbsPath)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 || fields[0] != fileDir {
+			continue
+		}
+		files := append(strings.Split(fields[1], ","), strings.Split(fields[2], ",")...)
+		if contains(files, fileName) {
+			return fields[3] == mainPkg || !g.excludedFromOwnership(fields[3]), nil
+		}
+	}
+	return false, nil
+}
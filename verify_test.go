@@ -0,0 +1,37 @@
+package godepfind
+
+import "testing"
+
+func TestVerifyOwnershipAgreesWithGoList(t *testing.T) {
+	finder := New("testproject")
+
+	handlers := []string{"appAserver/main.go", "appBcmd/main.go", "appCwasm/main.go"}
+	files := []string{
+		"appAserver/main.go",
+		"appBcmd/main.go",
+		"appCwasm/main.go",
+		"modules/module1/module1.go",
+		"modules/module2/module2.go",
+		"modules/module3/module3.go",
+		"modules/module4/module4.go",
+	}
+	owned := 0
+	for _, handler := range handlers {
+		for _, file := range files {
+			cached, authoritative, err := finder.VerifyOwnership(handler, file)
+			if err != nil {
+				t.Fatalf("VerifyOwnership(%s, %s) failed: %v", handler, file, err)
+			}
+			if cached != authoritative {
+				t.Errorf("VerifyOwnership(%s, %s): cached %v, go list -deps %v", handler, file, cached, authoritative)
+			}
+			if authoritative {
+				owned++
+			}
+		}
+	}
+	// each main owns itself; appAserver 2 modules, appBcmd 1, appCwasm 1
+	if owned != 7 {
+		t.Errorf("expected 7 owned (handler, file) pairs, got %d", owned)
+	}
+}
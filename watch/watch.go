@@ -0,0 +1,259 @@
+// Package watch turns a godepfind.GoDepFind into an incremental build
+// trigger: it watches the analyzed roots with fsnotify, applies the changes to
+// the dependency cache in debounced batches and reports the main packages each
+// change affects.
+package watch
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdvelop/godepfind"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWindow is the debounce window used when New is given zero
+const DefaultWindow = 100 * time.Millisecond
+
+// Change is a file event applied to the cache, with the main packages that
+// depend on the file
+type Change struct {
+	File  string              // absolute path of the file
+	Event godepfind.FileEvent // coalesced event of the batch
+	Mains []string            // main packages depending on File, sorted
+}
+
+// Watcher watches the roots of a GoDepFind recursively and calls its handler
+// once per debounced batch of changes. Only .go files and module files are
+// reported, unless the finder tracks embedded files (see SetTrackEmbeds), in
+// which case every file is. Directories ignored by the go tool (testdata and
+// names starting with "." or "_") are not watched.
+type Watcher struct {
+	finder    *godepfind.GoDepFind
+	fsw       *fsnotify.Watcher
+	debouncer *godepfind.Debouncer
+	handler   func(changes []Change, err error)
+
+	mu      sync.Mutex
+	removed map[string][]string // removed file -> mains depending on it before the removal
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New starts watching the roots of finder. handler is called, from a
+// background goroutine, after each batch of events was applied to the cache;
+// err reports a failure applying the batch or resolving the mains, or a
+// watcher error (with no changes). window is the debounce window
+// (DefaultWindow when zero). Call Close to stop watching.
+func New(finder *godepfind.GoDepFind, window time.Duration, handler func(changes []Change, err error)) (*Watcher, error) {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		finder:    finder,
+		fsw:       fsw,
+		debouncer: godepfind.NewDebouncer(finder, window),
+		handler:   handler,
+		removed:   make(map[string][]string),
+		done:      make(chan struct{}),
+	}
+	for _, root := range finder.Roots() {
+		rootAbs, err := filepath.Abs(root)
+		if err == nil {
+			_, err = w.addTree(rootAbs, false)
+		}
+		if err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+	w.debouncer.OnFlush(w.flushed)
+	go w.loop()
+	return w, nil
+}
+
+// Close stops watching. Events not yet applied to the cache are discarded.
+func (w *Watcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+		w.debouncer.Stop()
+	})
+	return err
+}
+
+// loop forwards fsnotify events to the debouncer until Close
+func (w *Watcher) loop() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			if w.handler != nil {
+				w.handler(nil, err)
+			}
+		}
+	}
+}
+
+// handle translates an fsnotify event and queues it. New directories are
+// watched, and the files already created in them are queued as created.
+func (w *Watcher) handle(event fsnotify.Event) {
+	fileEvent, ok := translateOp(event.Op)
+	if !ok {
+		return
+	}
+	if fileEvent == godepfind.EventCreate {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if skipDir(filepath.Base(event.Name)) {
+				return
+			}
+			files, _ := w.addTree(event.Name, true)
+			for _, file := range files {
+				w.queue(file, godepfind.EventCreate)
+			}
+			return
+		}
+	}
+	w.queue(event.Name, fileEvent)
+}
+
+// queue adds a file event to the debouncer, when the file is reported. Mains
+// depending on a removed file are resolved now: once the removal is applied
+// the cache no longer knows the file.
+func (w *Watcher) queue(file string, event godepfind.FileEvent) {
+	if !w.reports(file) {
+		return
+	}
+	if event == godepfind.EventRemove || event == godepfind.EventRename {
+		if affected, err := w.finder.FindAffectedMains([]string{file}); err == nil {
+			w.mu.Lock()
+			w.removed[file] = mergeSorted(w.removed[file], affected[file])
+			w.mu.Unlock()
+		}
+	}
+	w.debouncer.Add(file, event)
+}
+
+// flushed reports a batch applied by the debouncer to the handler
+func (w *Watcher) flushed(batch map[string]godepfind.FileEvent, err error) {
+	files := make([]string, 0, len(batch))
+	for file := range batch {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	w.mu.Lock()
+	removed := make(map[string][]string, len(files))
+	for _, file := range files {
+		removed[file] = w.removed[file]
+		delete(w.removed, file)
+	}
+	w.mu.Unlock()
+
+	affected, mainsErr := w.finder.FindAffectedMains(files)
+	if err == nil {
+		err = mainsErr
+	}
+	changes := make([]Change, 0, len(files))
+	for _, file := range files {
+		changes = append(changes, Change{
+			File:  file,
+			Event: batch[file],
+			Mains: mergeSorted(removed[file], affected[file]),
+		})
+	}
+	if w.handler != nil {
+		w.handler(changes, err)
+	}
+}
+
+// reports tells whether events for file are reported: .go files and module
+// files (go.mod, go.sum, go.work) always, any file when embedded files are
+// tracked
+func (w *Watcher) reports(file string) bool {
+	switch filepath.Base(file) {
+	case "go.mod", "go.sum", "go.work", "go.work.sum":
+		return true
+	}
+	return filepath.Ext(file) == ".go" || w.finder.TracksEmbeds()
+}
+
+// addTree watches dir and the directories below it. With collect set it
+// returns the files found, for directories that appeared after watching began.
+func (w *Watcher) addTree(dir string, collect bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			if collect {
+				files = append(files, path)
+			}
+			return nil
+		}
+		if path != dir && skipDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		return w.fsw.Add(path)
+	})
+	return files, err
+}
+
+// skipDir reports directories ./... never matches, so they hold no package
+// of the cache
+func skipDir(name string) bool {
+	return name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// translateOp maps an fsnotify operation to a FileEvent. Attribute changes
+// are not reported.
+func translateOp(op fsnotify.Op) (godepfind.FileEvent, bool) {
+	switch {
+	case op.Has(fsnotify.Create):
+		return godepfind.EventCreate, true
+	case op.Has(fsnotify.Write):
+		return godepfind.EventWrite, true
+	case op.Has(fsnotify.Remove):
+		return godepfind.EventRemove, true
+	case op.Has(fsnotify.Rename):
+		return godepfind.EventRename, true
+	}
+	return "", false
+}
+
+// mergeSorted returns the sorted union of two string slices, never nil
+func mergeSorted(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	merged := []string{}
+	for _, list := range [][]string{a, b} {
+		for _, s := range list {
+			if !seen[s] {
+				seen[s] = true
+				merged = append(merged, s)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,176 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cdvelop/godepfind"
+	"github.com/fsnotify/fsnotify"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// startWatcher watches root and returns the channel receiving each batch
+func startWatcher(t *testing.T, finder *godepfind.GoDepFind) <-chan []Change {
+	t.Helper()
+	batches := make(chan []Change, 16)
+	w, err := New(finder, 20*time.Millisecond, func(changes []Change, err error) {
+		if err != nil {
+			t.Errorf("watch error: %v", err)
+		}
+		batches <- changes
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return batches
+}
+
+// nextChange waits for the change of file, skipping batches about other files
+func nextChange(t *testing.T, batches <-chan []Change, file string) Change {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case changes := <-batches:
+			for _, change := range changes {
+				if change.File == file {
+					return change
+				}
+			}
+		case <-timeout:
+			t.Fatalf("no change reported for %s", file)
+		}
+	}
+}
+
+func TestWatcherReportsAffectedMains(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"go.mod":        "module testmod\n\ngo 1.21\n",
+		"app/main.go":   "package main\n\nimport \"testmod/lib\"\n\nfunc main() { lib.Do() }\n",
+		"other/main.go": "package main\n\nfunc main() {}\n",
+		"lib/lib.go":    "package lib\n\nfunc Do() {}\n",
+	})
+	finder := godepfind.New(root)
+	if _, err := finder.ListMainPackages(); err != nil {
+		t.Fatalf("cache build failed: %v", err)
+	}
+	batches := startWatcher(t, finder)
+
+	libFile := filepath.Join(root, "lib", "lib.go")
+	if err := os.WriteFile(libFile, []byte("package lib\n\nfunc Do() { _ = 1 }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	change := nextChange(t, batches, libFile)
+	if change.Event != godepfind.EventWrite {
+		t.Errorf("expected a write event, got %q", change.Event)
+	}
+	if !reflect.DeepEqual(change.Mains, []string{"testmod/app"}) {
+		t.Errorf("expected lib.go to affect [testmod/app], got %v", change.Mains)
+	}
+
+	// A file in a new directory is reported once the directory is watched
+	newFile := filepath.Join(root, "lib", "sub", "sub.go")
+	if err := os.MkdirAll(filepath.Dir(newFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newFile, []byte("package sub\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if change := nextChange(t, batches, newFile); change.Event != godepfind.EventCreate {
+		t.Errorf("expected a create event for the new file, got %q", change.Event)
+	}
+
+	// A removed file still reports the mains that depended on it
+	if err := os.Remove(libFile); err != nil {
+		t.Fatal(err)
+	}
+	change = nextChange(t, batches, libFile)
+	if change.Event != godepfind.EventRemove {
+		t.Errorf("expected a remove event, got %q", change.Event)
+	}
+	if !reflect.DeepEqual(change.Mains, []string{"testmod/app"}) {
+		t.Errorf("expected removed lib.go to affect [testmod/app], got %v", change.Mains)
+	}
+}
+
+func TestWatcherIgnoresNonGoFiles(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"go.mod":      "module testmod\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+	})
+	finder := godepfind.New(root)
+	batches := startWatcher(t, finder)
+
+	if err := os.WriteFile(filepath.Join(root, "app", "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mainFile := filepath.Join(root, "app", "main.go")
+	if err := os.WriteFile(mainFile, []byte("package main\n\nfunc main() { _ = 1 }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case changes := <-batches:
+			for _, change := range changes {
+				if filepath.Ext(change.File) != ".go" {
+					t.Fatalf("non-Go file reported: %s", change.File)
+				}
+				if change.File == mainFile {
+					if !reflect.DeepEqual(change.Mains, []string{"testmod/app"}) {
+						t.Errorf("expected main.go to affect [testmod/app], got %v", change.Mains)
+					}
+					return
+				}
+			}
+		case <-timeout:
+			t.Fatal("no change reported for main.go")
+		}
+	}
+}
+
+func TestTranslateOp(t *testing.T) {
+	cases := map[fsnotify.Op]godepfind.FileEvent{
+		fsnotify.Create: godepfind.EventCreate,
+		fsnotify.Write:  godepfind.EventWrite,
+		fsnotify.Remove: godepfind.EventRemove,
+		fsnotify.Rename: godepfind.EventRename,
+	}
+	for op, expected := range cases {
+		if event, ok := translateOp(op); !ok || event != expected {
+			t.Errorf("translateOp(%v) = %q, %v; expected %q", op, event, ok, expected)
+		}
+	}
+	if _, ok := translateOp(fsnotify.Chmod); ok {
+		t.Error("expected chmod to be ignored")
+	}
+}
+
+func TestSkipDir(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"vendor": true, "testdata": true, ".git": true, "_build": true,
+		"modules": false, "vendors": false,
+	} {
+		if skipDir(name) != expected {
+			t.Errorf("skipDir(%q) = %v; expected %v", name, !expected, expected)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package godepfind
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// workspaceModule is a module included by a go.work "use" directive
+type workspaceModule struct {
+	dir        string // module directory, in the same form as rootDir
+	use        string // directory as written in go.work, slash-separated
+	modulePath string // module path declared in the module's go.mod
+}
+
+// detectWorkspace returns the modules of the go.work file in rootDir, or nil
+// when rootDir is not a workspace root
+func (g *GoDepFind) detectWorkspace(rootAbs string) []workspaceModule {
+	uses := readWorkspaceUses(filepath.Join(rootAbs, "go.work"))
+	if len(uses) == 0 {
+		return nil
+	}
+
+	modules := make([]workspaceModule, 0, len(uses))
+	for _, use := range uses {
+		dir := filepath.FromSlash(use)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(g.rootDir, dir)
+		}
+		modulePath := readModulePath(filepath.Join(dir, "go.mod"))
+		if modulePath == "" {
+			continue // not a module directory; go list reports it
+		}
+		modules = append(modules, workspaceModule{dir: dir, use: use, modulePath: modulePath})
+	}
+	return modules
+}
+
+// detectPeerModules returns the modules of the roots given to NewMulti, which
+// are listed independently since no go.work ties them together. Roots that
+// are not inside a module are skipped.
+func (g *GoDepFind) detectPeerModules() []workspaceModule {
+	var modules []workspaceModule
+	for _, root := range g.roots() {
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		goModDir := findGoModDir(rootAbs)
+		if goModDir == "" {
+			continue
+		}
+		dir := goModDir
+		if goModDir == rootAbs {
+			dir = root // keep the root as given, like moduleRoot
+		}
+		modulePath := readModulePath(filepath.Join(goModDir, "go.mod"))
+		if modulePath == "" {
+			continue
+		}
+		modules = append(modules, workspaceModule{dir: dir, use: root, modulePath: modulePath})
+	}
+	return modules
+}
+
+// readWorkspaceUses returns the directories of the "use" directives of a
+// go.work file, in both the single-line and the block form
+func readWorkspaceUses(goWorkPath string) []string {
+	file, err := os.Open(goWorkPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var uses []string
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock:
+			if line != "" {
+				uses = append(uses, strings.Trim(line, `"`))
+			}
+		case line == "use (" || line == "use(":
+			inBlock = true
+		default:
+			if rest, ok := strings.CutPrefix(line, "use "); ok {
+				uses = append(uses, strings.Trim(strings.TrimSpace(rest), `"`))
+			}
+		}
+	}
+	return uses
+}
+
+// listPatterns returns the go list patterns matching every package of the
+// project: "./..." for a single module (or for each peer root, listed on its
+// own), or one pattern per workspace module since go list rejects "./..." at
+// a workspace root without a go.mod
+func (res *moduleResolution) listPatterns() []string {
+	if len(res.workspace) == 0 || res.peers {
+		return []string{"./..."}
+	}
+	patterns := make([]string, 0, len(res.workspace))
+	for _, module := range res.workspace {
+		use := strings.TrimSuffix(module.use, "/")
+		if use == "." {
+			patterns = append(patterns, "./...")
+			continue
+		}
+		if !filepath.IsAbs(filepath.FromSlash(use)) && !strings.HasPrefix(use, "./") && !strings.HasPrefix(use, "../") {
+			use = "./" + use
+		}
+		patterns = append(patterns, use+"/...")
+	}
+	return patterns
+}
+
+// workspaceImportPathDir resolves an import path against the workspace module
+// with the longest matching module path
+func (res *moduleResolution) workspaceImportPathDir(importPath string) (string, bool) {
+	var best *workspaceModule
+	for i, module := range res.workspace {
+		if importPath != module.modulePath && !strings.HasPrefix(importPath, module.modulePath+"/") {
+			continue
+		}
+		if best == nil || len(module.modulePath) > len(best.modulePath) {
+			best = &res.workspace[i]
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(importPath, best.modulePath), "/")
+	return filepath.Join(best.dir, filepath.FromSlash(rest)), true
+}
@@ -0,0 +1,98 @@
+package godepfind
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// workspaceFiles returns a go.work root stitching three modules together:
+// the app module imports packages of both sibling modules
+func workspaceFiles() map[string]string {
+	return map[string]string{
+		"go.work":                         "go 1.21\n\nuse (\n\t./services/app\n\t./libs/core // shared code\n)\n\nuse ./libs/extra\n",
+		"services/app/go.mod":             "module example.com/app\n\ngo 1.21\n\nrequire (\n\texample.com/core v0.0.0\n\texample.com/extra v0.0.0\n)\n",
+		"services/app/cmd/server/main.go": "package main\n\nimport (\n\t\"example.com/core/store\"\n\t\"example.com/extra\"\n)\n\nfunc main() { store.Open(); extra.Help() }\n",
+		"libs/core/go.mod":                "module example.com/core\n\ngo 1.21\n",
+		"libs/core/store/store.go":        "package store\n\nfunc Open() {}\n",
+		"libs/core/unused/unused.go":      "package unused\n",
+		"libs/extra/go.mod":               "module example.com/extra\n\ngo 1.21\n",
+		"libs/extra/extra.go":             "package extra\n\nfunc Help() {}\n",
+	}
+}
+
+func TestWorkspaceSpansModules(t *testing.T) {
+	// Workspace mode rejects -mod=mod, which some environments set globally
+	t.Setenv("GOFLAGS", "")
+	root := writeTestModule(t, workspaceFiles())
+	finder := New(root)
+
+	if err := finder.ensureCacheInitialized(); err != nil {
+		t.Fatalf("cache init failed: %v", err)
+	}
+	var listed []string
+	for pkgPath := range finder.packageCache {
+		listed = append(listed, pkgPath)
+	}
+	sort.Strings(listed)
+	expected := []string{
+		"example.com/app/cmd/server",
+		"example.com/core/store",
+		"example.com/core/unused",
+		"example.com/extra",
+	}
+	if !reflect.DeepEqual(listed, expected) {
+		t.Fatalf("expected packages %v, got %v", expected, listed)
+	}
+	if got, want := finder.packageCache["example.com/core/store"].Dir, filepath.Join(root, "libs", "core", "store"); got != want {
+		t.Errorf("core/store resolved to %s, want %s", got, want)
+	}
+	if !reflect.DeepEqual(finder.mainPackages, []string{"example.com/app/cmd/server"}) {
+		t.Errorf("expected the server main, got %v", finder.mainPackages)
+	}
+
+	tests := []struct {
+		file     string
+		expected bool
+	}{
+		{"libs/core/store/store.go", true},
+		{"libs/extra/extra.go", true},
+		{"libs/core/unused/unused.go", false},
+	}
+	for _, tt := range tests {
+		isMine, err := finder.ThisFileIsMine("services/app/cmd/server/main.go", filepath.Join(root, tt.file), "write")
+		if err != nil {
+			t.Fatalf("ThisFileIsMine(%s) failed: %v", tt.file, err)
+		}
+		if isMine != tt.expected {
+			t.Errorf("ThisFileIsMine(%s) = %v, want %v", tt.file, isMine, tt.expected)
+		}
+	}
+}
+
+func TestReadWorkspaceUses(t *testing.T) {
+	root := writeTestModule(t, map[string]string{
+		"go.work": "go 1.21\n\n// toolchain comment\nuse ./single\nuse (\n\t\"./quoted\"\n\n\t./block // trailing\n)\n",
+	})
+	uses := readWorkspaceUses(filepath.Join(root, "go.work"))
+	expected := []string{"./single", "./quoted", "./block"}
+	if !reflect.DeepEqual(uses, expected) {
+		t.Errorf("expected uses %v, got %v", expected, uses)
+	}
+}
+
+func TestWorkspaceModulePath(t *testing.T) {
+	root := writeTestModule(t, workspaceFiles())
+	if got, err := New(root).ModulePath(); err == nil {
+		t.Errorf("expected an error for a workspace root outside every module, got %q", got)
+	}
+	root = writeTestModule(t, map[string]string{
+		"go.work":          "go 1.21\n\nuse (\n\t.\n\t./libs/core\n)\n",
+		"go.mod":           "module example.com/root\n\ngo 1.21\n",
+		"libs/core/go.mod": "module example.com/core\n\ngo 1.21\n",
+	})
+	if got, err := New(root).ModulePath(); err != nil || got != "example.com/root" {
+		t.Errorf("ModulePath = %q, %v; want example.com/root", got, err)
+	}
+}